@@ -0,0 +1,214 @@
+// Package approvals enforces the two-person rule for critical election
+// actions. One admin proposes an action, which is stored as a pending request;
+// it only runs once a different admin approves it within the approval window.
+// Requests are kept after they are decided, so the table doubles as the audit
+// log of who proposed and who approved each action.
+package approvals
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/Haste007/E-Voting/Backend/ballot"
+)
+
+// Actions that need a second admin's approval
+const (
+	ActionStart         = "start"
+	ActionEnd           = "end"
+	ActionDelete        = "delete"
+	ActionCertify       = "certify"
+	ActionCorrectResult = "correct_result"
+	ActionHours         = "hours" // Polling hours the scheduler may open and close polls at
+)
+
+// Request states. Requests still pending past their expiry are reported as expired.
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+	StatusExpired  = "expired"
+)
+
+// DefaultWindow is how long a request waits for approval unless
+// APPROVAL_WINDOW is set
+const DefaultWindow = 30 * time.Minute
+
+var (
+	ErrNotFound   = errors.New("approval request not found")
+	ErrDecided    = errors.New("approval request has already been decided")
+	ErrExpired    = errors.New("approval request has expired; propose the action again")
+	ErrSameAdmin  = errors.New("an action must be approved by a different admin than the one who proposed it")
+	ErrDuplicate  = errors.New("the same action is already waiting for approval")
+	ErrNoApprover = errors.New("approving admin is unknown")
+)
+
+// Admin identifies the admin proposing or deciding a request
+type Admin struct {
+	ID       int
+	Username string
+}
+
+// Request is a proposed action and its outcome
+type Request struct {
+	ID         int             `json:"id"`
+	ElectionID int             `json:"election_id"` // Kept when the election itself is deleted
+	Action     string          `json:"action"`
+	Payload    json.RawMessage `json:"payload,omitempty"` // Action details, such as a result correction
+	Reason     string          `json:"reason"`
+	ProposedBy string          `json:"proposed_by"`
+	ProposedAt string          `json:"proposed_at"`
+	ExpiresAt  string          `json:"expires_at"`
+	Status     string          `json:"status"`
+	DecidedBy  *string         `json:"decided_by"`
+	DecidedAt  *string         `json:"decided_at"`
+	Outcome    *string         `json:"outcome"` // Result of an approved action, or why it was rejected
+
+	proposerID int
+}
+
+// window returns the approval window from APPROVAL_WINDOW, a Go duration such as "1h"
+func window() (time.Duration, error) {
+	value := os.Getenv("APPROVAL_WINDOW")
+	if value == "" {
+		return DefaultWindow, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// columns selects a request; status reads as expired once a pending request's window has passed
+const columns = `
+    id, election_id, action, payload, reason, proposed_by, proposed_by_id, proposed_at, expires_at,
+    CASE WHEN status = 'pending' AND expires_at <= NOW() THEN 'expired' ELSE status END AS status,
+    decided_by, decided_at, outcome
+`
+
+// scanner is satisfied by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scan reads a request selected with columns
+func scan(row scanner) (Request, error) {
+	var request Request
+	var payload []byte
+	err := row.Scan(&request.ID, &request.ElectionID, &request.Action, &payload, &request.Reason, &request.ProposedBy, &request.proposerID,
+		&request.ProposedAt, &request.ExpiresAt, &request.Status, &request.DecidedBy, &request.DecidedAt, &request.Outcome)
+	if payload != nil {
+		request.Payload = payload
+	}
+	return request, err
+}
+
+// Propose stores a pending request for action on an election. payload may be
+// nil. Only one request per election and action may be pending at a time,
+// except for result corrections.
+func Propose(tx *sql.Tx, electionID int, action string, payload interface{}, reason string, proposer Admin) (Request, error) {
+	duration, err := window()
+	if err != nil {
+		return Request{}, err
+	}
+
+	var data interface{}
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return Request{}, err
+		}
+		data = string(encoded)
+	}
+
+	// Requests left pending past their window no longer block new ones
+	expireQuery := "UPDATE approval_requests SET status = 'expired' WHERE election_id = $1 AND status = 'pending' AND expires_at <= NOW()"
+	if _, err := tx.Exec(expireQuery, electionID); err != nil {
+		return Request{}, err
+	}
+
+	query := `
+        INSERT INTO approval_requests (election_id, action, payload, reason, proposed_by, proposed_by_id, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6, NOW() + $7 * INTERVAL '1 second')
+        ON CONFLICT DO NOTHING
+        RETURNING ` + columns
+	request, err := scan(tx.QueryRow(query, electionID, action, data, reason, proposer.Username, proposer.ID, duration.Seconds()))
+	if err == sql.ErrNoRows {
+		return Request{}, ErrDuplicate
+	}
+	return request, err
+}
+
+// Claim locks a pending request for its approver to run. It fails unless the
+// request is pending, within its window and proposed by another admin.
+func Claim(tx *sql.Tx, id int, approver Admin) (Request, error) {
+	if approver.ID == 0 {
+		return Request{}, ErrNoApprover
+	}
+	request, err := scan(tx.QueryRow("SELECT "+columns+" FROM approval_requests WHERE id = $1 FOR UPDATE", id))
+	if err == sql.ErrNoRows {
+		return Request{}, ErrNotFound
+	} else if err != nil {
+		return Request{}, err
+	}
+
+	switch {
+	case request.Status == StatusExpired:
+		return request, ErrExpired
+	case request.Status != StatusPending:
+		return request, ErrDecided
+	case request.proposerID == approver.ID:
+		return request, ErrSameAdmin
+	}
+	return request, nil
+}
+
+// Decide records the approval or rejection of a claimed request
+func Decide(tx *sql.Tx, id int, status string, decider Admin, outcome string) (Request, error) {
+	query := `
+        UPDATE approval_requests
+        SET status = $2, decided_by = $3, decided_by_id = $4, decided_at = NOW(), outcome = $5
+        WHERE id = $1
+        RETURNING ` + columns
+	return scan(tx.QueryRow(query, id, status, decider.Username, decider.ID, outcome))
+}
+
+// Reject turns down a pending request. Unlike approval, the proposer may
+// reject their own request to withdraw it.
+func Reject(tx *sql.Tx, id int, decider Admin, reason string) (Request, error) {
+	request, err := scan(tx.QueryRow("SELECT "+columns+" FROM approval_requests WHERE id = $1 FOR UPDATE", id))
+	if err == sql.ErrNoRows {
+		return Request{}, ErrNotFound
+	} else if err != nil {
+		return Request{}, err
+	}
+	if request.Status != StatusPending {
+		return request, ErrDecided
+	}
+	return Decide(tx, id, StatusRejected, decider, reason)
+}
+
+// List returns requests, newest first. electionID 0 lists every election's
+// requests, and an empty status lists requests in every state.
+func List(q ballot.Querier, electionID int, status string) ([]Request, error) {
+	query := `
+        SELECT * FROM (SELECT ` + columns + ` FROM approval_requests) r
+        WHERE ($1 = 0 OR r.election_id = $1) AND ($2 = '' OR r.status = $2)
+        ORDER BY r.proposed_at DESC, r.id DESC
+    `
+	rows, err := q.Query(query, electionID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []Request{}
+	for rows.Next() {
+		request, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+	return requests, rows.Err()
+}
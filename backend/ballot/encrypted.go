@@ -0,0 +1,335 @@
+package ballot
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/Haste007/E-Voting/Backend/elgamal"
+)
+
+// Querier is satisfied by both *sql.DB and *sql.Tx
+type Querier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Context binds encrypted ballots and decryption proofs to one election and constituency
+func Context(electionID, constituencyID int) string {
+	return fmt.Sprintf("election:%d/constituency:%d", electionID, constituencyID)
+}
+
+// OptionContext binds a decryption proof to one option of a constituency's ballot
+func OptionContext(electionID, constituencyID int, option Option) string {
+	return fmt.Sprintf("%s/%s", Context(electionID, constituencyID), option.Key())
+}
+
+// IsEncrypted reports whether an election uses encrypted ballots
+func IsEncrypted(q Querier, electionID interface{}) (bool, error) {
+	var encrypted bool
+	err := q.QueryRow("SELECT encrypted FROM elections WHERE id = $1", electionID).Scan(&encrypted)
+	return encrypted, err
+}
+
+// ErrNoElectionKey is returned when an encrypted election has no public key yet
+var ErrNoElectionKey = errors.New("election key has not been generated yet")
+
+// RequireElectionKey returns ErrNoElectionKey if the election is encrypted and
+// its key (or, with trustees, the key ceremony) is not ready
+func RequireElectionKey(q Querier, electionID interface{}) error {
+	var missing bool
+	query := `
+        SELECT e.encrypted AND NOT EXISTS (SELECT 1 FROM election_keys k WHERE k.election_id = e.id)
+        FROM elections e
+        WHERE e.id = $1
+    `
+	if err := q.QueryRow(query, electionID).Scan(&missing); err != nil {
+		return err
+	}
+	if missing {
+		return ErrNoElectionKey
+	}
+	return nil
+}
+
+// EnsureElectionKey generates the key pair of an encrypted election if it has none yet.
+// Elections with trustees are skipped; their key comes from the trustee key ceremony.
+func EnsureElectionKey(tx *sql.Tx, electionID interface{}) error {
+	var encrypted, trustees bool
+	query := "SELECT encrypted, trustee_threshold IS NOT NULL FROM elections WHERE id = $1"
+	if err := tx.QueryRow(query, electionID).Scan(&encrypted, &trustees); err != nil || !encrypted || trustees {
+		return err
+	}
+
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM election_keys WHERE election_id = $1)", electionID).Scan(&exists); err != nil || exists {
+		return err
+	}
+
+	secret, publicKey, err := elgamal.GenerateKey(elgamal.DefaultGroup)
+	if err != nil {
+		return err
+	}
+
+	insertQuery := "INSERT INTO election_keys (election_id, public_key, secret_key) VALUES ($1, $2, $3)"
+	_, err = tx.Exec(insertQuery, electionID, publicKey.H.Big().Text(16), secret.Text(16))
+	return err
+}
+
+// ElectionPublicKey loads the public key ballots of an election are encrypted under
+func ElectionPublicKey(q Querier, electionID interface{}) (*elgamal.PublicKey, error) {
+	var hexKey string
+	if err := q.QueryRow("SELECT public_key FROM election_keys WHERE election_id = $1", electionID).Scan(&hexKey); err != nil {
+		return nil, err
+	}
+	h, ok := new(big.Int).SetString(hexKey, 16)
+	if !ok {
+		return nil, fmt.Errorf("election %v has a malformed public key", electionID)
+	}
+	return &elgamal.PublicKey{H: (*elgamal.Int)(h)}, nil
+}
+
+// aggregateConstituency homomorphically adds every counted encrypted ballot of a
+// constituency, returning one aggregate ciphertext per option and the ballot count
+func aggregateConstituency(q Querier, electionID, constituencyID, options int) ([]*elgamal.Ciphertext, int64, error) {
+	group := elgamal.DefaultGroup
+	totals := make([]*elgamal.Ciphertext, options)
+	for i := range totals {
+		totals[i] = elgamal.Zero()
+	}
+
+	rows, err := q.Query("SELECT encrypted_choice FROM ballot_box WHERE election_id = $1 AND constituency_id = $2 AND NOT replaced", electionID, constituencyID)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var count int64
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, 0, err
+		}
+		var encrypted elgamal.OneHotBallot
+		if err := json.Unmarshal(data, &encrypted); err != nil {
+			return nil, 0, err
+		}
+		if len(encrypted.Choices) != options {
+			return nil, 0, fmt.Errorf("ballot in constituency %d has %d choices, expected %d", constituencyID, len(encrypted.Choices), options)
+		}
+		for i, ct := range encrypted.Choices {
+			totals[i] = totals[i].Add(group, ct)
+		}
+		count++
+	}
+	return totals, count, rows.Err()
+}
+
+// TallyDecryption is one decryption share behind a published count, tagged with
+// the trustee that produced it (0 when the server holds the election key)
+type TallyDecryption struct {
+	Trustee int `json:"trustee"`
+	elgamal.DecryptionShare
+}
+
+// EncryptedTally is the homomorphic sum of one option over a constituency's ballots
+type EncryptedTally struct {
+	ConstituencyID int
+	Index          int // Position of the option on the ballot
+	Option         Option
+	Aggregate      *elgamal.Ciphertext
+	BallotCount    int64 // Upper bound for the decrypted count
+}
+
+// TallyEncrypted sums the encrypted ballots of every constituency into
+// encrypted_tallies. When the server holds the election secret the aggregates
+// are decrypted straight away and it returns true; trustee elections return
+// false and wait for partial decryptions.
+func TallyEncrypted(tx *sql.Tx, electionID int) (bool, error) {
+	constituencyIDs, err := electionConstituencies(tx, electionID)
+	if err != nil {
+		return false, err
+	}
+
+	var tallies []EncryptedTally
+	for _, constituencyID := range constituencyIDs {
+		options, err := BallotOptions(tx, electionID, constituencyID)
+		if err != nil {
+			return false, err
+		}
+		totals, count, err := aggregateConstituency(tx, electionID, constituencyID, len(options))
+		if err != nil {
+			return false, err
+		}
+		for i, option := range options {
+			tally := EncryptedTally{ConstituencyID: constituencyID, Index: i, Option: option, Aggregate: totals[i], BallotCount: count}
+			if err := storeAggregate(tx, electionID, tally); err != nil {
+				return false, err
+			}
+			tallies = append(tallies, tally)
+		}
+	}
+
+	var secretHex sql.NullString
+	if err := tx.QueryRow("SELECT secret_key FROM election_keys WHERE election_id = $1", electionID).Scan(&secretHex); err != nil {
+		return false, err
+	}
+	if !secretHex.Valid {
+		return false, nil
+	}
+	secret, ok := new(big.Int).SetString(secretHex.String, 16)
+	if !ok {
+		return false, fmt.Errorf("election %d has a malformed secret key", electionID)
+	}
+
+	group := elgamal.DefaultGroup
+	for _, tally := range tallies {
+		share, err := elgamal.NewDecryptionShare(group, secret, tally.Aggregate, OptionContext(electionID, tally.ConstituencyID, tally.Option))
+		if err != nil {
+			return false, err
+		}
+		votes, err := elgamal.DecryptWithFactor(group, tally.Aggregate, share.D.Big(), tally.BallotCount)
+		if err != nil {
+			return false, err
+		}
+		if err := PublishTally(tx, electionID, tally, []TallyDecryption{{DecryptionShare: *share}}, votes); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// storeAggregate records an option's aggregate before it is decrypted
+func storeAggregate(tx *sql.Tx, electionID int, tally EncryptedTally) error {
+	aggregateJSON, err := json.Marshal(tally.Aggregate)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO encrypted_tallies (election_id, constituency_id, option_index, choice, candidate_id, aggregate, ballot_count)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+    `
+	_, err = tx.Exec(query, electionID, tally.ConstituencyID, tally.Index, tally.Option.Choice, tally.Option.candidateID(), string(aggregateJSON), tally.BallotCount)
+	return err
+}
+
+// EncryptedTallies loads the stored aggregates of an election in ballot order
+func EncryptedTallies(q Querier, electionID int) ([]EncryptedTally, error) {
+	query := `
+        SELECT constituency_id, option_index, choice, candidate_id, aggregate, ballot_count
+        FROM encrypted_tallies
+        WHERE election_id = $1
+        ORDER BY constituency_id, option_index
+    `
+	rows, err := q.Query(query, electionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tallies []EncryptedTally
+	for rows.Next() {
+		var tally EncryptedTally
+		var aggregate []byte
+		var candidateID sql.NullInt64
+		if err := rows.Scan(&tally.ConstituencyID, &tally.Index, &tally.Option.Choice, &candidateID, &aggregate, &tally.BallotCount); err != nil {
+			return nil, err
+		}
+		tally.Option.CandidateID = int(candidateID.Int64)
+		if err := json.Unmarshal(aggregate, &tally.Aggregate); err != nil {
+			return nil, err
+		}
+		tallies = append(tallies, tally)
+	}
+	return tallies, rows.Err()
+}
+
+// PublishTally stores the decrypted count of an aggregate with the decryption
+// shares that prove it. The tally package copies the counts into election_results.
+func PublishTally(tx *sql.Tx, electionID int, tally EncryptedTally, decryption []TallyDecryption, votes int64) error {
+	decryptionJSON, err := json.Marshal(decryption)
+	if err != nil {
+		return err
+	}
+
+	tallyQuery := `
+        UPDATE encrypted_tallies SET decryption = $4, total_votes = $5
+        WHERE election_id = $1 AND constituency_id = $2 AND option_index = $3
+    `
+	_, err = tx.Exec(tallyQuery, electionID, tally.ConstituencyID, tally.Index, string(decryptionJSON), votes)
+	return err
+}
+
+// ErrAggregateMismatch is returned when the encrypted ballots of a constituency
+// no longer add up to the aggregates that were decrypted
+var ErrAggregateMismatch = errors.New("encrypted ballots do not match the decrypted aggregates")
+
+// VerifyAggregates adds up a constituency's counted encrypted ballots again and
+// checks the sums against its stored aggregates, so decrypted counts can be
+// reused by a recount
+func VerifyAggregates(q Querier, electionID, constituencyID int) error {
+	query := `
+        SELECT aggregate, ballot_count
+        FROM encrypted_tallies
+        WHERE election_id = $1 AND constituency_id = $2
+        ORDER BY option_index
+    `
+	rows, err := q.Query(query, electionID, constituencyID)
+	if err != nil {
+		return err
+	}
+	var stored []*elgamal.Ciphertext
+	var storedCount int64
+	for rows.Next() {
+		var data []byte
+		var aggregate *elgamal.Ciphertext
+		if err := rows.Scan(&data, &storedCount); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := json.Unmarshal(data, &aggregate); err != nil {
+			rows.Close()
+			return err
+		}
+		stored = append(stored, aggregate)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	totals, count, err := aggregateConstituency(q, electionID, constituencyID, len(stored))
+	if err != nil {
+		return err
+	}
+	if len(stored) > 0 && count != storedCount {
+		return ErrAggregateMismatch
+	}
+	for i, total := range totals {
+		if total.Alpha.Big().Cmp(stored[i].Alpha.Big()) != 0 || total.Beta.Big().Cmp(stored[i].Beta.Big()) != 0 {
+			return ErrAggregateMismatch
+		}
+	}
+	return nil
+}
+
+func electionConstituencies(q Querier, electionID int) ([]int, error) {
+	rows, err := q.Query("SELECT constituency_id FROM election_constituencies WHERE election_id = $1 ORDER BY constituency_id", electionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,106 @@
+package ballot
+
+import "fmt"
+
+// Ballot choices. A NOTA ("None of the Above") ballot is a counted vote for no
+// candidate: it adds to turnout but never to any candidate's total. A ranked
+// ballot orders some or all of the candidates by preference.
+const (
+	ChoiceCandidate = "candidate"
+	ChoiceNOTA      = "nota"
+	ChoiceRanked    = "ranked"
+)
+
+// Ballot types of an election, stored in elections.ballot_type
+const (
+	TypeFPTP = "fptp" // One candidate per ballot, most votes wins
+	TypeIRV  = "irv"  // Ranked ballots counted by instant runoff
+	TypeSTV  = "stv"  // Ranked ballots filling each constituency's seats by single transferable vote
+)
+
+// Contest tiers, stored in constituencies.tier. Each district lies in one
+// constituency per tier, and a voter casts one vote in each tier's contest.
+const (
+	TierNA = "na" // National Assembly
+	TierPA = "pa" // Provincial Assembly
+)
+
+// IsTier reports whether t is a known contest tier
+func IsTier(t string) bool {
+	return t == TierNA || t == TierPA
+}
+
+// BallotType returns the ballot type of an election
+func BallotType(q Querier, electionID interface{}) (string, error) {
+	var ballotType string
+	err := q.QueryRow("SELECT ballot_type FROM elections WHERE id = $1", electionID).Scan(&ballotType)
+	return ballotType, err
+}
+
+// IsRanked reports whether ballots of the given type are ranked
+func IsRanked(ballotType string) bool {
+	return ballotType != TypeFPTP
+}
+
+// Option is one line of a constituency's ballot
+type Option struct {
+	Choice      string
+	CandidateID int // Set only for ChoiceCandidate
+}
+
+// Key names the option in proof contexts, e.g. "candidate:3" or "nota"
+func (o Option) Key() string {
+	if o.Choice == ChoiceNOTA {
+		return ChoiceNOTA
+	}
+	return fmt.Sprintf("candidate:%d", o.CandidateID)
+}
+
+// candidateID is the option's candidate_id column value, NULL for NOTA
+func (o Option) candidateID() interface{} {
+	if o.Choice != ChoiceCandidate {
+		return nil
+	}
+	return o.CandidateID
+}
+
+// OffersNOTA reports whether an election's ballots carry a "None of the Above" option
+func OffersNOTA(q Querier, electionID interface{}) (bool, error) {
+	var allowNOTA bool
+	err := q.QueryRow("SELECT allow_nota FROM elections WHERE id = $1", electionID).Scan(&allowNOTA)
+	return allowNOTA, err
+}
+
+// BallotOptions returns the options on a constituency's ballot in ballot order:
+// one per candidate, party or independent, ordered by candidate ID, then NOTA
+// if the election offers it.
+// An encrypted ballot has one ciphertext per option, in this order.
+func BallotOptions(q Querier, electionID, constituencyID int) ([]Option, error) {
+	rows, err := q.Query("SELECT id FROM candidates WHERE constituency_id = $1 ORDER BY id", constituencyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var options []Option
+	for rows.Next() {
+		option := Option{Choice: ChoiceCandidate}
+		if err := rows.Scan(&option.CandidateID); err != nil {
+			return nil, err
+		}
+		options = append(options, option)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	allowNOTA, err := OffersNOTA(q, electionID)
+	if err != nil {
+		return nil, err
+	}
+	if allowNOTA {
+		options = append(options, Option{Choice: ChoiceNOTA})
+	}
+	return options, nil
+}
@@ -0,0 +1,43 @@
+package ballot
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+)
+
+// trackingCodeBytes of randomness give a 24-character base32 code (120 bits)
+const trackingCodeBytes = 15
+
+// NewTrackingCode returns a random receipt code such as "K3QF-7ZPA-...", shown to
+// the voter once, and the hash stored in its place. The code is random rather than
+// derived from the ballot or the voter, so it reveals neither the choice nor the NID.
+func NewTrackingCode() (code, codeHash string, err error) {
+	raw := make([]byte, trackingCodeBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	encoded := base32.StdEncoding.EncodeToString(raw)
+
+	groups := make([]string, 0, len(encoded)/4)
+	for i := 0; i < len(encoded); i += 4 {
+		groups = append(groups, encoded[i:i+4])
+	}
+	code = strings.Join(groups, "-")
+	return code, HashTrackingCode(code), nil
+}
+
+// HashTrackingCode returns the stored form of a tracking code. Case, spaces and
+// dashes are ignored so that codes typed back by voters still match.
+func HashTrackingCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,21 @@
+package ballot
+
+import "database/sql"
+
+// MarkPluralityWinners flags the candidate with the most votes in each
+// constituency of a single-seat election as elected. A tie for the most votes
+// elects nobody: it has to be settled outside the system, so no winner is
+// recorded for that constituency. NOTA rows never win.
+func MarkPluralityWinners(tx *sql.Tx, electionID int) error {
+	query := `
+        UPDATE election_results r SET elected = TRUE
+        WHERE r.election_id = $1 AND r.choice = 'candidate' AND r.total_votes > 0
+          AND r.total_votes > ALL (
+              SELECT o.total_votes FROM election_results o
+              WHERE o.election_id = r.election_id AND o.constituency_id = r.constituency_id
+                AND o.choice = 'candidate' AND o.id <> r.id
+          )
+    `
+	_, err := tx.Exec(query, electionID)
+	return err
+}
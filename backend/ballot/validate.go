@@ -0,0 +1,258 @@
+package ballot
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Haste007/E-Voting/Backend/elgamal"
+	"github.com/Haste007/E-Voting/Backend/lifecycle"
+)
+
+// Ballot is a single vote as submitted, together with the district taken from the voter's token.
+// In encrypted elections the choice is either a client-encrypted ballot in Encrypted
+// or a plaintext choice that Validate encrypts at the API edge.
+type Ballot struct {
+	ElectionID     int
+	ConstituencyID int
+	Choice         string // ChoiceCandidate or ChoiceNOTA; empty means ChoiceCandidate
+	CandidateID    int
+	PartyID        int   // Picks the party's candidate when CandidateID is not set
+	Ranking        []int // Candidate IDs of a ranked ballot, most preferred first
+	DistrictID     int
+	Encrypted      *elgamal.OneHotBallot
+}
+
+// Error codes returned to the client when a ballot is rejected
+const (
+	CodeElectionNotFound          = "ELECTION_NOT_FOUND"
+	CodeElectionNotOpen           = "ELECTION_NOT_OPEN"
+	CodeConstituencyNotInElection = "CONSTITUENCY_NOT_IN_ELECTION"
+	CodeDistrictNotInConstituency = "DISTRICT_NOT_IN_CONSTITUENCY"
+	CodeNoCandidateForParty       = "NO_CANDIDATE_FOR_PARTY"
+	CodeAmbiguousParty            = "PARTY_HAS_SEVERAL_CANDIDATES"
+	CodeCandidateNotFound         = "CANDIDATE_NOT_IN_CONSTITUENCY"
+	CodeInvalidChoice             = "INVALID_CHOICE"
+	CodeWrongBallotType           = "WRONG_BALLOT_TYPE"
+	CodeInvalidRanking            = "INVALID_RANKING"
+	CodeNOTANotOffered            = "NOTA_NOT_OFFERED"
+	CodeEncryptionNotEnabled      = "ENCRYPTION_NOT_ENABLED"
+	CodeInvalidEncryptedBallot    = "INVALID_ENCRYPTED_BALLOT"
+)
+
+// ValidationError describes why a ballot was rejected
+type ValidationError struct {
+	Code    string // One of the Code* constants
+	Message string // Human readable reason
+	Status  int    // HTTP status to respond with
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// Validate runs every server-side check a ballot must pass before it is stored.
+// The election row is read FOR SHARE, so when q is a transaction the election
+// cannot be closed until the ballot is committed. For encrypted elections it
+// leaves the sealed ballot in b.Encrypted and clears the plaintext choice.
+func Validate(q Querier, b *Ballot) error {
+	switch b.Choice {
+	case "", "party": // "party" predates independent candidates
+		b.Choice = ChoiceCandidate
+		if len(b.Ranking) > 0 {
+			b.Choice = ChoiceRanked
+		}
+	case ChoiceCandidate, ChoiceNOTA, ChoiceRanked:
+	default:
+		return &ValidationError{Code: CodeInvalidChoice, Message: "Choice must be \"candidate\", \"nota\" or \"ranked\"", Status: http.StatusUnprocessableEntity}
+	}
+
+	status, err := lifecycle.CurrentStatus(q, b.ElectionID, "FOR SHARE")
+	if errors.Is(err, lifecycle.ErrElectionNotFound) {
+		return &ValidationError{Code: CodeElectionNotFound, Message: "Election not found", Status: http.StatusNotFound}
+	} else if err != nil {
+		return err
+	}
+	if !lifecycle.Allows(lifecycle.OpVote, status) {
+		return &ValidationError{Code: CodeElectionNotOpen, Message: "Election is not open for voting", Status: http.StatusConflict}
+	}
+
+	checks := []struct {
+		query string
+		args  []interface{}
+		err   *ValidationError
+	}{
+		{
+			query: "SELECT EXISTS (SELECT 1 FROM election_constituencies WHERE election_id = $1 AND constituency_id = $2)",
+			args:  []interface{}{b.ElectionID, b.ConstituencyID},
+			err:   &ValidationError{Code: CodeConstituencyNotInElection, Message: "Constituency is not part of this election", Status: http.StatusUnprocessableEntity},
+		},
+		{
+			query: "SELECT EXISTS (SELECT 1 FROM constituency_districts WHERE constituency_id = $1 AND district_id = $2)",
+			args:  []interface{}{b.ConstituencyID, b.DistrictID},
+			err:   &ValidationError{Code: CodeDistrictNotInConstituency, Message: "Voter's district is not in this constituency", Status: http.StatusForbidden},
+		},
+	}
+
+	for _, check := range checks {
+		var ok bool
+		if err := q.QueryRow(check.query, check.args...).Scan(&ok); err != nil {
+			return err
+		}
+		if !ok {
+			return check.err
+		}
+	}
+
+	ballotType, err := BallotType(q, b.ElectionID)
+	if err != nil {
+		return err
+	}
+	if IsRanked(ballotType) != (b.Choice == ChoiceRanked) {
+		message := "Election takes a single choice, not a ranking"
+		if IsRanked(ballotType) {
+			message = "Election takes a ranking of candidates"
+		}
+		return &ValidationError{Code: CodeWrongBallotType, Message: message, Status: http.StatusUnprocessableEntity}
+	}
+	if b.Choice == ChoiceRanked {
+		// Ranked elections are never encrypted, so the ranking is stored as given
+		return validateRanking(q, b)
+	}
+
+	if b.Choice == ChoiceNOTA && b.Encrypted == nil {
+		allowNOTA, err := OffersNOTA(q, b.ElectionID)
+		if err != nil {
+			return err
+		}
+		if !allowNOTA {
+			return &ValidationError{Code: CodeNOTANotOffered, Message: "Election does not offer None of the Above", Status: http.StatusUnprocessableEntity}
+		}
+		b.CandidateID = 0
+	}
+
+	if b.Choice == ChoiceCandidate && b.Encrypted == nil {
+		if err := resolveCandidate(q, b); err != nil {
+			return err
+		}
+	}
+	b.PartyID = 0
+
+	encrypted, err := IsEncrypted(q, b.ElectionID)
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		return seal(q, b)
+	}
+
+	if b.Encrypted != nil {
+		return &ValidationError{Code: CodeEncryptionNotEnabled, Message: "Election does not accept encrypted ballots", Status: http.StatusUnprocessableEntity}
+	}
+
+	return nil
+}
+
+var (
+	errNoCandidate       = &ValidationError{Code: CodeNoCandidateForParty, Message: "Party has no candidate in this constituency", Status: http.StatusUnprocessableEntity}
+	errAmbiguousParty    = &ValidationError{Code: CodeAmbiguousParty, Message: "Party has several candidates in this constituency; choose one by candidate ID", Status: http.StatusUnprocessableEntity}
+	errCandidateNotFound = &ValidationError{Code: CodeCandidateNotFound, Message: "Candidate is not standing in this constituency", Status: http.StatusUnprocessableEntity}
+)
+
+// validateRanking checks that a ranking lists candidates of the ballot's
+// constituency, each at most once
+func validateRanking(q Querier, b *Ballot) error {
+	invalid := &ValidationError{Code: CodeInvalidRanking, Message: "Ranking must list candidates of this constituency, each at most once", Status: http.StatusUnprocessableEntity}
+	if len(b.Ranking) == 0 {
+		return invalid
+	}
+
+	options, err := BallotOptions(q, b.ElectionID, b.ConstituencyID)
+	if err != nil {
+		return err
+	}
+	standing := make(map[int]bool, len(options))
+	for _, option := range options {
+		if option.Choice == ChoiceCandidate {
+			standing[option.CandidateID] = true
+		}
+	}
+
+	ranked := make(map[int]bool, len(b.Ranking))
+	for _, candidateID := range b.Ranking {
+		if !standing[candidateID] || ranked[candidateID] {
+			return invalid
+		}
+		ranked[candidateID] = true
+	}
+
+	b.CandidateID = 0
+	b.PartyID = 0
+	return nil
+}
+
+// resolveCandidate checks that the chosen candidate stands in the ballot's
+// constituency, looking the candidate up by party when only PartyID is given
+func resolveCandidate(q Querier, b *Ballot) error {
+	if b.CandidateID == 0 {
+		// Multi-seat constituencies may have several candidates per party
+		var candidates int
+		query := "SELECT COUNT(*), COALESCE(MIN(id), 0) FROM candidates WHERE constituency_id = $1 AND party_id = $2"
+		if err := q.QueryRow(query, b.ConstituencyID, b.PartyID).Scan(&candidates, &b.CandidateID); err != nil {
+			return err
+		}
+		switch {
+		case candidates == 0:
+			return errNoCandidate
+		case candidates > 1:
+			return errAmbiguousParty
+		}
+		return nil
+	}
+
+	var standing bool
+	query := "SELECT EXISTS (SELECT 1 FROM candidates WHERE id = $1 AND constituency_id = $2)"
+	if err := q.QueryRow(query, b.CandidateID, b.ConstituencyID).Scan(&standing); err != nil {
+		return err
+	}
+	if !standing {
+		return errCandidateNotFound
+	}
+	return nil
+}
+
+// seal verifies a client-encrypted ballot, or encrypts a plaintext choice at
+// the API edge, so that only the encrypted form is ever stored
+func seal(q Querier, b *Ballot) error {
+	group := elgamal.DefaultGroup
+
+	options, err := BallotOptions(q, b.ElectionID, b.ConstituencyID)
+	if err != nil {
+		return err
+	}
+	publicKey, err := ElectionPublicKey(q, b.ElectionID)
+	if err != nil {
+		return err
+	}
+	context := Context(b.ElectionID, b.ConstituencyID)
+
+	if b.Encrypted == nil {
+		choice := -1
+		for i, option := range options {
+			if option.Choice == b.Choice && option.CandidateID == b.CandidateID {
+				choice = i
+			}
+		}
+		if choice < 0 {
+			return errCandidateNotFound
+		}
+		if b.Encrypted, err = elgamal.EncryptOneHot(group, publicKey, len(options), choice, context); err != nil {
+			return err
+		}
+	} else if err := b.Encrypted.Verify(group, publicKey, len(options), context); err != nil {
+		return &ValidationError{Code: CodeInvalidEncryptedBallot, Message: "Encrypted ballot or its proofs are invalid", Status: http.StatusUnprocessableEntity}
+	}
+
+	b.Choice = ""
+	b.CandidateID = 0
+	return nil
+}
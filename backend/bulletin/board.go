@@ -0,0 +1,297 @@
+// Package bulletin keeps the public, append-only bulletin board of accepted ballots.
+//
+// Every encrypted ballot accepted by CastVote is appended in the same
+// transaction as a board entry. Plaintext ballots and ballot question answers
+// would reveal how someone voted if they appeared in cast order, so they are
+// appended in one shuffled batch when polls close. Each entry hashes the previous entry's hash, so the board cannot be
+// rewritten without changing every later hash, and Merkle roots over the entry
+// hashes are published periodically so that anyone holding an old root can check
+// that the board has only grown. Replaying the board reproduces election_results.
+package bulletin
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/Haste007/E-Voting/Backend/elgamal"
+)
+
+// Querier is satisfied by both *sql.DB and *sql.Tx
+type Querier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Payload is the public content of a ballot: its constituency and either the
+// plaintext candidate, a plaintext NOTA flag, a ranking or the encrypted
+// choice. It never identifies the voter.
+// The closing entry of a re-voting election instead lists the positions of
+// ballots that were replaced by a later ballot and are not counted.
+type Payload struct {
+	ConstituencyID  int                   `json:"constituency_id,omitempty"` // Unset for question answers
+	CandidateID     *int                  `json:"candidate_id,omitempty"`
+	NOTA            bool                  `json:"nota,omitempty"`    // "None of the Above"
+	Ranking         []int                 `json:"ranking,omitempty"` // Candidate IDs, most preferred first
+	EncryptedChoice *elgamal.OneHotBallot `json:"encrypted_choice,omitempty"`
+	Superseded      []int                 `json:"superseded,omitempty"`
+	QuestionID      int                   `json:"question_id,omitempty"` // Set for an answer to a ballot question
+	OptionID        int                   `json:"option_id,omitempty"`
+}
+
+// Entry is one link of an election's hash chain. Payload holds the exact bytes
+// that were hashed, so verifiers must hash it as served rather than re-encode it.
+type Entry struct {
+	Position  int             `json:"position"`
+	Payload   json.RawMessage `json:"payload"`
+	PrevHash  string          `json:"prev_hash"`
+	EntryHash string          `json:"entry_hash"`
+}
+
+// GenesisHash is the previous-hash of an election's first entry, binding the chain to the election
+func GenesisHash(electionID int) string {
+	h := sha256.Sum256([]byte("evoting/bulletin/v1/genesis\nelection:" + strconv.Itoa(electionID)))
+	return hex.EncodeToString(h[:])
+}
+
+// EntryHash returns the hash of an entry: SHA-256 over a version tag, the
+// previous hash, the position and the payload bytes, separated by newlines
+func EntryHash(prevHash string, position int, payload []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "evoting/bulletin/v1\n%s\n%d\n", prevHash, position)
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Append adds a payload to the end of an election's board inside tx. The board
+// head row stays locked until tx ends, so concurrent ballots are chained one
+// after the other and a rolled-back vote leaves no entry behind.
+func Append(tx *sql.Tx, electionID int, payload Payload) (*Entry, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	headQuery := `
+        INSERT INTO bulletin_heads (election_id, size, head_hash)
+        VALUES ($1, 0, $2)
+        ON CONFLICT (election_id) DO NOTHING
+    `
+	if _, err := tx.Exec(headQuery, electionID, GenesisHash(electionID)); err != nil {
+		return nil, err
+	}
+
+	entry := &Entry{Payload: data}
+	lockQuery := "SELECT size, head_hash FROM bulletin_heads WHERE election_id = $1 FOR UPDATE"
+	if err := tx.QueryRow(lockQuery, electionID).Scan(&entry.Position, &entry.PrevHash); err != nil {
+		return nil, err
+	}
+	entry.EntryHash = EntryHash(entry.PrevHash, entry.Position, data)
+
+	insertQuery := `
+        INSERT INTO bulletin_board (election_id, position, payload, prev_hash, entry_hash)
+        VALUES ($1, $2, $3, $4, $5)
+    `
+	if _, err := tx.Exec(insertQuery, electionID, entry.Position, string(data), entry.PrevHash, entry.EntryHash); err != nil {
+		return nil, err
+	}
+
+	updateQuery := "UPDATE bulletin_heads SET size = $2, head_hash = $3 WHERE election_id = $1"
+	if _, err := tx.Exec(updateQuery, electionID, entry.Position+1, entry.EntryHash); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Entries returns up to limit entries of an election's board starting at position from
+func Entries(q Querier, electionID, from, limit int) ([]Entry, error) {
+	query := `
+        SELECT position, payload, prev_hash, entry_hash
+        FROM bulletin_board
+        WHERE election_id = $1 AND position >= $2
+        ORDER BY position
+        LIMIT $3
+    `
+	rows, err := q.Query(query, electionID, from, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var entry Entry
+		var payload string
+		if err := rows.Scan(&entry.Position, &payload, &entry.PrevHash, &entry.EntryHash); err != nil {
+			return nil, err
+		}
+		entry.Payload = json.RawMessage(payload)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// ErrBrokenChain is returned by VerifyChain when an entry does not link to its predecessor
+var ErrBrokenChain = errors.New("bulletin: broken hash chain")
+
+// VerifyChain checks that entries form an unbroken chain from the election's
+// genesis hash, with every entry hash matching its contents
+func VerifyChain(electionID int, entries []Entry) error {
+	prev := GenesisHash(electionID)
+	for i, entry := range entries {
+		if entry.Position != i || entry.PrevHash != prev || EntryHash(prev, i, entry.Payload) != entry.EntryHash {
+			return fmt.Errorf("%w at position %d", ErrBrokenChain, i)
+		}
+		prev = entry.EntryHash
+	}
+	return nil
+}
+
+// Choice identifies one row of election_results; CandidateID is 0 for NOTA
+type Choice struct {
+	ConstituencyID int
+	CandidateID    int
+	NOTA           bool
+}
+
+// counted decodes the ballot payloads of a board, leaving out replaced ballots,
+// the closing entry that lists them and answers to ballot questions
+func counted(entries []Entry) ([]Payload, error) {
+	payloads, err := decoded(entries)
+	if err != nil {
+		return nil, err
+	}
+	var ballots []Payload
+	for _, payload := range payloads {
+		if payload.QuestionID == 0 {
+			ballots = append(ballots, payload)
+		}
+	}
+	return ballots, nil
+}
+
+// decoded decodes every payload of a board except replaced ballots and the
+// closing entry that lists them
+func decoded(entries []Entry) ([]Payload, error) {
+	payloads := make([]Payload, len(entries))
+	skip := make(map[int]bool)
+	for i, entry := range entries {
+		if err := json.Unmarshal(entry.Payload, &payloads[i]); err != nil {
+			return nil, err
+		}
+		if payloads[i].Superseded != nil {
+			skip[entry.Position] = true
+			for _, position := range payloads[i].Superseded {
+				skip[position] = true
+			}
+		}
+	}
+
+	var ballots []Payload
+	for i, entry := range entries {
+		if !skip[entry.Position] {
+			ballots = append(ballots, payloads[i])
+		}
+	}
+	return ballots, nil
+}
+
+// Answers replays the ballot question answers on a board and returns the
+// number of answers per question and option, the counts stored in question_results
+func Answers(entries []Entry) (map[int]map[int]int, error) {
+	payloads, err := decoded(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	answers := make(map[int]map[int]int)
+	for _, payload := range payloads {
+		if payload.QuestionID == 0 {
+			continue
+		}
+		if answers[payload.QuestionID] == nil {
+			answers[payload.QuestionID] = make(map[int]int)
+		}
+		answers[payload.QuestionID][payload.OptionID]++
+	}
+	return answers, nil
+}
+
+// Count replays a plaintext election's board and returns the counted votes per
+// constituency and candidate or NOTA, the numbers EndElection writes to election_results
+func Count(entries []Entry) (map[Choice]int, error) {
+	ballots, err := counted(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[Choice]int)
+	for _, payload := range ballots {
+		switch {
+		case payload.NOTA:
+			counts[Choice{ConstituencyID: payload.ConstituencyID, NOTA: true}]++
+		case payload.CandidateID != nil:
+			counts[Choice{ConstituencyID: payload.ConstituencyID, CandidateID: *payload.CandidateID}]++
+		default:
+			return nil, errors.New("bulletin: ballot has no plaintext choice")
+		}
+	}
+	return counts, nil
+}
+
+// Rankings replays a ranked election's board and returns the counted rankings
+// per constituency, in board order, the input of the tabulation package
+func Rankings(entries []Entry) (map[int][][]int, error) {
+	ballots, err := counted(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	rankings := make(map[int][][]int)
+	for _, payload := range ballots {
+		if payload.Ranking == nil {
+			return nil, errors.New("bulletin: ballot has no ranking")
+		}
+		rankings[payload.ConstituencyID] = append(rankings[payload.ConstituencyID], payload.Ranking)
+	}
+	return rankings, nil
+}
+
+// Aggregate replays an encrypted election's board and multiplies the ballots of
+// each constituency option by option. The results must equal the aggregates
+// published with the encrypted tallies, whose decryption proofs give the counts.
+func Aggregate(entries []Entry) (map[int][]*elgamal.Ciphertext, error) {
+	ballots, err := counted(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	group := elgamal.DefaultGroup
+	totals := make(map[int][]*elgamal.Ciphertext)
+	for _, payload := range ballots {
+		if payload.EncryptedChoice == nil {
+			return nil, errors.New("bulletin: ballot has no encrypted choice")
+		}
+
+		choices := payload.EncryptedChoice.Choices
+		sums, ok := totals[payload.ConstituencyID]
+		if !ok {
+			sums = make([]*elgamal.Ciphertext, len(choices))
+			for i := range sums {
+				sums[i] = elgamal.Zero()
+			}
+			totals[payload.ConstituencyID] = sums
+		}
+		if len(sums) != len(choices) {
+			return nil, fmt.Errorf("bulletin: ballot in constituency %d has %d choices, expected %d", payload.ConstituencyID, len(choices), len(sums))
+		}
+		for i, ct := range choices {
+			sums[i] = sums[i].Add(group, ct)
+		}
+	}
+	return totals, nil
+}
@@ -0,0 +1,89 @@
+package bulletin
+
+import (
+	"bytes"
+	"crypto/sha256"
+)
+
+// The Merkle tree follows RFC 6962 (Certificate Transparency): leaves and
+// interior nodes are hashed with different prefixes, and a tree of n leaves is
+// split at the largest power of two smaller than n.
+
+func leafHash(leaf []byte) []byte {
+	h := sha256.Sum256(append([]byte{0x00}, leaf...))
+	return h[:]
+}
+
+func nodeHash(left, right []byte) []byte {
+	data := make([]byte, 0, 1+len(left)+len(right))
+	data = append(data, 0x01)
+	data = append(data, left...)
+	data = append(data, right...)
+	h := sha256.Sum256(data)
+	return h[:]
+}
+
+// splitPoint returns the largest power of two smaller than n
+func splitPoint(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// MerkleRoot returns the root hash of the tree over leaves; the empty tree
+// hashes to SHA-256 of the empty string
+func MerkleRoot(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		h := sha256.Sum256(nil)
+		return h[:]
+	case 1:
+		return leafHash(leaves[0])
+	}
+	k := splitPoint(len(leaves))
+	return nodeHash(MerkleRoot(leaves[:k]), MerkleRoot(leaves[k:]))
+}
+
+// InclusionProof returns the audit path for leaves[index], from the leaf's
+// sibling up to the root's child
+func InclusionProof(leaves [][]byte, index int) [][]byte {
+	if len(leaves) <= 1 {
+		return nil
+	}
+	k := splitPoint(len(leaves))
+	if index < k {
+		return append(InclusionProof(leaves[:k], index), MerkleRoot(leaves[k:]))
+	}
+	return append(InclusionProof(leaves[k:], index-k), MerkleRoot(leaves[:k]))
+}
+
+// VerifyInclusion checks that leaf sits at index in a tree of size leaves with
+// the given root, using the audit path from InclusionProof
+func VerifyInclusion(leaf []byte, index, size int, proof [][]byte, root []byte) bool {
+	if index < 0 || index >= size {
+		return false
+	}
+	hash, ok := rootFromPath(leafHash(leaf), index, size, proof)
+	return ok && bytes.Equal(hash, root)
+}
+
+// rootFromPath recomputes the root of a size-leaf tree from a node hash at
+// index and its audit path, mirroring InclusionProof's recursion
+func rootFromPath(hash []byte, index, size int, proof [][]byte) ([]byte, bool) {
+	if size == 1 {
+		return hash, len(proof) == 0
+	}
+	if len(proof) == 0 {
+		return nil, false
+	}
+	sibling, rest := proof[len(proof)-1], proof[:len(proof)-1]
+	k := splitPoint(size)
+	if index < k {
+		left, ok := rootFromPath(hash, index, k, rest)
+		return nodeHash(left, sibling), ok
+	}
+	right, ok := rootFromPath(hash, index-k, size-k, rest)
+	return nodeHash(sibling, right), ok
+}
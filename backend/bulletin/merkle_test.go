@@ -0,0 +1,87 @@
+package bulletin
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+// referenceLeaves are the leaves of the RFC 6962 reference tests
+func referenceLeaves(t *testing.T) [][]byte {
+	t.Helper()
+	var leaves [][]byte
+	for _, h := range []string{"", "00", "10", "2021", "3031", "40414243", "5051525354555657", "606162636465666768696a6b6c6d6e6f"} {
+		leaf, err := hex.DecodeString(h)
+		if err != nil {
+			t.Fatalf("decoding leaf %q: %v", h, err)
+		}
+		leaves = append(leaves, leaf)
+	}
+	return leaves
+}
+
+// TestMerkleRoot checks roots against the RFC 6962 reference tree
+func TestMerkleRoot(t *testing.T) {
+	leaves := referenceLeaves(t)
+	for _, tt := range []struct {
+		size int
+		root string
+	}{
+		{0, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{1, "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d"},
+		{2, "fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125"},
+		{8, "5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328"},
+	} {
+		if got := hex.EncodeToString(MerkleRoot(leaves[:tt.size])); got != tt.root {
+			t.Errorf("root of %d leaves = %s, want %s", tt.size, got, tt.root)
+		}
+	}
+}
+
+// TestVerifyInclusion checks the proof of every leaf of trees of every size up
+// to 17, and that a proof altered in any way no longer verifies
+func TestVerifyInclusion(t *testing.T) {
+	var leaves [][]byte
+	for i := 0; i < 17; i++ {
+		leaves = append(leaves, []byte(fmt.Sprintf("entry %d", i)))
+	}
+
+	for size := 1; size <= len(leaves); size++ {
+		tree := leaves[:size]
+		root := MerkleRoot(tree)
+		for index := range tree {
+			proof := InclusionProof(tree, index)
+			if !VerifyInclusion(tree[index], index, size, proof, root) {
+				t.Errorf("size %d: proof of leaf %d rejected", size, index)
+				continue
+			}
+
+			if VerifyInclusion([]byte("forged"), index, size, proof, root) {
+				t.Errorf("size %d: proof of leaf %d accepted another leaf", size, index)
+			}
+			if other := (index + 1) % size; other != index && VerifyInclusion(tree[index], other, size, proof, root) {
+				t.Errorf("size %d: proof of leaf %d accepted at index %d", size, index, other)
+			}
+			if VerifyInclusion(tree[index], index, size, proof, MerkleRoot(leaves[:size-1])) {
+				t.Errorf("size %d: proof of leaf %d accepted against another root", size, index)
+			}
+			for i := range proof {
+				corrupted := append([][]byte(nil), proof...)
+				corrupted[i] = append([]byte{corrupted[i][0] ^ 1}, corrupted[i][1:]...)
+				if VerifyInclusion(tree[index], index, size, corrupted, root) {
+					t.Errorf("size %d: proof of leaf %d accepted with hash %d corrupted", size, index, i)
+				}
+			}
+			if len(proof) > 0 && VerifyInclusion(tree[index], index, size, proof[:len(proof)-1], root) {
+				t.Errorf("size %d: truncated proof of leaf %d accepted", size, index)
+			}
+			if VerifyInclusion(tree[index], index, size, append(append([][]byte(nil), proof...), root), root) {
+				t.Errorf("size %d: proof of leaf %d accepted with an extra hash", size, index)
+			}
+		}
+
+		if VerifyInclusion(tree[0], -1, size, nil, root) || VerifyInclusion(tree[0], size, size, nil, root) {
+			t.Errorf("size %d: index out of range accepted", size)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package bulletin
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Haste007/E-Voting/Backend/utils"
+)
+
+// DefaultRootInterval is how often new roots are published when BULLETIN_ROOT_INTERVAL
+// is not set. It matches the hour granularity of ballot_box.cast_hour, so root
+// publication times reveal no more about when a ballot was cast than the ballot does.
+const DefaultRootInterval = time.Hour
+
+// Root is a published Merkle root over the first Size entries of a board
+type Root struct {
+	Size        int       `json:"size"`
+	Root        string    `json:"root"`
+	HeadHash    string    `json:"head_hash"` // Hash of entry Size-1, tying the root to the chain
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// leaves loads the decoded entry hashes of the first size entries of a board
+func leaves(q Querier, electionID, size int) ([][]byte, error) {
+	rows, err := q.Query("SELECT entry_hash FROM bulletin_board WHERE election_id = $1 AND position < $2 ORDER BY position", electionID, size)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes [][]byte
+	for rows.Next() {
+		var entryHash string
+		if err := rows.Scan(&entryHash); err != nil {
+			return nil, err
+		}
+		leaf, err := hex.DecodeString(entryHash)
+		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, leaf)
+	}
+	return hashes, rows.Err()
+}
+
+// PublishRoot records the Merkle root of an election's whole board, unless a
+// root of that size exists already or the board is empty
+func PublishRoot(tx *sql.Tx, electionID int) error {
+	var size int
+	var headHash string
+	err := tx.QueryRow("SELECT size, head_hash FROM bulletin_heads WHERE election_id = $1 FOR UPDATE", electionID).Scan(&size, &headHash)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	hashes, err := leaves(tx, electionID, size)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO bulletin_roots (election_id, size, root, head_hash)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (election_id, size) DO NOTHING
+    `
+	_, err = tx.Exec(query, electionID, size, hex.EncodeToString(MerkleRoot(hashes)), headHash)
+	return err
+}
+
+// PublishPendingRoots publishes a root for every board that grew since its last root
+func PublishPendingRoots() error {
+	query := `
+        SELECT h.election_id
+        FROM bulletin_heads h
+        WHERE h.size > COALESCE((SELECT MAX(r.size) FROM bulletin_roots r WHERE r.election_id = h.election_id), 0)
+    `
+	rows, err := utils.DB.Query(query)
+	if err != nil {
+		return err
+	}
+	var electionIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		electionIDs = append(electionIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, electionID := range electionIDs {
+		tx, err := utils.DB.Begin()
+		if err != nil {
+			return err
+		}
+		if err := PublishRoot(tx, electionID); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartRootPublisher publishes pending roots every BULLETIN_ROOT_INTERVAL (a Go
+// duration such as "30m") in the background. Running it on several replicas
+// is safe; a root is only stored once per board size.
+func StartRootPublisher() error {
+	interval := DefaultRootInterval
+	if value := os.Getenv("BULLETIN_ROOT_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		interval = parsed
+	}
+
+	go func() {
+		for range time.Tick(interval) {
+			if err := PublishPendingRoots(); err != nil {
+				log.Println("Error publishing bulletin board roots:", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// Roots returns every published root of an election's board, oldest first
+func Roots(q Querier, electionID int) ([]Root, error) {
+	rows, err := q.Query("SELECT size, root, head_hash, published_at FROM bulletin_roots WHERE election_id = $1 ORDER BY size", electionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roots := []Root{}
+	for rows.Next() {
+		var root Root
+		if err := rows.Scan(&root.Size, &root.Root, &root.HeadHash, &root.PublishedAt); err != nil {
+			return nil, err
+		}
+		roots = append(roots, root)
+	}
+	return roots, rows.Err()
+}
+
+// Proof shows that an entry is included in a published root
+type Proof struct {
+	Position  int      `json:"position"`
+	EntryHash string   `json:"entry_hash"`
+	Root      Root     `json:"root"`
+	AuditPath []string `json:"audit_path"` // RFC 6962 audit path, leaf sibling first
+}
+
+// InclusionProofFor builds the proof that the entry at position is in the
+// published root of the given size, or in the latest root when size is 0.
+// It returns sql.ErrNoRows if no such root covers the entry.
+func InclusionProofFor(q Querier, electionID, position, size int) (*Proof, error) {
+	proof := &Proof{Position: position, AuditPath: []string{}}
+	rootQuery := `
+        SELECT size, root, head_hash, published_at
+        FROM bulletin_roots
+        WHERE election_id = $1 AND size > $2 AND ($3 = 0 OR size = $3)
+        ORDER BY size DESC
+        LIMIT 1
+    `
+	root := &proof.Root
+	if err := q.QueryRow(rootQuery, electionID, position, size).Scan(&root.Size, &root.Root, &root.HeadHash, &root.PublishedAt); err != nil {
+		return nil, err
+	}
+
+	hashes, err := leaves(q, electionID, root.Size)
+	if err != nil {
+		return nil, err
+	}
+	proof.EntryHash = hex.EncodeToString(hashes[position])
+	for _, node := range InclusionProof(hashes, position) {
+		proof.AuditPath = append(proof.AuditPath, hex.EncodeToString(node))
+	}
+	return proof, nil
+}
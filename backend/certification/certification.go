@@ -0,0 +1,279 @@
+// Package certification has returning officers sign the result of each
+// constituency. The backend builds a canonical document from the
+// constituency's current tally, the officer assigned to the constituency signs
+// its exact bytes offline with an Ed25519 key registered to their account, and
+// the signed document is stored for anyone to download and verify. An
+// election can only be certified once every constituency carries a signature
+// over its latest tally snapshot; a recount or correction after signing makes
+// the signature stale until the officer signs again.
+package certification
+
+import (
+	"crypto/ed25519"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/Haste007/E-Voting/Backend/ballot"
+	"github.com/Haste007/E-Voting/Backend/lifecycle"
+	"github.com/Haste007/E-Voting/Backend/models"
+	"github.com/Haste007/E-Voting/Backend/tally"
+)
+
+// Format names the layout of a result document and is part of what is signed
+const Format = "evoting/constituency-result/v1"
+
+var (
+	ErrNotInElection  = errors.New("constituency is not part of this election")
+	ErrNotOfficer     = errors.New("admin is not a returning officer")
+	ErrNotAssigned    = errors.New("not the returning officer of this constituency")
+	ErrNoKey          = errors.New("no signing key is registered to this returning officer")
+	ErrInvalidKey     = errors.New("signing key must be a base64 Ed25519 public key")
+	ErrBadSignature   = errors.New("signature does not verify against the result document")
+	ErrAlreadySigned  = errors.New("the current result of this constituency is already signed")
+	ErrIncomplete     = errors.New("every constituency's current result must be signed by its returning officer first")
+	ErrNoSignedResult = errors.New("constituency result has not been signed")
+)
+
+// Document is the canonical result of one constituency. It is serialised as
+// compact JSON with the fields in this order and the lines ordered by
+// candidate ID, NOTA last.
+type Document struct {
+	Format         string         `json:"format"`
+	ElectionID     int            `json:"election_id"`
+	Election       string         `json:"election"`
+	ConstituencyID int            `json:"constituency_id"`
+	Constituency   string         `json:"constituency"`
+	Tier           string         `json:"tier"`
+	Seats          int            `json:"seats"`
+	TallyVersion   int            `json:"tally_version"` // Snapshot in tally_runs the result comes from
+	InputHash      string         `json:"input_hash"`    // Hash of the ballots counted, from that snapshot
+	Lines          []DocumentLine `json:"lines"`
+}
+
+// DocumentLine is one result line of a Document
+type DocumentLine struct {
+	Choice      string `json:"choice"`
+	CandidateID *int   `json:"candidate_id"` // NULL for NOTA
+	Candidate   string `json:"candidate"`    // Empty for NOTA
+	Party       string `json:"party"`        // "Independent" for independents, empty for NOTA
+	Votes       int    `json:"votes"`
+	Elected     bool   `json:"elected"`
+}
+
+// Build returns the canonical bytes of a constituency's current result
+func Build(q ballot.Querier, electionID, constituencyID int) ([]byte, Document, error) {
+	document := Document{Format: Format, ElectionID: electionID, ConstituencyID: constituencyID, Lines: []DocumentLine{}}
+	query := `
+        SELECT e.name, c.name, c.tier, c.seats
+        FROM election_constituencies ec
+        JOIN elections e ON e.id = ec.election_id
+        JOIN constituencies c ON c.id = ec.constituency_id
+        WHERE ec.election_id = $1 AND ec.constituency_id = $2
+    `
+	err := q.QueryRow(query, electionID, constituencyID).Scan(&document.Election, &document.Constituency, &document.Tier, &document.Seats)
+	if err == sql.ErrNoRows {
+		return nil, Document{}, ErrNotInElection
+	} else if err != nil {
+		return nil, Document{}, err
+	}
+
+	version, snapshot, err := tally.Latest(q, electionID, constituencyID)
+	if err != nil {
+		return nil, Document{}, err
+	}
+	document.TallyVersion = version
+	document.InputHash = snapshot.InputHash
+
+	lineQuery := `
+        SELECT er.choice, er.candidate_id, COALESCE(ci.name, ''),
+               CASE WHEN er.choice = 'nota' THEN '' ELSE COALESCE(p.name, 'Independent') END,
+               er.total_votes, er.elected
+        FROM election_results er
+        LEFT JOIN candidates ca ON ca.id = er.candidate_id
+        LEFT JOIN citizens ci ON ci.id = ca.citizen_id
+        LEFT JOIN parties p ON p.id = ca.party_id
+        WHERE er.election_id = $1 AND er.constituency_id = $2
+        ORDER BY er.candidate_id NULLS LAST
+    `
+	rows, err := q.Query(lineQuery, electionID, constituencyID)
+	if err != nil {
+		return nil, Document{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var line DocumentLine
+		if err := rows.Scan(&line.Choice, &line.CandidateID, &line.Candidate, &line.Party, &line.Votes, &line.Elected); err != nil {
+			return nil, Document{}, err
+		}
+		document.Lines = append(document.Lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, Document{}, err
+	}
+
+	data, err := json.Marshal(document)
+	return data, document, err
+}
+
+// SignedDocument is a result document with everything needed to verify it offline
+type SignedDocument struct {
+	ElectionID     int    `json:"election_id"`
+	ConstituencyID int    `json:"constituency_id"`
+	TallyVersion   int    `json:"tally_version"`
+	Document       string `json:"document"`   // Exact bytes that were signed
+	Signature      string `json:"signature"`  // Base64 Ed25519 signature over Document
+	PublicKey      string `json:"public_key"` // Base64 Ed25519 key of the signer
+	KeyID          int    `json:"key_id"`
+	Signer         string `json:"signer"`
+	SignedAt       string `json:"signed_at"`
+	Current        bool   `json:"current"` // Signed over the constituency's latest tally snapshot
+}
+
+// Verify checks the signature of a signed document with nothing but the document itself
+func (d SignedDocument) Verify() error {
+	publicKey, err := decodeKey(d.PublicKey)
+	if err != nil {
+		return err
+	}
+	signature, err := base64.StdEncoding.DecodeString(d.Signature)
+	if err != nil || !ed25519.Verify(publicKey, []byte(d.Document), signature) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// decodeKey parses a base64 Ed25519 public key
+func decodeKey(value string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// RegisterKey makes publicKey, in base64, the signing key of a returning
+// officer. A key it replaces is revoked but kept, so signatures made with it
+// still verify.
+func RegisterKey(tx *sql.Tx, adminID int, publicKey, registeredBy string) (int, error) {
+	if _, err := decodeKey(publicKey); err != nil {
+		return 0, err
+	}
+	if err := requireRole(tx, adminID, "FOR UPDATE"); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec("UPDATE signing_keys SET revoked_at = NOW() WHERE admin_id = $1 AND revoked_at IS NULL", adminID); err != nil {
+		return 0, err
+	}
+	var id int
+	query := "INSERT INTO signing_keys (admin_id, public_key, registered_by) VALUES ($1, $2, $3) RETURNING id"
+	err := tx.QueryRow(query, adminID, publicKey, registeredBy).Scan(&id)
+	return id, err
+}
+
+// Assign makes an admin the returning officer of a constituency in an election
+func Assign(tx *sql.Tx, electionID, constituencyID, adminID int, assignedBy string) error {
+	if _, err := lifecycle.Require(tx, electionID, lifecycle.OpAssignOfficer, "FOR UPDATE"); err != nil {
+		return err
+	}
+	var ok bool
+	query := "SELECT EXISTS (SELECT 1 FROM election_constituencies WHERE election_id = $1 AND constituency_id = $2)"
+	if err := tx.QueryRow(query, electionID, constituencyID).Scan(&ok); err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotInElection
+	}
+	if err := requireRole(tx, adminID, ""); err != nil {
+		return err
+	}
+
+	assignQuery := `
+        INSERT INTO returning_officers (election_id, constituency_id, admin_id, assigned_by)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (election_id, constituency_id) DO UPDATE
+        SET admin_id = EXCLUDED.admin_id, assigned_by = EXCLUDED.assigned_by, assigned_at = NOW()
+    `
+	_, err := tx.Exec(assignQuery, electionID, constituencyID, adminID, assignedBy)
+	return err
+}
+
+// OfficerDocument returns the document the returning officer of a constituency is asked to sign
+func OfficerDocument(q ballot.Querier, electionID, constituencyID, adminID int) ([]byte, Document, error) {
+	if err := requireOfficer(q, electionID, constituencyID, adminID); err != nil {
+		return nil, Document{}, err
+	}
+	return Build(q, electionID, constituencyID)
+}
+
+// Sign stores a returning officer's signature over the current result of
+// their constituency. The document is rebuilt and the signature checked
+// against the officer's registered key before it is accepted.
+func Sign(tx *sql.Tx, electionID, constituencyID int, officer models.Admin, signature string) (SignedDocument, error) {
+	if _, err := lifecycle.Require(tx, electionID, lifecycle.OpSignResult, "FOR UPDATE"); err != nil {
+		return SignedDocument{}, err
+	}
+	if err := requireOfficer(tx, electionID, constituencyID, officer.ID); err != nil {
+		return SignedDocument{}, err
+	}
+
+	signed := SignedDocument{ElectionID: electionID, ConstituencyID: constituencyID, Signer: officer.Username, Signature: signature, Current: true}
+	err := tx.QueryRow("SELECT id, public_key FROM signing_keys WHERE admin_id = $1 AND revoked_at IS NULL", officer.ID).Scan(&signed.KeyID, &signed.PublicKey)
+	if err == sql.ErrNoRows {
+		return SignedDocument{}, ErrNoKey
+	} else if err != nil {
+		return SignedDocument{}, err
+	}
+
+	data, document, err := Build(tx, electionID, constituencyID)
+	if err != nil {
+		return SignedDocument{}, err
+	}
+	signed.Document = string(data)
+	signed.TallyVersion = document.TallyVersion
+	if err := signed.Verify(); err != nil {
+		return SignedDocument{}, err
+	}
+
+	query := `
+        INSERT INTO result_certifications (election_id, constituency_id, tally_version, key_id, signer, document, signature)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        ON CONFLICT (election_id, constituency_id, tally_version) DO NOTHING
+        RETURNING signed_at
+    `
+	err = tx.QueryRow(query, electionID, constituencyID, signed.TallyVersion, signed.KeyID, signed.Signer, signed.Document, signed.Signature).Scan(&signed.SignedAt)
+	if err == sql.ErrNoRows {
+		return SignedDocument{}, ErrAlreadySigned
+	}
+	return signed, err
+}
+
+// requireRole returns ErrNotOfficer unless adminID is a returning officer account
+func requireRole(q ballot.Querier, adminID int, lock string) error {
+	var role string
+	err := q.QueryRow("SELECT role FROM admins WHERE id = $1 "+lock, adminID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return ErrNotOfficer
+	} else if err != nil {
+		return err
+	}
+	if role != models.RoleReturningOfficer {
+		return ErrNotOfficer
+	}
+	return nil
+}
+
+// requireOfficer returns ErrNotAssigned unless adminID is the returning officer of the constituency
+func requireOfficer(q ballot.Querier, electionID, constituencyID, adminID int) error {
+	var ok bool
+	query := "SELECT EXISTS (SELECT 1 FROM returning_officers WHERE election_id = $1 AND constituency_id = $2 AND admin_id = $3)"
+	if err := q.QueryRow(query, electionID, constituencyID, adminID).Scan(&ok); err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotAssigned
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package certification
+
+import "github.com/Haste007/E-Voting/Backend/ballot"
+
+// latestVersion is the SQL for the latest tally snapshot covering ec.constituency_id, 0 if there is none
+const latestVersion = `
+    (SELECT COALESCE(MAX(t.version), 0) FROM tally_runs t
+     WHERE t.election_id = ec.election_id AND (t.constituency_id IS NULL OR t.constituency_id = ec.constituency_id))
+`
+
+// Status is the certification state of one constituency of an election
+type Status struct {
+	ConstituencyID   int     `json:"constituency_id"`
+	ReturningOfficer *string `json:"returning_officer"` // Null until one is assigned
+	Signed           bool    `json:"signed"`            // The latest tally snapshot is signed
+	Signer           *string `json:"signer"`            // Of the most recent signature, current or not
+	SignedAt         *string `json:"signed_at"`
+	TallyVersion     *int    `json:"tally_version"` // Snapshot the most recent signature covers
+}
+
+// Statuses returns the certification state of each of an election's constituencies
+func Statuses(q ballot.Querier, electionID int) ([]Status, error) {
+	query := `
+        SELECT ec.constituency_id, a.username, rc.signer, rc.signed_at, rc.tally_version,
+               COALESCE(rc.tally_version = ` + latestVersion + `, FALSE)
+        FROM election_constituencies ec
+        LEFT JOIN returning_officers ro ON ro.election_id = ec.election_id AND ro.constituency_id = ec.constituency_id
+        LEFT JOIN admins a ON a.id = ro.admin_id
+        LEFT JOIN LATERAL (
+            SELECT signer, signed_at, tally_version FROM result_certifications r
+            WHERE r.election_id = ec.election_id AND r.constituency_id = ec.constituency_id
+            ORDER BY r.tally_version DESC
+            LIMIT 1
+        ) rc ON TRUE
+        WHERE ec.election_id = $1
+        ORDER BY ec.constituency_id
+    `
+	rows, err := q.Query(query, electionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statuses := []Status{}
+	for rows.Next() {
+		var status Status
+		if err := rows.Scan(&status.ConstituencyID, &status.ReturningOfficer, &status.Signer, &status.SignedAt, &status.TallyVersion, &status.Signed); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, rows.Err()
+}
+
+// RequireComplete returns ErrIncomplete unless the latest tally of every
+// constituency of the election is signed
+func RequireComplete(q ballot.Querier, electionID int) error {
+	statuses, err := Statuses(q, electionID)
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		if !status.Signed {
+			return ErrIncomplete
+		}
+	}
+	return nil
+}
+
+// Documents returns the signed result documents of a constituency, newest first
+func Documents(q ballot.Querier, electionID, constituencyID int) ([]SignedDocument, error) {
+	query := `
+        SELECT r.election_id, r.constituency_id, r.tally_version, r.document, r.signature, k.public_key, k.id, r.signer, r.signed_at,
+               r.tally_version = ` + latestVersion + `
+        FROM result_certifications r
+        JOIN election_constituencies ec ON ec.election_id = r.election_id AND ec.constituency_id = r.constituency_id
+        JOIN signing_keys k ON k.id = r.key_id
+        WHERE r.election_id = $1 AND r.constituency_id = $2
+        ORDER BY r.tally_version DESC
+    `
+	rows, err := q.Query(query, electionID, constituencyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	documents := []SignedDocument{}
+	for rows.Next() {
+		var d SignedDocument
+		if err := rows.Scan(&d.ElectionID, &d.ConstituencyID, &d.TallyVersion, &d.Document, &d.Signature, &d.PublicKey, &d.KeyID, &d.Signer, &d.SignedAt, &d.Current); err != nil {
+			return nil, err
+		}
+		documents = append(documents, d)
+	}
+	return documents, rows.Err()
+}
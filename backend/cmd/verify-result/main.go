@@ -0,0 +1,64 @@
+// Command verify-result checks a signed constituency result downloaded from
+// GET /api/elections/:id/constituencies/:constituencyId/signed-result without
+// contacting the backend:
+//
+//	go run ./cmd/verify-result [-key <base64 public key>] result.json
+//
+// Pass -key with the returning officer's key as published by the election
+// authority; otherwise only the key embedded in the file is checked.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Haste007/E-Voting/Backend/certification"
+)
+
+func main() {
+	key := flag.String("key", "", "expected base64 Ed25519 public key of the returning officer")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: verify-result [-key <base64 public key>] result.json")
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	var signed certification.SignedDocument
+	if err := json.Unmarshal(data, &signed); err != nil {
+		fmt.Fprintln(os.Stderr, "not a signed result:", err)
+		os.Exit(1)
+	}
+	if *key != "" && *key != signed.PublicKey {
+		fmt.Fprintln(os.Stderr, "FAIL: signed with a different key than expected")
+		os.Exit(1)
+	}
+	if err := signed.Verify(); err != nil {
+		fmt.Fprintln(os.Stderr, "FAIL:", err)
+		os.Exit(1)
+	}
+
+	var document certification.Document
+	if err := json.Unmarshal([]byte(signed.Document), &document); err != nil {
+		fmt.Fprintln(os.Stderr, "FAIL: signed bytes are not a result document:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("OK: %s, %s (tally version %d) signed by %s at %s\n", document.Election, document.Constituency, document.TallyVersion, signed.Signer, signed.SignedAt)
+	for _, line := range document.Lines {
+		name := line.Candidate
+		if line.CandidateID == nil {
+			name = "None of the Above"
+		}
+		mark := ""
+		if line.Elected {
+			mark = " (elected)"
+		}
+		fmt.Printf("  %-40s %8d%s\n", name, line.Votes, mark)
+	}
+}
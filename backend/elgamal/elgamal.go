@@ -0,0 +1,345 @@
+// Package elgamal implements exponential ElGamal encryption with the
+// zero-knowledge proofs needed for homomorphically tallied one-hot ballots.
+//
+// A vote m is encrypted as (g^r, g^m * h^r). Multiplying ciphertexts adds the
+// votes inside them, so a constituency's ballots can be summed without being
+// opened, and only the aggregate is ever decrypted.
+package elgamal
+
+import (
+	"errors"
+	"math/big"
+)
+
+// PublicKey is an election public key h = g^x
+type PublicKey struct {
+	H *Int `json:"h"`
+}
+
+// GenerateKey returns a new secret exponent x and the public key g^x
+func GenerateKey(group *Group) (*big.Int, *PublicKey, error) {
+	x, err := group.RandomScalar()
+	if err != nil {
+		return nil, nil, err
+	}
+	return x, &PublicKey{H: wrap(group.exp(group.G, x))}, nil
+}
+
+// Ciphertext is an exponential ElGamal ciphertext (g^r, g^m * h^r)
+type Ciphertext struct {
+	Alpha *Int `json:"alpha"`
+	Beta  *Int `json:"beta"`
+}
+
+// Zero returns the trivial encryption of 0, the identity for Add
+func Zero() *Ciphertext {
+	return &Ciphertext{Alpha: wrap(big.NewInt(1)), Beta: wrap(big.NewInt(1))}
+}
+
+// Encrypt encrypts m under pk and also returns the randomness used
+func Encrypt(group *Group, pk *PublicKey, m int64) (*Ciphertext, *big.Int, error) {
+	r, err := group.RandomScalar()
+	if err != nil {
+		return nil, nil, err
+	}
+	alpha := group.exp(group.G, r)
+	beta := group.mul(group.exp(group.G, big.NewInt(m)), group.exp(pk.H.Big(), r))
+	return &Ciphertext{Alpha: wrap(alpha), Beta: wrap(beta)}, r, nil
+}
+
+// Add returns a ciphertext of the sum of the plaintexts of c and d
+func (c *Ciphertext) Add(group *Group, d *Ciphertext) *Ciphertext {
+	return &Ciphertext{
+		Alpha: wrap(group.mul(c.Alpha.Big(), d.Alpha.Big())),
+		Beta:  wrap(group.mul(c.Beta.Big(), d.Beta.Big())),
+	}
+}
+
+// valid reports whether both components are group elements
+func (c *Ciphertext) valid(group *Group) bool {
+	return c != nil && c.Alpha != nil && c.Beta != nil && group.isElement(c.Alpha.Big()) && group.isElement(c.Beta.Big())
+}
+
+// EqualityProof is a Chaum-Pedersen proof that log_g(u) = log_y(v)
+type EqualityProof struct {
+	A *Int `json:"a"` // g^w
+	B *Int `json:"b"` // y^w
+	R *Int `json:"r"` // w + c*secret mod q
+}
+
+func proveEquality(group *Group, domain, context string, g, y, u, v, secret *big.Int) (*EqualityProof, error) {
+	w, err := group.RandomScalar()
+	if err != nil {
+		return nil, err
+	}
+	a := group.exp(g, w)
+	b := group.exp(y, w)
+	c := group.challenge(domain, context, g, y, u, v, a, b)
+	r := new(big.Int).Mul(c, secret)
+	r.Add(r, w).Mod(r, group.Q)
+	return &EqualityProof{A: wrap(a), B: wrap(b), R: wrap(r)}, nil
+}
+
+func (p *EqualityProof) verify(group *Group, domain, context string, g, y, u, v *big.Int) bool {
+	if p == nil || p.A == nil || p.B == nil || p.R == nil {
+		return false
+	}
+	a, b, r := p.A.Big(), p.B.Big(), p.R.Big()
+	if !group.isElement(a) || !group.isElement(b) || !group.isScalar(r) {
+		return false
+	}
+	c := group.challenge(domain, context, g, y, u, v, a, b)
+	return group.exp(g, r).Cmp(group.mul(a, group.exp(u, c))) == 0 &&
+		group.exp(y, r).Cmp(group.mul(b, group.exp(v, c))) == 0
+}
+
+// ZeroOneProof is a disjunctive Chaum-Pedersen proof that a ciphertext encrypts 0 or 1
+type ZeroOneProof struct {
+	A0 *Int `json:"a0"`
+	B0 *Int `json:"b0"`
+	A1 *Int `json:"a1"`
+	B1 *Int `json:"b1"`
+	C0 *Int `json:"c0"`
+	C1 *Int `json:"c1"`
+	R0 *Int `json:"r0"`
+	R1 *Int `json:"r1"`
+}
+
+const (
+	domainZeroOne    = "evoting/zero-one"
+	domainSum        = "evoting/one-hot-sum"
+	domainDecryption = "evoting/decryption"
+)
+
+// proveZeroOne proves ct = (g^r, g^m h^r) with m in {0, 1}. The branch for the
+// real m is proven honestly and the other branch is simulated.
+func proveZeroOne(group *Group, pk *PublicKey, ct *Ciphertext, m int, r *big.Int, context string) (*ZeroOneProof, error) {
+	alpha, beta, h := ct.Alpha.Big(), ct.Beta.Big(), pk.H.Big()
+	// betaOver[j] = beta / g^j, which equals h^r for the true branch j = m
+	betaOver := [2]*big.Int{beta, group.div(beta, group.G)}
+
+	var a, b, c, z [2]*big.Int
+	fake := 1 - m
+
+	// Simulate the false branch with a chosen challenge and response
+	cf, err := group.RandomScalar()
+	if err != nil {
+		return nil, err
+	}
+	zf, err := group.RandomScalar()
+	if err != nil {
+		return nil, err
+	}
+	c[fake], z[fake] = cf, zf
+	a[fake] = group.div(group.exp(group.G, zf), group.exp(alpha, cf))
+	b[fake] = group.div(group.exp(h, zf), group.exp(betaOver[fake], cf))
+
+	// Commit honestly on the true branch
+	w, err := group.RandomScalar()
+	if err != nil {
+		return nil, err
+	}
+	a[m] = group.exp(group.G, w)
+	b[m] = group.exp(h, w)
+
+	total := group.challenge(domainZeroOne, context, h, alpha, beta, a[0], b[0], a[1], b[1])
+	c[m] = new(big.Int).Sub(total, c[fake])
+	c[m].Mod(c[m], group.Q)
+	z[m] = new(big.Int).Mul(c[m], r)
+	z[m].Add(z[m], w).Mod(z[m], group.Q)
+
+	return &ZeroOneProof{
+		A0: wrap(a[0]), B0: wrap(b[0]), A1: wrap(a[1]), B1: wrap(b[1]),
+		C0: wrap(c[0]), C1: wrap(c[1]), R0: wrap(z[0]), R1: wrap(z[1]),
+	}, nil
+}
+
+func (p *ZeroOneProof) verify(group *Group, pk *PublicKey, ct *Ciphertext, context string) bool {
+	if p == nil {
+		return false
+	}
+	for _, e := range []*Int{p.A0, p.B0, p.A1, p.B1} {
+		if e == nil || !group.isElement(e.Big()) {
+			return false
+		}
+	}
+	for _, s := range []*Int{p.C0, p.C1, p.R0, p.R1} {
+		if s == nil || !group.isScalar(s.Big()) {
+			return false
+		}
+	}
+
+	alpha, beta, h := ct.Alpha.Big(), ct.Beta.Big(), pk.H.Big()
+	betaOver := [2]*big.Int{beta, group.div(beta, group.G)}
+	a := [2]*big.Int{p.A0.Big(), p.A1.Big()}
+	b := [2]*big.Int{p.B0.Big(), p.B1.Big()}
+	c := [2]*big.Int{p.C0.Big(), p.C1.Big()}
+	z := [2]*big.Int{p.R0.Big(), p.R1.Big()}
+
+	total := group.challenge(domainZeroOne, context, h, alpha, beta, a[0], b[0], a[1], b[1])
+	sum := new(big.Int).Add(c[0], c[1])
+	if sum.Mod(sum, group.Q).Cmp(total) != 0 {
+		return false
+	}
+
+	for j := 0; j < 2; j++ {
+		if group.exp(group.G, z[j]).Cmp(group.mul(a[j], group.exp(alpha, c[j]))) != 0 {
+			return false
+		}
+		if group.exp(h, z[j]).Cmp(group.mul(b[j], group.exp(betaOver[j], c[j]))) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// OneHotBallot is an encrypted ballot with one ciphertext per option. Each
+// ciphertext carries a proof that it encrypts 0 or 1, and SumProof shows that
+// the ciphertexts add up to exactly 1, so the ballot selects one option.
+type OneHotBallot struct {
+	Choices  []*Ciphertext   `json:"choices"`
+	Proofs   []*ZeroOneProof `json:"proofs"`
+	SumProof *EqualityProof  `json:"sum_proof"`
+}
+
+// optionContext binds a per-option proof to its position on the ballot
+func optionContext(context string, index int) string {
+	return context + "/option:" + big.NewInt(int64(index)).String()
+}
+
+// EncryptOneHot encrypts a vote for option choice out of n options. The context
+// (for example the election and constituency) is bound into every proof so a
+// ballot cannot be replayed elsewhere.
+func EncryptOneHot(group *Group, pk *PublicKey, n, choice int, context string) (*OneHotBallot, error) {
+	if choice < 0 || choice >= n {
+		return nil, errors.New("elgamal: choice out of range")
+	}
+
+	ballot := &OneHotBallot{}
+	rSum := new(big.Int)
+	for i := 0; i < n; i++ {
+		m := 0
+		if i == choice {
+			m = 1
+		}
+		ct, r, err := Encrypt(group, pk, int64(m))
+		if err != nil {
+			return nil, err
+		}
+		proof, err := proveZeroOne(group, pk, ct, m, r, optionContext(context, i))
+		if err != nil {
+			return nil, err
+		}
+		ballot.Choices = append(ballot.Choices, ct)
+		ballot.Proofs = append(ballot.Proofs, proof)
+		rSum.Add(rSum, r)
+	}
+	rSum.Mod(rSum, group.Q)
+
+	total := ballot.Total(group)
+	sumProof, err := proveEquality(group, domainSum, context, group.G, pk.H.Big(), total.Alpha.Big(), group.div(total.Beta.Big(), group.G), rSum)
+	if err != nil {
+		return nil, err
+	}
+	ballot.SumProof = sumProof
+	return ballot, nil
+}
+
+// Total returns the homomorphic sum of all choices on the ballot
+func (b *OneHotBallot) Total(group *Group) *Ciphertext {
+	total := Zero()
+	for _, ct := range b.Choices {
+		total = total.Add(group, ct)
+	}
+	return total
+}
+
+// ErrInvalidBallot is returned when an encrypted ballot or its proofs do not verify
+var ErrInvalidBallot = errors.New("elgamal: invalid encrypted ballot")
+
+// Verify checks that the ballot has n well-formed choices, that each encrypts
+// 0 or 1 and that exactly one option is selected
+func (b *OneHotBallot) Verify(group *Group, pk *PublicKey, n int, context string) error {
+	if b == nil || len(b.Choices) != n || len(b.Proofs) != n || n == 0 {
+		return ErrInvalidBallot
+	}
+	for i, ct := range b.Choices {
+		if !ct.valid(group) || !b.Proofs[i].verify(group, pk, ct, optionContext(context, i)) {
+			return ErrInvalidBallot
+		}
+	}
+	total := b.Total(group)
+	if !b.SumProof.verify(group, domainSum, context, group.G, pk.H.Big(), total.Alpha.Big(), group.div(total.Beta.Big(), group.G)) {
+		return ErrInvalidBallot
+	}
+	return nil
+}
+
+// DecryptionShare is alpha^x for a ciphertext, with a proof that the same x
+// was used as in the matching public key (or public key share) g^x
+type DecryptionShare struct {
+	D     *Int           `json:"d"`
+	Proof *EqualityProof `json:"proof"`
+}
+
+// NewDecryptionShare computes and proves alpha^x for ct
+func NewDecryptionShare(group *Group, x *big.Int, ct *Ciphertext, context string) (*DecryptionShare, error) {
+	alpha := ct.Alpha.Big()
+	d := group.exp(alpha, x)
+	proof, err := proveEquality(group, domainDecryption, context, group.G, alpha, group.exp(group.G, x), d, x)
+	if err != nil {
+		return nil, err
+	}
+	return &DecryptionShare{D: wrap(d), Proof: proof}, nil
+}
+
+// Verify checks the share against the public key (share) h = g^x
+func (s *DecryptionShare) Verify(group *Group, h *big.Int, ct *Ciphertext, context string) bool {
+	return s != nil && s.D != nil && group.isElement(s.D.Big()) &&
+		s.Proof.verify(group, domainDecryption, context, group.G, ct.Alpha.Big(), h, s.D.Big())
+}
+
+// DecryptWithFactor recovers m from ct given the decryption factor d = alpha^x,
+// searching m in [0, max]
+func DecryptWithFactor(group *Group, ct *Ciphertext, d *big.Int, max int64) (int64, error) {
+	gm := group.div(ct.Beta.Big(), d)
+	return DiscreteLog(group, gm, max)
+}
+
+// ErrNoDiscreteLog is returned when g^m has no solution in the searched range
+var ErrNoDiscreteLog = errors.New("elgamal: plaintext out of range")
+
+// DiscreteLog finds m in [0, max] with g^m = y using baby-step giant-step
+func DiscreteLog(group *Group, y *big.Int, max int64) (int64, error) {
+	if max < 0 {
+		return 0, ErrNoDiscreteLog
+	}
+	step := int64(1)
+	for step*step <= max {
+		step++
+	}
+
+	// Baby steps: g^j for j in [0, step)
+	baby := make(map[string]int64, step)
+	cur := big.NewInt(1)
+	for j := int64(0); j < step; j++ {
+		key := string(cur.Bytes())
+		if _, ok := baby[key]; !ok {
+			baby[key] = j
+		}
+		cur = group.mul(cur, group.G)
+	}
+
+	// Giant steps: y * g^(-i*step)
+	giant := new(big.Int).ModInverse(group.exp(group.G, big.NewInt(step)), group.P)
+	gamma := new(big.Int).Set(y)
+	for i := int64(0); i*step <= max; i++ {
+		if j, ok := baby[string(gamma.Bytes())]; ok {
+			if m := i*step + j; m <= max {
+				return m, nil
+			}
+		}
+		gamma = group.mul(gamma, giant)
+	}
+	return 0, ErrNoDiscreteLog
+}
@@ -0,0 +1,289 @@
+package elgamal
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// testKey returns a fresh key pair in DefaultGroup
+func testKey(t *testing.T) (*big.Int, *PublicKey) {
+	t.Helper()
+	x, pk, err := GenerateKey(DefaultGroup)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	return x, pk
+}
+
+// decrypt opens ct with the secret exponent x
+func decrypt(t *testing.T, x *big.Int, ct *Ciphertext, max int64) int64 {
+	t.Helper()
+	m, err := DecryptWithFactor(DefaultGroup, ct, DefaultGroup.exp(ct.Alpha.Big(), x), max)
+	if err != nil {
+		t.Fatalf("decrypting: %v", err)
+	}
+	return m
+}
+
+// TestEncryptRoundTrip checks that ciphertexts decrypt to their plaintext and
+// that adding ciphertexts adds the plaintexts
+func TestEncryptRoundTrip(t *testing.T) {
+	group := DefaultGroup
+	x, pk := testKey(t)
+
+	sum := Zero()
+	var want int64
+	for _, m := range []int64{0, 1, 5, 42} {
+		ct, _, err := Encrypt(group, pk, m)
+		if err != nil {
+			t.Fatalf("encrypting %d: %v", m, err)
+		}
+		if got := decrypt(t, x, ct, 100); got != m {
+			t.Errorf("decrypted %d, want %d", got, m)
+		}
+		sum = sum.Add(group, ct)
+		want += m
+	}
+	if got := decrypt(t, x, sum, 100); got != want {
+		t.Errorf("decrypted sum %d, want %d", got, want)
+	}
+	if got := decrypt(t, x, Zero(), 0); got != 0 {
+		t.Errorf("decrypted Zero() to %d", got)
+	}
+}
+
+// TestDiscreteLogOutOfRange checks that plaintexts above max are not found
+func TestDiscreteLogOutOfRange(t *testing.T) {
+	group := DefaultGroup
+	y := group.exp(group.G, big.NewInt(30))
+
+	if m, err := DiscreteLog(group, y, 30); err != nil || m != 30 {
+		t.Errorf("DiscreteLog(g^30, 30) = %d, %v", m, err)
+	}
+	if _, err := DiscreteLog(group, y, 29); !errors.Is(err, ErrNoDiscreteLog) {
+		t.Errorf("DiscreteLog(g^30, 29) error = %v, want ErrNoDiscreteLog", err)
+	}
+	if _, err := DiscreteLog(group, y, -1); !errors.Is(err, ErrNoDiscreteLog) {
+		t.Errorf("DiscreteLog with negative max error = %v, want ErrNoDiscreteLog", err)
+	}
+}
+
+// TestOneHotBallot checks that honest ballots verify, decrypt to their choice
+// and survive a JSON round trip
+func TestOneHotBallot(t *testing.T) {
+	group := DefaultGroup
+	x, pk := testKey(t)
+	const options, context = 3, "election:1/constituency:2"
+
+	for choice := 0; choice < options; choice++ {
+		ballot, err := EncryptOneHot(group, pk, options, choice, context)
+		if err != nil {
+			t.Fatalf("encrypting choice %d: %v", choice, err)
+		}
+		data, err := json.Marshal(ballot)
+		if err != nil {
+			t.Fatalf("encoding ballot: %v", err)
+		}
+		var decoded OneHotBallot
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("decoding ballot: %v", err)
+		}
+		if err := decoded.Verify(group, pk, options, context); err != nil {
+			t.Errorf("honest ballot for choice %d rejected: %v", choice, err)
+		}
+		for i, ct := range decoded.Choices {
+			want := int64(0)
+			if i == choice {
+				want = 1
+			}
+			if got := decrypt(t, x, ct, 1); got != want {
+				t.Errorf("choice %d option %d decrypted to %d, want %d", choice, i, got, want)
+			}
+		}
+	}
+
+	if _, err := EncryptOneHot(group, pk, options, options, context); err == nil {
+		t.Error("encrypted a choice out of range")
+	}
+}
+
+// TestOneHotBallotRejectsTampering checks that every kind of altered ballot,
+// and every ballot checked in the wrong setting, fails verification
+func TestOneHotBallotRejectsTampering(t *testing.T) {
+	group := DefaultGroup
+	_, pk := testKey(t)
+	_, otherKey := testKey(t)
+	const options, context = 3, "election:1/constituency:2"
+
+	honest, err := EncryptOneHot(group, pk, options, 1, context)
+	if err != nil {
+		t.Fatalf("encrypting ballot: %v", err)
+	}
+	// tampered returns a deep copy of the honest ballot after applying change
+	tampered := func(change func(b *OneHotBallot)) *OneHotBallot {
+		data, _ := json.Marshal(honest)
+		var b OneHotBallot
+		json.Unmarshal(data, &b)
+		change(&b)
+		return &b
+	}
+	plusOne := func(i *Int) *Int {
+		return wrap(new(big.Int).Add(i.Big(), big.NewInt(1)))
+	}
+	zero, _, err := Encrypt(group, pk, 0)
+	if err != nil {
+		t.Fatalf("encrypting zero: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		ballot  *OneHotBallot
+		key     *PublicKey
+		options int
+		context string
+	}{
+		{name: "re-randomised ciphertext", ballot: tampered(func(b *OneHotBallot) { b.Choices[0] = b.Choices[0].Add(group, zero) })},
+		{name: "swapped choices", ballot: tampered(func(b *OneHotBallot) { b.Choices[0], b.Choices[1] = b.Choices[1], b.Choices[0] })},
+		{name: "swapped choices and proofs", ballot: tampered(func(b *OneHotBallot) {
+			b.Choices[0], b.Choices[1] = b.Choices[1], b.Choices[0]
+			b.Proofs[0], b.Proofs[1] = b.Proofs[1], b.Proofs[0]
+		})},
+		{name: "zero-one challenge altered", ballot: tampered(func(b *OneHotBallot) { b.Proofs[2].C0 = plusOne(b.Proofs[2].C0) })},
+		{name: "zero-one response altered", ballot: tampered(func(b *OneHotBallot) { b.Proofs[1].R1 = plusOne(b.Proofs[1].R1) })},
+		{name: "zero-one commitment altered", ballot: tampered(func(b *OneHotBallot) { b.Proofs[0].A0 = wrap(group.mul(b.Proofs[0].A0.Big(), group.G)) })},
+		{name: "zero-one scalar out of range", ballot: tampered(func(b *OneHotBallot) { b.Proofs[0].R0 = wrap(new(big.Int).Add(b.Proofs[0].R0.Big(), group.Q)) })},
+		{name: "zero-one proof missing", ballot: tampered(func(b *OneHotBallot) { b.Proofs[1] = nil })},
+		{name: "sum response altered", ballot: tampered(func(b *OneHotBallot) { b.SumProof.R = plusOne(b.SumProof.R) })},
+		{name: "sum commitment altered", ballot: tampered(func(b *OneHotBallot) { b.SumProof.B = wrap(group.mul(b.SumProof.B.Big(), group.G)) })},
+		{name: "sum proof missing", ballot: tampered(func(b *OneHotBallot) { b.SumProof = nil })},
+		{name: "ciphertext outside the subgroup", ballot: tampered(func(b *OneHotBallot) { b.Choices[0].Alpha = wrap(new(big.Int).Sub(group.P, big.NewInt(1))) })},
+		{name: "option dropped", ballot: tampered(func(b *OneHotBallot) { b.Choices, b.Proofs = b.Choices[:2], b.Proofs[:2] })},
+		{name: "wrong number of options", ballot: honest, options: options + 1},
+		{name: "wrong context", ballot: honest, context: "election:1/constituency:3"},
+		{name: "wrong key", ballot: honest, key: otherKey},
+		{name: "nil ballot"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, n, ctx := pk, options, context
+			if tt.key != nil {
+				key = tt.key
+			}
+			if tt.options != 0 {
+				n = tt.options
+			}
+			if tt.context != "" {
+				ctx = tt.context
+			}
+			if err := tt.ballot.Verify(group, key, n, ctx); !errors.Is(err, ErrInvalidBallot) {
+				t.Errorf("Verify = %v, want ErrInvalidBallot", err)
+			}
+		})
+	}
+}
+
+// TestOneHotBallotRejectsOvervotes checks that valid 0/1 proofs cannot carry a
+// ballot selecting two options or none, and that 0/1 proofs cannot be made for
+// other plaintexts even when the ballot sums to 1
+func TestOneHotBallotRejectsOvervotes(t *testing.T) {
+	group := DefaultGroup
+	_, pk := testKey(t)
+	const context = "election:1/constituency:2"
+
+	// forge builds a ballot for the given plaintexts, proving each as claimed
+	forge := func(plaintexts, claimed []int) *OneHotBallot {
+		ballot := &OneHotBallot{}
+		rSum := new(big.Int)
+		for i, m := range plaintexts {
+			ct, r, err := Encrypt(group, pk, int64(m))
+			if err != nil {
+				t.Fatalf("encrypting: %v", err)
+			}
+			proof, err := proveZeroOne(group, pk, ct, claimed[i], r, optionContext(context, i))
+			if err != nil {
+				t.Fatalf("proving: %v", err)
+			}
+			ballot.Choices = append(ballot.Choices, ct)
+			ballot.Proofs = append(ballot.Proofs, proof)
+			rSum.Add(rSum, r)
+		}
+		total := ballot.Total(group)
+		sumProof, err := proveEquality(group, domainSum, context, group.G, pk.H.Big(), total.Alpha.Big(), group.div(total.Beta.Big(), group.G), rSum.Mod(rSum, group.Q))
+		if err != nil {
+			t.Fatalf("proving sum: %v", err)
+		}
+		ballot.SumProof = sumProof
+		return ballot
+	}
+
+	if err := forge([]int{0, 1, 0}, []int{0, 1, 0}).Verify(group, pk, 3, context); err != nil {
+		t.Fatalf("forge helper built an invalid honest ballot: %v", err)
+	}
+	if err := forge([]int{1, 1, 0}, []int{1, 1, 0}).Verify(group, pk, 3, context); !errors.Is(err, ErrInvalidBallot) {
+		t.Errorf("two selections: Verify = %v, want ErrInvalidBallot", err)
+	}
+	if err := forge([]int{0, 0, 0}, []int{0, 0, 0}).Verify(group, pk, 3, context); !errors.Is(err, ErrInvalidBallot) {
+		t.Errorf("no selection: Verify = %v, want ErrInvalidBallot", err)
+	}
+	// Still sums to 1, so only the 0/1 proofs can catch it
+	if err := forge([]int{2, -1, 0}, []int{1, 0, 0}).Verify(group, pk, 3, context); !errors.Is(err, ErrInvalidBallot) {
+		t.Errorf("a 2 and a -1 proven as 1 and 0: Verify = %v, want ErrInvalidBallot", err)
+	}
+}
+
+// TestDecryptionShare checks that a decryption share verifies against its key
+// and fails when the share, key, ciphertext or context is wrong
+func TestDecryptionShare(t *testing.T) {
+	group := DefaultGroup
+	x, pk := testKey(t)
+	_, otherKey := testKey(t)
+	const context = "election:1/constituency:2/option:0"
+
+	ct, _, err := Encrypt(group, pk, 3)
+	if err != nil {
+		t.Fatalf("encrypting: %v", err)
+	}
+	other, _, err := Encrypt(group, pk, 3)
+	if err != nil {
+		t.Fatalf("encrypting: %v", err)
+	}
+	share, err := NewDecryptionShare(group, x, ct, context)
+	if err != nil {
+		t.Fatalf("computing share: %v", err)
+	}
+
+	if !share.Verify(group, pk.H.Big(), ct, context) {
+		t.Fatal("honest decryption share rejected")
+	}
+	if m, err := DecryptWithFactor(group, ct, share.D.Big(), 10); err != nil || m != 3 {
+		t.Errorf("decrypted %d, %v; want 3", m, err)
+	}
+
+	wrongFactor := &DecryptionShare{D: wrap(group.mul(share.D.Big(), group.G)), Proof: share.Proof}
+	wrongResponse := &DecryptionShare{D: share.D, Proof: &EqualityProof{A: share.Proof.A, B: share.Proof.B, R: wrap(new(big.Int).Add(share.Proof.R.Big(), big.NewInt(1)))}}
+	tests := []struct {
+		name    string
+		share   *DecryptionShare
+		key     *big.Int
+		ct      *Ciphertext
+		context string
+	}{
+		{name: "wrong factor", share: wrongFactor, key: pk.H.Big(), ct: ct, context: context},
+		{name: "wrong response", share: wrongResponse, key: pk.H.Big(), ct: ct, context: context},
+		{name: "wrong key", share: share, key: otherKey.H.Big(), ct: ct, context: context},
+		{name: "wrong ciphertext", share: share, key: pk.H.Big(), ct: other, context: context},
+		{name: "wrong context", share: share, key: pk.H.Big(), ct: ct, context: context + "/other"},
+		{name: "missing proof", share: &DecryptionShare{D: share.D}, key: pk.H.Big(), ct: ct, context: context},
+		{name: "nil share", key: pk.H.Big(), ct: ct, context: context},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.share.Verify(group, tt.key, tt.ct, tt.context) {
+				t.Error("tampered decryption share accepted")
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package elgamal
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+)
+
+// Group is the order-q subgroup of quadratic residues modulo a safe prime p = 2q + 1
+type Group struct {
+	P *big.Int // Safe prime modulus
+	Q *big.Int // Prime order of the subgroup, (P - 1) / 2
+	G *big.Int // Generator of the subgroup
+}
+
+// rfc3526Prime is the 2048-bit MODP group prime from RFC 3526, section 3
+const rfc3526Prime = "FFFFFFFFFFFFFFFFC90FDAA22168C234C4C6628B80DC1CD1" +
+	"29024E088A67CC74020BBEA63B139B22514A08798E3404DD" +
+	"EF9519B3CD3A431B302B0A6DF25F14374FE1356D6D51C245" +
+	"E485B576625E7EC6F44C42E9A637ED6B0BFF5CB6F406B7ED" +
+	"EE386BFB5A899FA5AE9F24117C4B1FE649286651ECE45B3D" +
+	"C2007CB8A163BF0598DA48361C55D39A69163FA8FD24CF5F" +
+	"83655D23DCA3AD961C62F356208552BB9ED529077096966D" +
+	"670C354E4ABC9804F1746C08CA18217C32905E462E36CE3B" +
+	"E39E772C180E86039B2783A2EC07A28FB5C55DF06F4C52C9" +
+	"DE2BCBF6955817183995497CEA956AE515D2261898FA0510" +
+	"15728E5A8AACAA68FFFFFFFFFFFFFFFF"
+
+// DefaultGroup is the group every election key and ballot uses. G = 4 is a square,
+// so it generates the prime-order subgroup rather than all of Z_p^*.
+var DefaultGroup = func() *Group {
+	p, _ := new(big.Int).SetString(rfc3526Prime, 16)
+	q := new(big.Int).Rsh(p, 1)
+	return &Group{P: p, Q: q, G: big.NewInt(4)}
+}()
+
+// Params returns the group parameters in their JSON (hex) encoding
+func (g *Group) Params() map[string]*Int {
+	return map[string]*Int{"p": wrap(g.P), "q": wrap(g.Q), "g": wrap(g.G)}
+}
+
+// exp returns base^e mod P
+func (g *Group) exp(base, e *big.Int) *big.Int {
+	return new(big.Int).Exp(base, e, g.P)
+}
+
+// mul returns a * b mod P
+func (g *Group) mul(a, b *big.Int) *big.Int {
+	r := new(big.Int).Mul(a, b)
+	return r.Mod(r, g.P)
+}
+
+// div returns a * b^-1 mod P
+func (g *Group) div(a, b *big.Int) *big.Int {
+	return g.mul(a, new(big.Int).ModInverse(b, g.P))
+}
+
+// isElement reports whether x is a member of the order-Q subgroup
+func (g *Group) isElement(x *big.Int) bool {
+	if x == nil || x.Sign() <= 0 || x.Cmp(g.P) >= 0 {
+		return false
+	}
+	return g.exp(x, g.Q).Cmp(big.NewInt(1)) == 0
+}
+
+// isScalar reports whether x is in [0, Q)
+func (g *Group) isScalar(x *big.Int) bool {
+	return x != nil && x.Sign() >= 0 && x.Cmp(g.Q) < 0
+}
+
+// RandomScalar returns a uniformly random exponent in [1, Q)
+func (g *Group) RandomScalar() (*big.Int, error) {
+	max := new(big.Int).Sub(g.Q, big.NewInt(1))
+	x, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return nil, err
+	}
+	return x.Add(x, big.NewInt(1)), nil
+}
+
+// challenge derives a Fiat-Shamir challenge in [0, Q) from a domain tag, the
+// proof context and the group elements the proof commits to
+func (g *Group) challenge(domain, context string, elements ...*big.Int) *big.Int {
+	h := sha256.New()
+	write := func(b []byte) {
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(b)))
+		h.Write(length[:])
+		h.Write(b)
+	}
+	write([]byte(domain))
+	write([]byte(context))
+	for _, e := range elements {
+		write(e.Bytes())
+	}
+	c := new(big.Int).SetBytes(h.Sum(nil))
+	return c.Mod(c, g.Q)
+}
+
+// Int is a big.Int that encodes to JSON as a hex string, so clients never
+// have to parse 2048-bit JSON numbers
+type Int big.Int
+
+func wrap(x *big.Int) *Int {
+	return (*Int)(x)
+}
+
+// Big returns the value as a *big.Int
+func (i *Int) Big() *big.Int {
+	return (*big.Int)(i)
+}
+
+func (i *Int) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.Big().Text(16))
+}
+
+func (i *Int) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	if _, ok := i.Big().SetString(text, 16); !ok {
+		return errors.New("elgamal: invalid hex integer")
+	}
+	return nil
+}
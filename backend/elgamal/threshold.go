@@ -0,0 +1,219 @@
+package elgamal
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// Threshold keys follow Pedersen's distributed key generation. Each of n trustees
+// picks a random polynomial f_i of degree k-1, publishes Feldman commitments
+// g^a_it to its coefficients and sends f_i(j) to trustee j. Trustee j's key share
+// is x_j = sum_i f_i(j), the joint public key is the product of every g^a_i0, and
+// the joint secret sum_i a_i0 is never held by anyone. Any k trustees can
+// decrypt together by combining their factors alpha^x_j with Lagrange coefficients.
+
+const (
+	domainDealing = "evoting/dealing"
+	domainShare   = "evoting/share"
+)
+
+// ErrInvalidDealing is returned when a dealing's commitments or proof do not check out
+var ErrInvalidDealing = errors.New("elgamal: invalid dealing")
+
+// Valid reports whether the key is a member of the group
+func (pk *PublicKey) Valid(group *Group) bool {
+	return pk != nil && pk.H != nil && group.isElement(pk.H.Big())
+}
+
+// SchnorrProof proves knowledge of x for a public value g^x
+type SchnorrProof struct {
+	A *Int `json:"a"` // g^w
+	R *Int `json:"r"` // w + c*x mod q
+}
+
+// Dealing is a trustee's public contribution to the key generation: commitments
+// to its polynomial coefficients and a proof that it knows the constant term, so
+// that no trustee can pick its contribution after seeing everyone else's
+type Dealing struct {
+	Commitments []*Int        `json:"commitments"`
+	Proof       *SchnorrProof `json:"proof"`
+}
+
+// NewDealing picks a random polynomial of degree threshold-1 and returns its
+// coefficients, which the trustee keeps secret, and the public dealing
+func NewDealing(group *Group, threshold int, context string) ([]*big.Int, *Dealing, error) {
+	if threshold < 1 {
+		return nil, nil, ErrInvalidDealing
+	}
+	coefficients := make([]*big.Int, threshold)
+	commitments := make([]*Int, threshold)
+	for t := range coefficients {
+		a, err := group.RandomScalar()
+		if err != nil {
+			return nil, nil, err
+		}
+		coefficients[t] = a
+		commitments[t] = wrap(group.exp(group.G, a))
+	}
+
+	w, err := group.RandomScalar()
+	if err != nil {
+		return nil, nil, err
+	}
+	a := group.exp(group.G, w)
+	c := group.challenge(domainDealing, context, group.G, commitments[0].Big(), a)
+	r := new(big.Int).Mul(c, coefficients[0])
+	r.Add(r, w).Mod(r, group.Q)
+
+	return coefficients, &Dealing{Commitments: commitments, Proof: &SchnorrProof{A: wrap(a), R: wrap(r)}}, nil
+}
+
+// Verify checks that the dealing has threshold commitments, all in the group,
+// and a valid proof of knowledge of the constant term
+func (d *Dealing) Verify(group *Group, threshold int, context string) error {
+	if d == nil || len(d.Commitments) != threshold || d.Proof == nil || d.Proof.A == nil || d.Proof.R == nil {
+		return ErrInvalidDealing
+	}
+	for _, commitment := range d.Commitments {
+		if commitment == nil || !group.isElement(commitment.Big()) {
+			return ErrInvalidDealing
+		}
+	}
+
+	a, r := d.Proof.A.Big(), d.Proof.R.Big()
+	if !group.isElement(a) || !group.isScalar(r) {
+		return ErrInvalidDealing
+	}
+	a0 := d.Commitments[0].Big()
+	c := group.challenge(domainDealing, context, group.G, a0, a)
+	if group.exp(group.G, r).Cmp(group.mul(a, group.exp(a0, c))) != 0 {
+		return ErrInvalidDealing
+	}
+	return nil
+}
+
+// EvaluatePolynomial returns f(index) mod q, the share a dealer sends to trustee index
+func EvaluatePolynomial(group *Group, coefficients []*big.Int, index int) *big.Int {
+	x := big.NewInt(int64(index))
+	result := new(big.Int)
+	for t := len(coefficients) - 1; t >= 0; t-- {
+		result.Mul(result, x).Add(result, coefficients[t]).Mod(result, group.Q)
+	}
+	return result
+}
+
+// committedShare returns g^f(index), computed from the commitments alone
+func (d *Dealing) committedShare(group *Group, index int) *big.Int {
+	x := big.NewInt(int64(index))
+	power := big.NewInt(1)
+	result := big.NewInt(1)
+	for _, commitment := range d.Commitments {
+		result = group.mul(result, group.exp(commitment.Big(), power))
+		power = new(big.Int).Mul(power, x)
+		power.Mod(power, group.Q)
+	}
+	return result
+}
+
+// VerifyShare checks a received share f(index) against the dealer's commitments
+func (d *Dealing) VerifyShare(group *Group, index int, share *big.Int) bool {
+	return group.isScalar(share) && group.exp(group.G, share).Cmp(d.committedShare(group, index)) == 0
+}
+
+// JointPublicKey combines every trustee's dealing into the election public key
+func JointPublicKey(group *Group, dealings []*Dealing) *PublicKey {
+	h := big.NewInt(1)
+	for _, d := range dealings {
+		h = group.mul(h, d.Commitments[0].Big())
+	}
+	return &PublicKey{H: wrap(h)}
+}
+
+// VerificationKey returns g^x_index for trustee index, which its partial
+// decryptions are checked against
+func VerificationKey(group *Group, dealings []*Dealing, index int) *big.Int {
+	h := big.NewInt(1)
+	for _, d := range dealings {
+		h = group.mul(h, d.committedShare(group, index))
+	}
+	return h
+}
+
+// EncryptedShare carries a polynomial share to its recipient: R = g^r and
+// S = share + mask(h^r) mod q, where h is the recipient's communication key
+type EncryptedShare struct {
+	R *Int `json:"r"`
+	S *Int `json:"s"`
+}
+
+// EncryptShare encrypts a share for the holder of recipient
+func EncryptShare(group *Group, recipient *PublicKey, share *big.Int, context string) (*EncryptedShare, error) {
+	r, err := group.RandomScalar()
+	if err != nil {
+		return nil, err
+	}
+	rr := group.exp(group.G, r)
+	mask := group.derive(domainShare, context, rr, group.exp(recipient.H.Big(), r))
+	s := new(big.Int).Add(share, mask)
+	return &EncryptedShare{R: wrap(rr), S: wrap(s.Mod(s, group.Q))}, nil
+}
+
+// Valid reports whether the encrypted share is well formed
+func (e *EncryptedShare) Valid(group *Group) bool {
+	return e != nil && e.R != nil && e.S != nil && group.isElement(e.R.Big()) && group.isScalar(e.S.Big())
+}
+
+// Decrypt recovers the share with the recipient's communication secret x
+func (e *EncryptedShare) Decrypt(group *Group, x *big.Int, context string) *big.Int {
+	mask := group.derive(domainShare, context, e.R.Big(), group.exp(e.R.Big(), x))
+	s := new(big.Int).Sub(e.S.Big(), mask)
+	return s.Mod(s, group.Q)
+}
+
+// LagrangeCoefficient returns the coefficient of trustee index when
+// interpolating at 0 from the given set of trustee indices
+func LagrangeCoefficient(group *Group, indices []int, index int) *big.Int {
+	num := big.NewInt(1)
+	den := big.NewInt(1)
+	for _, j := range indices {
+		if j == index {
+			continue
+		}
+		num.Mul(num, big.NewInt(int64(j))).Mod(num, group.Q)
+		diff := big.NewInt(int64(j - index))
+		den.Mul(den, diff.Mod(diff, group.Q)).Mod(den, group.Q)
+	}
+	lambda := new(big.Int).ModInverse(den, group.Q)
+	return lambda.Mul(lambda, num).Mod(lambda, group.Q)
+}
+
+// CombineDecryptionFactors turns the partial factors alpha^x_j of at least
+// threshold trustees, keyed by trustee index, into the full factor alpha^x
+func CombineDecryptionFactors(group *Group, factors map[int]*big.Int) *big.Int {
+	indices := make([]int, 0, len(factors))
+	for index := range factors {
+		indices = append(indices, index)
+	}
+
+	d := big.NewInt(1)
+	for _, index := range indices {
+		d = group.mul(d, group.exp(factors[index], LagrangeCoefficient(group, indices, index)))
+	}
+	return d
+}
+
+// derive expands a hash of its inputs into a uniform scalar in [0, Q), long
+// enough that reducing it mod Q leaves no usable bias
+func (g *Group) derive(domain, context string, elements ...*big.Int) *big.Int {
+	seed := g.challenge(domain, context, elements...).Bytes()
+	size := (g.Q.BitLen()+7)/8 + 16
+
+	var out []byte
+	for counter := byte(0); len(out) < size; counter++ {
+		block := sha256.Sum256(append([]byte{counter}, seed...))
+		out = append(out, block[:]...)
+	}
+	x := new(big.Int).SetBytes(out[:size])
+	return x.Mod(x, g.Q)
+}
@@ -0,0 +1,298 @@
+package elgamal
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// trustee is one participant of a simulated key ceremony
+type trustee struct {
+	index        int
+	coefficients []*big.Int
+	dealing      *Dealing
+}
+
+// ceremony runs the dealing step for trustees 1..n with the given threshold
+func ceremony(t *testing.T, n, threshold int) []trustee {
+	t.Helper()
+	trustees := make([]trustee, n)
+	for i := range trustees {
+		index := i + 1
+		coefficients, dealing, err := NewDealing(DefaultGroup, threshold, fmt.Sprintf("election:1/trustee:%d", index))
+		if err != nil {
+			t.Fatalf("dealing for trustee %d: %v", index, err)
+		}
+		trustees[i] = trustee{index: index, coefficients: coefficients, dealing: dealing}
+	}
+	return trustees
+}
+
+// keyShare sums the shares every dealer in dealers sent to trustee index
+func keyShare(dealers []trustee, index int) *big.Int {
+	x := new(big.Int)
+	for _, dealer := range dealers {
+		x.Add(x, EvaluatePolynomial(DefaultGroup, dealer.coefficients, index))
+	}
+	return x.Mod(x, DefaultGroup.Q)
+}
+
+// dealings lists the public dealings of dealers
+func dealings(dealers []trustee) []*Dealing {
+	list := make([]*Dealing, len(dealers))
+	for i, dealer := range dealers {
+		list[i] = dealer.dealing
+	}
+	return list
+}
+
+// TestDealingVerify checks that honest dealings verify and altered ones, or
+// ones checked against the wrong threshold or trustee, do not
+func TestDealingVerify(t *testing.T) {
+	group := DefaultGroup
+	const threshold, context = 3, "election:1/trustee:2"
+
+	_, honest, err := NewDealing(group, threshold, context)
+	if err != nil {
+		t.Fatalf("dealing: %v", err)
+	}
+	if err := honest.Verify(group, threshold, context); err != nil {
+		t.Fatalf("honest dealing rejected: %v", err)
+	}
+	if _, _, err := NewDealing(group, 0, context); !errors.Is(err, ErrInvalidDealing) {
+		t.Errorf("NewDealing with threshold 0 error = %v, want ErrInvalidDealing", err)
+	}
+
+	// A constant term swapped for one the dealer has no proof for
+	_, other, err := NewDealing(group, threshold, context)
+	if err != nil {
+		t.Fatalf("dealing: %v", err)
+	}
+	copyDealing := func(change func(d *Dealing)) *Dealing {
+		d := &Dealing{Commitments: append([]*Int(nil), honest.Commitments...), Proof: &SchnorrProof{A: honest.Proof.A, R: honest.Proof.R}}
+		change(d)
+		return d
+	}
+
+	tests := []struct {
+		name      string
+		dealing   *Dealing
+		threshold int
+		context   string
+	}{
+		{name: "constant term replaced", dealing: copyDealing(func(d *Dealing) { d.Commitments[0] = other.Commitments[0] })},
+		{name: "proof response altered", dealing: copyDealing(func(d *Dealing) { d.Proof.R = wrap(new(big.Int).Add(d.Proof.R.Big(), big.NewInt(1))) })},
+		{name: "proof commitment altered", dealing: copyDealing(func(d *Dealing) { d.Proof.A = wrap(group.mul(d.Proof.A.Big(), group.G)) })},
+		{name: "commitment outside the subgroup", dealing: copyDealing(func(d *Dealing) { d.Commitments[2] = wrap(new(big.Int).Sub(group.P, big.NewInt(1))) })},
+		{name: "commitment missing", dealing: copyDealing(func(d *Dealing) { d.Commitments[1] = nil })},
+		{name: "too few commitments", dealing: copyDealing(func(d *Dealing) { d.Commitments = d.Commitments[:2] })},
+		{name: "proof missing", dealing: copyDealing(func(d *Dealing) { d.Proof = nil })},
+		{name: "wrong threshold", dealing: honest, threshold: threshold + 1},
+		{name: "another trustee's context", dealing: honest, context: "election:1/trustee:3"},
+		{name: "nil dealing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, ctx := threshold, context
+			if tt.threshold != 0 {
+				k = tt.threshold
+			}
+			if tt.context != "" {
+				ctx = tt.context
+			}
+			if err := tt.dealing.Verify(group, k, ctx); !errors.Is(err, ErrInvalidDealing) {
+				t.Errorf("Verify = %v, want ErrInvalidDealing", err)
+			}
+		})
+	}
+}
+
+// TestVerifyShare checks shares against their dealer's commitments, the check
+// a trustee makes before complaining about a share
+func TestVerifyShare(t *testing.T) {
+	group := DefaultGroup
+	dealer := ceremony(t, 1, 3)[0]
+
+	for index := 1; index <= 5; index++ {
+		share := EvaluatePolynomial(group, dealer.coefficients, index)
+		if !dealer.dealing.VerifyShare(group, index, share) {
+			t.Errorf("honest share for trustee %d rejected", index)
+		}
+		if dealer.dealing.VerifyShare(group, index+1, share) {
+			t.Errorf("share for trustee %d accepted for trustee %d", index, index+1)
+		}
+		altered := new(big.Int).Add(share, big.NewInt(1))
+		if dealer.dealing.VerifyShare(group, index, altered.Mod(altered, group.Q)) {
+			t.Errorf("altered share for trustee %d accepted", index)
+		}
+		if dealer.dealing.VerifyShare(group, index, new(big.Int).Add(share, group.Q)) {
+			t.Errorf("share for trustee %d accepted outside [0, q)", index)
+		}
+	}
+}
+
+// TestEncryptedShare checks that a share encrypted to a trustee's communication
+// key decrypts only with the right secret and context
+func TestEncryptedShare(t *testing.T) {
+	group := DefaultGroup
+	x, pk := testKey(t)
+	wrongX, _ := testKey(t)
+	const context = "election:1/trustee:1/share:2"
+
+	share, err := group.RandomScalar()
+	if err != nil {
+		t.Fatalf("random share: %v", err)
+	}
+	encrypted, err := EncryptShare(group, pk, share, context)
+	if err != nil {
+		t.Fatalf("encrypting share: %v", err)
+	}
+	if !encrypted.Valid(group) {
+		t.Fatal("encrypted share not valid")
+	}
+	if got := encrypted.Decrypt(group, x, context); got.Cmp(share) != 0 {
+		t.Error("share did not round trip")
+	}
+	if got := encrypted.Decrypt(group, wrongX, context); got.Cmp(share) == 0 {
+		t.Error("share decrypted with the wrong secret")
+	}
+	if got := encrypted.Decrypt(group, x, context+"/other"); got.Cmp(share) == 0 {
+		t.Error("share decrypted under the wrong context")
+	}
+
+	for name, e := range map[string]*EncryptedShare{
+		"nil":              nil,
+		"missing R":        {S: encrypted.S},
+		"R outside group":  {R: wrap(new(big.Int).Sub(group.P, big.NewInt(1))), S: encrypted.S},
+		"S out of range":   {R: encrypted.R, S: wrap(group.Q)},
+		"S missing":        {R: encrypted.R},
+		"S negative value": {R: encrypted.R, S: wrap(big.NewInt(-1))},
+	} {
+		if e.Valid(group) {
+			t.Errorf("%s: malformed encrypted share accepted", name)
+		}
+	}
+}
+
+// TestLagrangeCoefficients checks that the coefficients of any set of indices
+// interpolate a constant polynomial to itself, so they sum to 1
+func TestLagrangeCoefficients(t *testing.T) {
+	group := DefaultGroup
+	for _, indices := range [][]int{{1, 2}, {1, 2, 3}, {2, 4, 5}, {1, 3, 4, 7}} {
+		sum := new(big.Int)
+		for _, index := range indices {
+			sum.Add(sum, LagrangeCoefficient(group, indices, index))
+		}
+		if sum.Mod(sum, group.Q).Cmp(big.NewInt(1)) != 0 {
+			t.Errorf("coefficients for %v sum to %v, want 1", indices, sum)
+		}
+	}
+}
+
+// TestThresholdDecryption runs a 3-of-5 ceremony and checks that every subset
+// of at least three trustees decrypts a tally, with each partial decryption
+// verified against the trustee's verification key, and that two cannot
+func TestThresholdDecryption(t *testing.T) {
+	group := DefaultGroup
+	const n, threshold, votes = 5, 3, 7
+	trustees := ceremony(t, n, threshold)
+	public := dealings(trustees)
+	pk := JointPublicKey(group, public)
+
+	// A tally of several ballots, as the server aggregates them
+	tally := Zero()
+	for i := 0; i < votes; i++ {
+		ct, _, err := Encrypt(group, pk, 1)
+		if err != nil {
+			t.Fatalf("encrypting: %v", err)
+		}
+		tally = tally.Add(group, ct)
+	}
+	for i := 0; i < 3; i++ {
+		ct, _, err := Encrypt(group, pk, 0)
+		if err != nil {
+			t.Fatalf("encrypting: %v", err)
+		}
+		tally = tally.Add(group, ct)
+	}
+
+	const context = "election:1/constituency:2/option:0"
+	factors := make(map[int]*big.Int, n)
+	for _, tr := range trustees {
+		x := keyShare(trustees, tr.index)
+		verificationKey := VerificationKey(group, public, tr.index)
+		if group.exp(group.G, x).Cmp(verificationKey) != 0 {
+			t.Fatalf("verification key of trustee %d does not match their key share", tr.index)
+		}
+		share, err := NewDecryptionShare(group, x, tally, context)
+		if err != nil {
+			t.Fatalf("partial decryption of trustee %d: %v", tr.index, err)
+		}
+		if !share.Verify(group, verificationKey, tally, context) {
+			t.Fatalf("partial decryption of trustee %d rejected", tr.index)
+		}
+		if other := VerificationKey(group, public, tr.index%n+1); share.Verify(group, other, tally, context) {
+			t.Errorf("partial decryption of trustee %d accepted for another trustee", tr.index)
+		}
+		factors[tr.index] = share.D.Big()
+	}
+
+	subset := func(indices ...int) map[int]*big.Int {
+		picked := make(map[int]*big.Int, len(indices))
+		for _, index := range indices {
+			picked[index] = factors[index]
+		}
+		return picked
+	}
+	for _, indices := range [][]int{{1, 2, 3}, {1, 3, 5}, {2, 4, 5}, {3, 4, 5}, {1, 2, 4, 5}, {1, 2, 3, 4, 5}} {
+		t.Run(fmt.Sprint(indices), func(t *testing.T) {
+			got, err := DecryptWithFactor(group, tally, CombineDecryptionFactors(group, subset(indices...)), 10)
+			if err != nil || got != votes {
+				t.Errorf("decrypted %d, %v; want %d", got, err, votes)
+			}
+		})
+	}
+
+	if got, err := DecryptWithFactor(group, tally, CombineDecryptionFactors(group, subset(2, 5)), 10); err == nil {
+		t.Errorf("two of three trustees decrypted the tally to %d", got)
+	}
+}
+
+// TestThresholdDecryptionWithoutDisqualifiedDealer checks that leaving a
+// disqualified dealer's dealing out of the joint key, verification keys and key
+// shares still lets any threshold of the remaining trustees decrypt
+func TestThresholdDecryptionWithoutDisqualifiedDealer(t *testing.T) {
+	group := DefaultGroup
+	const threshold = 2
+	trustees := ceremony(t, 4, threshold)
+	qualified := append(append([]trustee(nil), trustees[:2]...), trustees[3])
+	public := dealings(qualified)
+	pk := JointPublicKey(group, public)
+
+	ct, _, err := Encrypt(group, pk, 4)
+	if err != nil {
+		t.Fatalf("encrypting: %v", err)
+	}
+
+	const context = "election:1/constituency:1/option:1"
+	factors := make(map[int]*big.Int)
+	for _, tr := range []trustee{qualified[0], qualified[2]} {
+		share, err := NewDecryptionShare(group, keyShare(qualified, tr.index), ct, context)
+		if err != nil {
+			t.Fatalf("partial decryption: %v", err)
+		}
+		if !share.Verify(group, VerificationKey(group, public, tr.index), ct, context) {
+			t.Fatalf("partial decryption of trustee %d rejected", tr.index)
+		}
+		factors[tr.index] = share.D.Big()
+	}
+	if got, err := DecryptWithFactor(group, ct, CombineDecryptionFactors(group, factors), 10); err != nil || got != 4 {
+		t.Errorf("decrypted %d, %v; want 4", got, err)
+	}
+
+	// Key shares that still include the disqualified dealing do not match
+	if x := keyShare(trustees, 1); group.exp(group.G, x).Cmp(VerificationKey(group, public, 1)) == 0 {
+		t.Error("key share including the disqualified dealing matched the qualified verification key")
+	}
+}
@@ -7,7 +7,11 @@ require (
 	github.com/lib/pq v1.10.9 // direct
 )
 
-require github.com/joho/godotenv v1.5.1
+require (
+	github.com/golang-jwt/jwt/v5 v5.2.1
+	github.com/joho/godotenv v1.5.1
+	golang.org/x/crypto v0.31.0
+)
 
 require (
 	github.com/andybalholm/brotli v1.1.0 // indirect
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/Haste007/E-Voting/Backend/middleware"
+	"github.com/Haste007/E-Voting/Backend/models"
+	"github.com/Haste007/E-Voting/Backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// CreateAdmin adds a new admin account with a bcrypt-hashed password
+func CreateAdmin(c *fiber.Ctx) error {
+	var request struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Role     string `json:"role"`
+	}
+
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	if request.Username == "" || len(request.Password) < 8 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Username and a password of at least 8 characters are required"})
+	}
+	if !models.IsValidAdminRole(request.Role) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid role"})
+	}
+
+	hash, err := utils.HashPassword(request.Password)
+	if err != nil {
+		log.Println("Error hashing admin password:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create admin"})
+	}
+
+	var admin models.Admin
+	query := `
+        INSERT INTO admins (username, password_hash, role)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (username) DO NOTHING
+        RETURNING id, username, role, created_at
+    `
+	if err := utils.DB.QueryRow(query, request.Username, hash, request.Role).Scan(&admin.ID, &admin.Username, &admin.Role, &admin.CreatedAt); err != nil {
+		log.Println("Error creating admin:", err)
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Username is already taken"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(admin)
+}
+
+// GetAdmins lists all admin accounts without their password hashes
+func GetAdmins(c *fiber.Ctx) error {
+	rows, err := utils.DB.Query("SELECT id, username, role, created_at FROM admins ORDER BY id")
+	if err != nil {
+		log.Println("Error fetching admins:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch admins"})
+	}
+	defer rows.Close()
+
+	var admins []models.Admin
+	for rows.Next() {
+		var admin models.Admin
+		if err := rows.Scan(&admin.ID, &admin.Username, &admin.Role, &admin.CreatedAt); err != nil {
+			log.Println("Error parsing admin row:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to parse admins"})
+		}
+		admins = append(admins, admin)
+	}
+
+	return c.JSON(admins)
+}
+
+// DeleteAdmin removes an admin account by ID; admins cannot delete themselves
+func DeleteAdmin(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid admin ID"})
+	}
+
+	if current, ok := c.Locals(middleware.AdminLocalsKey).(*utils.AdminClaims); ok && current.AdminID == id {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Admins cannot delete their own account"})
+	}
+
+	result, err := utils.DB.Exec("DELETE FROM admins WHERE id = $1", id)
+	if err != nil {
+		log.Println("Error deleting admin:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete admin"})
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Admin not found"})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"database/sql"
+	"log"
 	"net/http"
-	"os"
 
+	"github.com/Haste007/E-Voting/Backend/utils"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -13,8 +15,11 @@ type AdminLoginRequest struct {
 }
 
 type AdminLoginResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message,omitempty"`
+	Success   bool   `json:"success"`
+	Message   string `json:"message,omitempty"`
+	Token     string `json:"token,omitempty"`
+	Role      string `json:"role,omitempty"`
+	ExpiresIn int    `json:"expires_in,omitempty"` // Token lifetime in seconds
 }
 
 func AdminLoginHandler(c *fiber.Ctx) error {
@@ -23,12 +28,29 @@ func AdminLoginHandler(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(AdminLoginResponse{Success: false, Message: "Invalid request body"})
 	}
 
-	// For security, use environment variables for admin credentials
-	adminUser := os.Getenv("ADMIN_USER")
-	adminPass := os.Getenv("ADMIN_PASS")
+	// Look up the admin account and compare against the stored bcrypt hash
+	var adminID int
+	var passwordHash, role string
+	query := "SELECT id, password_hash, role FROM admins WHERE username = $1"
+	err := utils.DB.QueryRow(query, req.Username).Scan(&adminID, &passwordHash, &role)
+	if err != nil && err != sql.ErrNoRows {
+		log.Println("Error fetching admin account:", err)
+		return c.Status(http.StatusInternalServerError).JSON(AdminLoginResponse{Success: false, Message: "Failed to log in"})
+	}
+	if err == sql.ErrNoRows || !utils.CheckPassword(passwordHash, req.Password) {
+		return c.Status(http.StatusUnauthorized).JSON(AdminLoginResponse{Success: false, Message: "Invalid credentials"})
+	}
 
-	if req.Username == adminUser && req.Password == adminPass {
-		return c.JSON(AdminLoginResponse{Success: true})
+	token, err := utils.GenerateAdminToken(adminID, req.Username, role)
+	if err != nil {
+		log.Println("Error issuing admin token:", err)
+		return c.Status(http.StatusInternalServerError).JSON(AdminLoginResponse{Success: false, Message: "Failed to issue admin token"})
 	}
-	return c.Status(http.StatusUnauthorized).JSON(AdminLoginResponse{Success: false, Message: "Invalid credentials"})
+
+	return c.JSON(AdminLoginResponse{
+		Success:   true,
+		Token:     token,
+		Role:      role,
+		ExpiresIn: int(utils.AdminTokenTTL.Seconds()),
+	})
 }
@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Haste007/E-Voting/Backend/approvals"
+	"github.com/Haste007/E-Voting/Backend/ballot"
+	"github.com/Haste007/E-Voting/Backend/certification"
+	"github.com/Haste007/E-Voting/Backend/lifecycle"
+	"github.com/Haste007/E-Voting/Backend/middleware"
+	"github.com/Haste007/E-Voting/Backend/seats"
+	"github.com/Haste007/E-Voting/Backend/tally"
+	"github.com/Haste007/E-Voting/Backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Error codes of refused result corrections
+const (
+	codeInvalidCorrection   = "INVALID_CORRECTION"
+	codeRankedCorrection    = "RANKED_RESULTS_NOT_CORRECTABLE"
+	codeNoPollingHours      = "NO_POLLING_HOURS"
+	codePollingHoursChanged = "POLLING_HOURS_CHANGED"
+)
+
+// adminIdentity returns the admin making the request, for approval records
+func adminIdentity(c *fiber.Ctx) approvals.Admin {
+	if claims, ok := c.Locals(middleware.AdminLocalsKey).(*utils.AdminClaims); ok {
+		return approvals.Admin{ID: claims.AdminID, Username: claims.Username}
+	}
+	return approvals.Admin{Username: "unknown"}
+}
+
+// approvalErrorResponse maps approval workflow errors to HTTP responses, and
+// anything else as actionErrorResponse does
+func approvalErrorResponse(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, approvals.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Approval request not found"})
+	case errors.Is(err, approvals.ErrSameAdmin):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, approvals.ErrNoApprover):
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, approvals.ErrDecided), errors.Is(err, approvals.ErrExpired), errors.Is(err, approvals.ErrDuplicate):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	return actionErrorResponse(c, err, fallback)
+}
+
+// proposeAction records a request to run action on the election in the :id
+// param once check passes. The body may carry a "reason".
+func proposeAction(c *fiber.Ctx, action string, check func(tx *sql.Tx, electionID int) error) error {
+	return proposeActionWith(c, action, check, nil)
+}
+
+// proposeActionWith is proposeAction for requests that carry a payload
+func proposeActionWith(c *fiber.Ctx, action string, check func(tx *sql.Tx, electionID int) error, payload interface{}) error {
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+		}
+	}
+	return propose(c, action, check, payload, strings.TrimSpace(body.Reason))
+}
+
+// propose stores an approval request in its own transaction once check passes
+func propose(c *fiber.Ctx, action string, check func(tx *sql.Tx, electionID int) error, payload interface{}, reason string) error {
+	electionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid election ID"})
+	}
+
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to propose action"})
+	}
+	defer tx.Rollback()
+
+	// Refuse now what could not run on approval either
+	if err := check(tx, electionID); err != nil {
+		return approvalErrorResponse(c, err, "Failed to propose action")
+	}
+
+	request, err := approvals.Propose(tx, electionID, action, payload, reason, adminIdentity(c))
+	if err != nil {
+		return approvalErrorResponse(c, err, "Failed to propose action")
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("Error committing approval request:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to propose action"})
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"message": "Waiting for approval by a second admin", "request": request})
+}
+
+// resultCorrection replaces the vote count of one result line
+type resultCorrection struct {
+	ConstituencyID int  `json:"constituencyId"`
+	CandidateID    *int `json:"candidateId"` // Omitted for the None of the Above line
+	Votes          int  `json:"votes"`
+}
+
+// check returns a *ballot.ValidationError if the correction cannot apply to the election
+func (r resultCorrection) check(q ballot.Querier, electionID int) error {
+	invalid := func(message string) error {
+		return &ballot.ValidationError{Code: codeInvalidCorrection, Message: message, Status: http.StatusUnprocessableEntity}
+	}
+	if r.Votes < 0 {
+		return invalid("Votes cannot be negative")
+	}
+
+	ballotType, err := ballot.BallotType(q, electionID)
+	if err != nil {
+		return err
+	}
+	// Ranked results come out of a count, not a single number per line
+	if ballot.IsRanked(ballotType) {
+		return &ballot.ValidationError{Code: codeRankedCorrection, Message: "Ranked results are corrected by recounting, not by editing a line", Status: http.StatusConflict}
+	}
+
+	var ok bool
+	query := "SELECT EXISTS (SELECT 1 FROM election_constituencies WHERE election_id = $1 AND constituency_id = $2)"
+	if err := q.QueryRow(query, electionID, r.ConstituencyID).Scan(&ok); err != nil {
+		return err
+	}
+	if !ok {
+		return invalid("Constituency is not part of this election")
+	}
+
+	if r.CandidateID == nil {
+		offered, err := ballot.OffersNOTA(q, electionID)
+		if err != nil {
+			return err
+		}
+		if !offered {
+			return invalid("Election does not offer None of the Above")
+		}
+		return nil
+	}
+	query = "SELECT EXISTS (SELECT 1 FROM candidates WHERE id = $1 AND constituency_id = $2)"
+	if err := q.QueryRow(query, *r.CandidateID, r.ConstituencyID).Scan(&ok); err != nil {
+		return err
+	}
+	if !ok {
+		return invalid("Candidate is not standing in this constituency")
+	}
+	return nil
+}
+
+// apply sets the corrected count, redoes the constituency's winner and the
+// reserved seat allocation that depend on it, and snapshots the corrected result
+func (r resultCorrection) apply(tx *sql.Tx, electionID int, reason, actor string) error {
+	if _, err := lifecycle.Require(tx, electionID, lifecycle.OpCorrectResult, "FOR UPDATE"); err != nil {
+		return err
+	}
+	if err := r.check(tx, electionID); err != nil {
+		return err
+	}
+
+	choice := ballot.ChoiceCandidate
+	if r.CandidateID == nil {
+		choice = ballot.ChoiceNOTA
+	}
+	updateQuery := `
+        UPDATE election_results SET total_votes = $4
+        WHERE election_id = $1 AND constituency_id = $2 AND choice = $5 AND candidate_id IS NOT DISTINCT FROM $3::int
+    `
+	result, err := tx.Exec(updateQuery, electionID, r.ConstituencyID, r.CandidateID, r.Votes, choice)
+	if err != nil {
+		return err
+	}
+	// Lines without any ballots have no row yet
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		insertQuery := `
+            INSERT INTO election_results (election_id, constituency_id, choice, candidate_id, total_votes)
+            VALUES ($1, $2, $5, $3, $4)
+        `
+		if _, err := tx.Exec(insertQuery, electionID, r.ConstituencyID, r.CandidateID, r.Votes, choice); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE election_results SET elected = FALSE WHERE election_id = $1 AND constituency_id = $2", electionID, r.ConstituencyID); err != nil {
+		return err
+	}
+	if err := ballot.MarkPluralityWinners(tx, electionID); err != nil {
+		return err
+	}
+	if err := seats.AllocateElection(tx, electionID); err != nil {
+		return err
+	}
+	_, err = tally.Correct(tx, electionID, r.ConstituencyID, reason, actor)
+	return err
+}
+
+// ProposeResultCorrection proposes replacing the count of one line of a
+// tallied election's results. A reason is required, and a second admin has
+// to approve the correction before it applies.
+func ProposeResultCorrection(c *fiber.Ctx) error {
+	var request struct {
+		resultCorrection
+		Reason string `json:"reason"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	request.Reason = strings.TrimSpace(request.Reason)
+	if request.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A reason is required"})
+	}
+
+	correction := request.resultCorrection
+	return propose(c, approvals.ActionCorrectResult, func(tx *sql.Tx, electionID int) error {
+		if _, err := lifecycle.Require(tx, electionID, lifecycle.OpCorrectResult, "FOR SHARE"); err != nil {
+			return err
+		}
+		return correction.check(tx, electionID)
+	}, correction, request.Reason)
+}
+
+// runApproved carries out an approved request inside tx and describes what it did
+func runApproved(tx *sql.Tx, request approvals.Request, actor string) (string, error) {
+	switch request.Action {
+	case approvals.ActionStart:
+		// Votes stop at the closing time, so approving the start approves it
+		// too, provided it is still the one the proposer saw
+		var hours pollingHours
+		if len(request.Payload) > 0 {
+			if err := json.Unmarshal(request.Payload, &hours); err != nil {
+				return "", err
+			}
+		}
+		if err := OpenElection(tx, request.ElectionID, actor); err != nil {
+			return "", err
+		}
+		if err := hours.requireCurrent(tx, request.ElectionID); err != nil {
+			return "", err
+		}
+		_, err := tx.Exec("UPDATE elections SET approved_closes_at = closes_at WHERE id = $1", request.ElectionID)
+		return "Election started successfully", err
+	case approvals.ActionEnd:
+		tallied, err := CloseElection(tx, request.ElectionID, actor)
+		// Trustee elections stay closed until enough trustees submit partial decryptions
+		if !tallied {
+			return "Election closed; results will be published once enough trustees decrypt the tally", err
+		}
+		return "Election ended and results calculated successfully", err
+	case approvals.ActionDelete:
+		return "Election deleted successfully", deleteElection(tx, request.ElectionID)
+	case approvals.ActionCertify:
+		if err := lifecycle.Transition(tx, request.ElectionID, lifecycle.StatusCertified, actor); err != nil {
+			return "", err
+		}
+		// Checked again under the row lock, as results may have been recounted since the proposal
+		return "Election certified successfully", certification.RequireComplete(tx, request.ElectionID)
+	case approvals.ActionHours:
+		var hours pollingHours
+		if err := json.Unmarshal(request.Payload, &hours); err != nil {
+			return "", err
+		}
+		return "Polling hours approved", hours.approve(tx, request.ElectionID)
+	case approvals.ActionCorrectResult:
+		var correction resultCorrection
+		if err := json.Unmarshal(request.Payload, &correction); err != nil {
+			return "", err
+		}
+		return "Result corrected successfully", correction.apply(tx, request.ElectionID, request.Reason, actor)
+	}
+	return "", errors.New("unknown action " + request.Action)
+}
+
+// ApproveRequest lets a second admin approve a pending request, which runs
+// the action in the same transaction. If the action fails the request stays
+// pending.
+func ApproveRequest(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request ID"})
+	}
+	approver := adminIdentity(c)
+
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to approve action"})
+	}
+	defer tx.Rollback()
+
+	request, err := approvals.Claim(tx, id, approver)
+	if err != nil {
+		return approvalErrorResponse(c, err, "Failed to approve action")
+	}
+
+	// The status history names both admins
+	actor := request.ProposedBy + " (approved by " + approver.Username + ")"
+	outcome, err := runApproved(tx, request, actor)
+	if err != nil {
+		return approvalErrorResponse(c, err, "Failed to run approved action")
+	}
+
+	decided, err := approvals.Decide(tx, id, approvals.StatusApproved, approver, outcome)
+	if err != nil {
+		log.Println("Error recording approval:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to approve action"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("Error committing approved action:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to approve action"})
+	}
+	return c.JSON(fiber.Map{"message": outcome, "request": decided})
+}
+
+// RejectRequest turns down a pending request; its proposer may use it to withdraw
+func RejectRequest(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request ID"})
+	}
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+		}
+	}
+
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reject action"})
+	}
+	defer tx.Rollback()
+
+	decided, err := approvals.Reject(tx, id, adminIdentity(c), strings.TrimSpace(body.Reason))
+	if err != nil {
+		return approvalErrorResponse(c, err, "Failed to reject action")
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("Error committing rejection:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reject action"})
+	}
+	return c.JSON(fiber.Map{"message": "Request rejected", "request": decided})
+}
+
+// GetApprovalRequests lists approval requests, newest first, optionally
+// filtered by ?electionId= and ?status= (pending, approved, rejected or expired)
+func GetApprovalRequests(c *fiber.Ctx) error {
+	electionID := c.QueryInt("electionId", 0)
+	status := c.Query("status")
+
+	requests, err := approvals.List(utils.DB, electionID, status)
+	if err != nil {
+		log.Println("Error fetching approval requests:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch approval requests"})
+	}
+	return c.JSON(requests)
+}
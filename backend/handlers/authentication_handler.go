@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"database/sql"
 	"encoding/base64"
 	"encoding/json"
 	"io"
@@ -23,10 +24,12 @@ func AuthenticateCitizen(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
 	}
 
-	// Retrieve the saved image path for the given NID
+	// Retrieve the citizen and the saved image path for the given NID
+	var citizenID int
+	var districtID sql.NullInt64
 	var imagePath string
-	query := "SELECT face FROM citizens WHERE nid = $1"
-	if err := utils.DB.QueryRow(query, request.NID).Scan(&imagePath); err != nil {
+	query := "SELECT id, district_id, face FROM citizens WHERE nid = $1"
+	if err := utils.DB.QueryRow(query, request.NID).Scan(&citizenID, &districtID, &imagePath); err != nil {
 		log.Println("Error fetching citizen image:", err)
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Citizen not found"})
 	}
@@ -88,7 +91,25 @@ func AuthenticateCitizen(c *fiber.Ctx) error {
 
 	// Check the similarity index
 	if similarity, ok := result["similarity_index"].(float64); ok && similarity > 0.5 {
-		return c.JSON(fiber.Map{"message": "Authentication successful", "nid": request.NID})
+		// A voter without a district cannot be placed in any constituency
+		if !districtID.Valid {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Citizen has no registered district"})
+		}
+
+		// Issue a short-lived voter session token scoped to the citizen and their district
+		token, err := utils.GenerateVoterToken(citizenID, request.NID, int(districtID.Int64))
+		if err != nil {
+			log.Println("Error issuing voter token:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to issue voter token"})
+		}
+
+		return c.JSON(fiber.Map{
+			"message":     "Authentication successful",
+			"nid":         request.NID,
+			"district_id": districtID.Int64,
+			"token":       token,
+			"expires_in":  int(utils.VoterTokenTTL.Seconds()),
+		})
 	}
 
 	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Authentication failed", "details": result})
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"strconv"
+
+	"github.com/Haste007/E-Voting/Backend/bulletin"
+	"github.com/Haste007/E-Voting/Backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxBulletinPage caps how many board entries one request returns
+const maxBulletinPage = 1000
+
+// GetBulletinBoard returns a page of an election's bulletin board, starting at
+// ?from= (default 0) with at most ?limit= entries
+func GetBulletinBoard(c *fiber.Ctx) error {
+	electionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid election ID"})
+	}
+	from := c.QueryInt("from", 0)
+	limit := c.QueryInt("limit", maxBulletinPage)
+	if from < 0 || limit <= 0 || limit > maxBulletinPage {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid page"})
+	}
+
+	entries, err := bulletin.Entries(utils.DB, electionID, from, limit)
+	if err != nil {
+		log.Println("Error fetching bulletin board:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch bulletin board"})
+	}
+
+	var size int
+	var headHash string
+	err = utils.DB.QueryRow("SELECT size, head_hash FROM bulletin_heads WHERE election_id = $1", electionID).Scan(&size, &headHash)
+	if err == sql.ErrNoRows {
+		headHash = bulletin.GenesisHash(electionID)
+	} else if err != nil {
+		log.Println("Error fetching bulletin board head:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch bulletin board"})
+	}
+
+	return c.JSON(fiber.Map{
+		"election_id":  electionID,
+		"genesis_hash": bulletin.GenesisHash(electionID),
+		"size":         size,
+		"head_hash":    headHash,
+		"entries":      entries,
+	})
+}
+
+// GetBulletinRoots returns every Merkle root published for an election's board
+func GetBulletinRoots(c *fiber.Ctx) error {
+	electionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid election ID"})
+	}
+
+	roots, err := bulletin.Roots(utils.DB, electionID)
+	if err != nil {
+		log.Println("Error fetching bulletin roots:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch bulletin roots"})
+	}
+
+	return c.JSON(roots)
+}
+
+// GetInclusionProof returns the audit path proving that the entry at :position
+// is in the published root of size ?size=, or in the latest root if omitted
+func GetInclusionProof(c *fiber.Ctx) error {
+	electionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid election ID"})
+	}
+	position, err := strconv.Atoi(c.Params("position"))
+	size := c.QueryInt("size", 0)
+	if err != nil || position < 0 || size < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid position or size"})
+	}
+
+	proof, err := bulletin.InclusionProofFor(utils.DB, electionID, position, size)
+	if err == sql.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No published root covers this entry yet"})
+	} else if err != nil {
+		log.Println("Error building inclusion proof:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to build inclusion proof"})
+	}
+
+	return c.JSON(proof)
+}
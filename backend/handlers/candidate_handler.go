@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/Haste007/E-Voting/Backend/lifecycle"
+	"github.com/Haste007/E-Voting/Backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// UploadCandidateSymbol stores the ballot symbol of an independent candidate.
+// Party candidates stand under their party's logo and cannot have one.
+func UploadCandidateSymbol(c *fiber.Ctx) error {
+	candidateID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid candidate ID"})
+	}
+
+	var request struct {
+		Symbol string `json:"symbol"` // Base64 encoded symbol image
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	decodedSymbol, err := base64.StdEncoding.DecodeString(request.Symbol)
+	if err != nil || len(decodedSymbol) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid symbol image"})
+	}
+
+	var independent bool
+	var electionID int
+	query := `
+        SELECT c.party_id IS NULL, ec.election_id
+        FROM candidates c
+        JOIN election_constituencies ec ON ec.constituency_id = c.constituency_id
+        WHERE c.id = $1
+    `
+	err = utils.DB.QueryRow(query, candidateID).Scan(&independent, &electionID)
+	if err == sql.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Candidate not found"})
+	} else if err != nil {
+		log.Println("Error fetching candidate:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save symbol"})
+	}
+	if !independent {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Party candidates use their party's logo"})
+	}
+
+	// The ballot may only change while the election can still be edited
+	if _, err := lifecycle.Require(utils.DB, electionID, lifecycle.OpEdit, ""); err != nil {
+		return lifecycleErrorResponse(c, err, "Failed to save symbol")
+	}
+
+	// Save the symbol image to the images/candidate_symbols folder
+	symbolPath := filepath.Join("images", "candidate_symbols", strconv.Itoa(candidateID)+".jpg")
+	if err := os.MkdirAll(filepath.Dir(symbolPath), os.ModePerm); err != nil {
+		log.Println("Error creating directory for symbol image:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save symbol"})
+	}
+	if err := os.WriteFile(symbolPath, decodedSymbol, 0644); err != nil {
+		log.Println("Error saving symbol image:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save symbol image"})
+	}
+
+	if _, err := utils.DB.Exec("UPDATE candidates SET symbol = $1 WHERE id = $2", symbolPath, candidateID); err != nil {
+		log.Println("Error updating candidate symbol:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save symbol"})
+	}
+
+	return c.JSON(fiber.Map{"id": candidateID, "symbol": symbolPath})
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/Haste007/E-Voting/Backend/certification"
+	"github.com/Haste007/E-Voting/Backend/middleware"
+	"github.com/Haste007/E-Voting/Backend/models"
+	"github.com/Haste007/E-Voting/Backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// certificationErrorResponse maps certification errors to HTTP responses, and
+// anything else as lifecycleErrorResponse does
+func certificationErrorResponse(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, certification.ErrInvalidKey):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, certification.ErrNotAssigned):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, certification.ErrNoSignedResult):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, certification.ErrNotInElection), errors.Is(err, certification.ErrNotOfficer), errors.Is(err, certification.ErrBadSignature):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, certification.ErrNoKey), errors.Is(err, certification.ErrAlreadySigned):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	return lifecycleErrorResponse(c, err, fallback)
+}
+
+// constituencyParams parses the :id and :constituencyId params, returning a
+// *fiber.Error with the message to send if either is invalid
+func constituencyParams(c *fiber.Ctx) (int, int, *fiber.Error) {
+	electionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return 0, 0, fiber.NewError(fiber.StatusBadRequest, "Invalid election ID")
+	}
+	constituencyID, err := strconv.Atoi(c.Params("constituencyId"))
+	if err != nil {
+		return 0, 0, fiber.NewError(fiber.StatusBadRequest, "Invalid constituency ID")
+	}
+	return electionID, constituencyID, nil
+}
+
+// AssignReturningOfficer makes a returning officer account responsible for
+// signing one constituency's result in an election, replacing any earlier one
+func AssignReturningOfficer(c *fiber.Ctx) error {
+	electionID, constituencyID, paramErr := constituencyParams(c)
+	if paramErr != nil {
+		return c.Status(paramErr.Code).JSON(fiber.Map{"error": paramErr.Message})
+	}
+	var request struct {
+		AdminID int `json:"adminId"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to assign returning officer"})
+	}
+	defer tx.Rollback()
+
+	if err := certification.Assign(tx, electionID, constituencyID, request.AdminID, adminActor(c)); err != nil {
+		return certificationErrorResponse(c, err, "Failed to assign returning officer")
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println("Error committing returning officer:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to assign returning officer"})
+	}
+	return c.JSON(fiber.Map{"message": "Returning officer assigned successfully"})
+}
+
+// RegisterSigningKey registers the Ed25519 public key a returning officer signs
+// results with. The officer keeps the private key; a new key replaces the old one.
+func RegisterSigningKey(c *fiber.Ctx) error {
+	adminID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid admin ID"})
+	}
+	var request struct {
+		PublicKey string `json:"publicKey"` // Base64 of the 32-byte key
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to register signing key"})
+	}
+	defer tx.Rollback()
+
+	keyID, err := certification.RegisterKey(tx, adminID, request.PublicKey, adminActor(c))
+	if err != nil {
+		return certificationErrorResponse(c, err, "Failed to register signing key")
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println("Error committing signing key:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to register signing key"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Signing key registered successfully", "key_id": keyID})
+}
+
+// GetResultDocument returns the canonical result document of a constituency
+// to its returning officer. The body is the exact bytes to sign.
+func GetResultDocument(c *fiber.Ctx) error {
+	electionID, constituencyID, paramErr := constituencyParams(c)
+	if paramErr != nil {
+		return c.Status(paramErr.Code).JSON(fiber.Map{"error": paramErr.Message})
+	}
+	claims := c.Locals(middleware.AdminLocalsKey).(*utils.AdminClaims)
+
+	data, _, err := certification.OfficerDocument(utils.DB, electionID, constituencyID, claims.AdminID)
+	if err != nil {
+		return certificationErrorResponse(c, err, "Failed to build result document")
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Send(data)
+}
+
+// SignResult stores the calling returning officer's signature over the
+// current result document of their constituency
+func SignResult(c *fiber.Ctx) error {
+	electionID, constituencyID, paramErr := constituencyParams(c)
+	if paramErr != nil {
+		return c.Status(paramErr.Code).JSON(fiber.Map{"error": paramErr.Message})
+	}
+	var request struct {
+		Signature string `json:"signature"` // Base64 Ed25519 signature over the document bytes
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	// Super admins pass every role check, but only returning officers sign results
+	claims := c.Locals(middleware.AdminLocalsKey).(*utils.AdminClaims)
+	if claims.Role != models.RoleReturningOfficer {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only returning officer accounts can sign results"})
+	}
+
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to sign result"})
+	}
+	defer tx.Rollback()
+
+	signed, err := certification.Sign(tx, electionID, constituencyID, models.Admin{ID: claims.AdminID, Username: claims.Username}, request.Signature)
+	if err != nil {
+		return certificationErrorResponse(c, err, "Failed to sign result")
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println("Error committing signature:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to sign result"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(signed)
+}
+
+// GetCertifications lists each constituency's returning officer and whether
+// its latest result is signed
+func GetCertifications(c *fiber.Ctx) error {
+	electionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid election ID"})
+	}
+
+	statuses, err := certification.Statuses(utils.DB, electionID)
+	if err != nil {
+		log.Println("Error fetching certifications:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch certifications"})
+	}
+	return c.JSON(statuses)
+}
+
+// GetSignedResult downloads the most recently signed result document of a
+// constituency, with the signature and public key needed to verify it offline.
+// ?all=true returns every signed version, newest first.
+func GetSignedResult(c *fiber.Ctx) error {
+	electionID, constituencyID, paramErr := constituencyParams(c)
+	if paramErr != nil {
+		return c.Status(paramErr.Code).JSON(fiber.Map{"error": paramErr.Message})
+	}
+
+	documents, err := certification.Documents(utils.DB, electionID, constituencyID)
+	if err != nil {
+		log.Println("Error fetching signed result:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch signed result"})
+	}
+	if len(documents) == 0 {
+		return certificationErrorResponse(c, certification.ErrNoSignedResult, "Failed to fetch signed result")
+	}
+	if c.QueryBool("all") {
+		return c.JSON(documents)
+	}
+
+	c.Attachment(fmt.Sprintf("election-%d-constituency-%d-v%d.json", electionID, constituencyID, documents[0].TallyVersion))
+	return c.JSON(documents[0])
+}
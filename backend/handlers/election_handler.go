@@ -1,24 +1,54 @@
 package handlers
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"strconv"
+	"strings"
 
+	"github.com/Haste007/E-Voting/Backend/approvals"
+	"github.com/Haste007/E-Voting/Backend/ballot"
+	"github.com/Haste007/E-Voting/Backend/bulletin"
+	"github.com/Haste007/E-Voting/Backend/certification"
+	"github.com/Haste007/E-Voting/Backend/lifecycle"
+	"github.com/Haste007/E-Voting/Backend/questions"
+	"github.com/Haste007/E-Voting/Backend/scheduler"
+	"github.com/Haste007/E-Voting/Backend/seats"
+	"github.com/Haste007/E-Voting/Backend/tabulation"
+	"github.com/Haste007/E-Voting/Backend/tally"
 	"github.com/Haste007/E-Voting/Backend/utils"
 	"github.com/gofiber/fiber/v2"
+	"github.com/lib/pq"
 )
 
 // CreateElection adds a new election
 func CreateElection(c *fiber.Ctx) error {
 	var request struct {
-		Name           string `json:"name"`
-		Constituencies []struct {
+		Name             string `json:"name"`
+		Encrypted        bool   `json:"encrypted"`        // Use homomorphically tallied encrypted ballots
+		AllowRevote      bool   `json:"allowRevote"`      // Let voters recast; only their last ballot counts
+		AllowNOTA        bool   `json:"allowNota"`        // Offer "None of the Above" on every ballot
+		LiveResults      bool   `json:"liveResults"`      // Stream running vote shares while polls are open
+		BallotType       string `json:"ballotType"`       // "fptp" (default), or "irv" or "stv" for ranked ballots
+		STVTransfer      string `json:"stvTransfer"`      // STV surplus transfers: "gregory" (default) or "meek"
+		TrusteeThreshold int    `json:"trusteeThreshold"` // Trustees needed to decrypt; 0 keeps the key on the server
+		OpensAt          string `json:"opensAt"`          // RFC 3339, or local "YYYY-MM-DDTHH:MM" in timeZone; empty to open by hand
+		ClosesAt         string `json:"closesAt"`         // As opensAt; empty to close by hand
+		TimeZone         string `json:"timeZone"`         // IANA zone, e.g. "Asia/Karachi" (default)
+		Constituencies   []struct {
 			Name       string   `json:"name"`
+			Tier       string   `json:"tier"` // "na" (default) or "pa"
 			Districts  []string `json:"districts"`
+			Seats      int      `json:"seats"` // Defaults to 1; more only for STV
 			Candidates []struct {
-				PartyID   int `json:"party_id"`
-				CitizenID int `json:"citizen_id"`
+				PartyID   *int `json:"party_id"` // Omitted for independents
+				CitizenID int  `json:"citizen_id"`
 			} `json:"candidates"`
 		} `json:"constituencies"`
+		Questions []questionRequest `json:"questions"` // Referendums and other ballot questions
 	}
 
 	// Parse and validate the request body
@@ -27,10 +57,86 @@ func CreateElection(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
 	}
 
-	if request.Name == "" || len(request.Constituencies) == 0 {
-		log.Println("Invalid election structure: Missing name or constituencies")
+	// A referendum may be held without any candidate race
+	if request.Name == "" || (len(request.Constituencies) == 0 && len(request.Questions) == 0) {
+		log.Println("Invalid election structure: Missing name, constituencies or questions")
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid election structure"})
 	}
+	for i := range request.Questions {
+		if problem := request.Questions[i].check(); problem != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": problem})
+		}
+	}
+
+	hours, err := scheduler.ParseHours(request.OpensAt, request.ClosesAt, request.TimeZone)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// A threshold of 1 would let a single trustee decrypt every ballot
+	if request.TrusteeThreshold != 0 && (!request.Encrypted || request.TrusteeThreshold < 2) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Trustee threshold must be at least 2 and requires encrypted ballots"})
+	}
+	var trusteeThreshold interface{}
+	if request.TrusteeThreshold > 0 {
+		trusteeThreshold = request.TrusteeThreshold
+	}
+
+	if request.BallotType == "" {
+		request.BallotType = ballot.TypeFPTP
+	}
+	if request.BallotType != ballot.TypeFPTP && request.BallotType != ballot.TypeIRV && request.BallotType != ballot.TypeSTV {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Ballot type must be \"fptp\", \"irv\" or \"stv\""})
+	}
+	var stvTransfer interface{}
+	if request.BallotType == ballot.TypeSTV {
+		if request.STVTransfer == "" {
+			request.STVTransfer = tabulation.TransferGregory
+		}
+		if request.STVTransfer != tabulation.TransferGregory && request.STVTransfer != tabulation.TransferMeek {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "STV transfer must be \"gregory\" or \"meek\""})
+		}
+		stvTransfer = request.STVTransfer
+	} else if request.STVTransfer != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "An STV transfer rule needs STV ballots"})
+	}
+	// Ranked ballots are counted in the clear and have no NOTA line
+	if ballot.IsRanked(request.BallotType) && (request.Encrypted || request.AllowNOTA) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Ranked ballots cannot be encrypted or offer None of the Above"})
+	}
+	// Encrypted ballots cannot be counted before the trustees decrypt them
+	if request.Encrypted && request.LiveResults {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Encrypted elections cannot stream live results"})
+	}
+
+	// Each district lies in one constituency per tier, so its voters have one
+	// National Assembly and one Provincial Assembly contest
+	covered := make(map[string]string)
+	for i := range request.Constituencies {
+		constituency := &request.Constituencies[i]
+		if constituency.Tier == "" {
+			constituency.Tier = ballot.TierNA
+		}
+		if !ballot.IsTier(constituency.Tier) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Constituency tier must be \"na\" or \"pa\""})
+		}
+		for _, districtName := range constituency.Districts {
+			key := constituency.Tier + ":" + districtName
+			if other, ok := covered[key]; ok {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "District " + districtName + " is in both " + other + " and " + constituency.Name})
+			}
+			covered[key] = constituency.Name
+		}
+	}
+
+	// The election is saved in one transaction, so a request rejected partway
+	// leaves nothing behind and can simply be retried
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save election"})
+	}
+	defer tx.Rollback()
 
 	// Save constituencies and get their IDs
 	constituencyIDs := make(map[string]int)
@@ -39,14 +145,24 @@ func CreateElection(c *fiber.Ctx) error {
 			log.Printf("Invalid constituency structure: %+v\n", constituency)
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid constituency structure"})
 		}
+		if constituency.Seats == 0 {
+			constituency.Seats = 1
+		}
+		// Only STV can fill more than one seat from the same ballots
+		if constituency.Seats < 1 || (constituency.Seats > 1 && request.BallotType != ballot.TypeSTV) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Multi-seat constituencies need STV ballots"})
+		}
+		if request.BallotType != ballot.TypeSTV && partyStandsTwice(constituency.Candidates) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Only STV elections allow several candidates per party in a constituency"})
+		}
 
 		var constituencyID int
 		query := `
-            INSERT INTO constituencies (name)
-            VALUES ($1)
+            INSERT INTO constituencies (name, tier, seats)
+            VALUES ($1, $2, $3)
             RETURNING id
         `
-		if err := utils.DB.QueryRow(query, constituency.Name).Scan(&constituencyID); err != nil {
+		if err := tx.QueryRow(query, constituency.Name, constituency.Tier, constituency.Seats).Scan(&constituencyID); err != nil {
 			log.Println("Error saving constituency:", err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save constituency"})
 		}
@@ -56,7 +172,7 @@ func CreateElection(c *fiber.Ctx) error {
 		for _, districtName := range constituency.Districts {
 			var districtID int
 			districtQuery := "SELECT id FROM districts WHERE name = $1"
-			if err := utils.DB.QueryRow(districtQuery, districtName).Scan(&districtID); err != nil {
+			if err := tx.QueryRow(districtQuery, districtName).Scan(&districtID); err != nil {
 				log.Println("Error finding district:", err)
 				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid district name"})
 			}
@@ -65,25 +181,35 @@ func CreateElection(c *fiber.Ctx) error {
                 INSERT INTO constituency_districts (constituency_id, district_id)
                 VALUES ($1, $2)
             `
-			if _, err := utils.DB.Exec(linkQuery, constituencyID, districtID); err != nil {
+			if _, err := tx.Exec(linkQuery, constituencyID, districtID); err != nil {
 				log.Println("Error linking district to constituency:", err)
 				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to link district to constituency"})
 			}
 		}
 	}
 
-	// Save the election and get its ID
+	// Save the election and get its ID; new elections start as drafts and
+	// derive voter pseudonyms from the current key version
 	var electionID int
 	electionQuery := `
-        INSERT INTO elections (name, date)
-        VALUES ($1, NOW())
+        INSERT INTO elections (name, date, opens_at, closes_at, time_zone, status, pseudonym_key_version, encrypted, trustee_threshold, allow_revote, allow_nota, live_results, ballot_type, stv_transfer)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
         RETURNING id
     `
-	if err := utils.DB.QueryRow(electionQuery, request.Name).Scan(&electionID); err != nil {
+	if err := tx.QueryRow(electionQuery, request.Name, hours.Date(), hours.OpensAt, hours.ClosesAt, hours.Zone, lifecycle.StatusDraft, utils.CurrentVoterKeyVersion(), request.Encrypted, trusteeThreshold, request.AllowRevote, request.AllowNOTA, request.LiveResults, request.BallotType, stvTransfer).Scan(&electionID); err != nil {
 		log.Println("Error saving election:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save election"})
 	}
 
+	historyQuery := `
+        INSERT INTO election_status_history (election_id, from_status, to_status, actor)
+        VALUES ($1, NULL, $2, $3)
+    `
+	if _, err := tx.Exec(historyQuery, electionID, lifecycle.StatusDraft, adminActor(c)); err != nil {
+		log.Println("Error recording election creation:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save election"})
+	}
+
 	// Link the election with constituencies and save candidates
 	for _, constituency := range request.Constituencies {
 		constituencyID := constituencyIDs[constituency.Name]
@@ -93,7 +219,7 @@ func CreateElection(c *fiber.Ctx) error {
             INSERT INTO election_constituencies (election_id, constituency_id)
             VALUES ($1, $2)
         `
-		if _, err := utils.DB.Exec(linkQuery, electionID, constituencyID); err != nil {
+		if _, err := tx.Exec(linkQuery, electionID, constituencyID); err != nil {
 			log.Println("Error linking election with constituency:", err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to link election with constituencies"})
 		}
@@ -104,13 +230,26 @@ func CreateElection(c *fiber.Ctx) error {
                 INSERT INTO candidates (party_id, citizen_id, constituency_id)
                 VALUES ($1, $2, $3)
             `
-			if _, err := utils.DB.Exec(candidateQuery, candidate.PartyID, candidate.CitizenID, constituencyID); err != nil {
+			if _, err := tx.Exec(candidateQuery, candidate.PartyID, candidate.CitizenID, constituencyID); err != nil {
 				log.Println("Error saving candidate:", err, "\n", "query: ", candidateQuery, candidate.PartyID, candidate.CitizenID, constituencyID)
 				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save candidate"})
 			}
 		}
 	}
 
+	for _, question := range request.Questions {
+		if _, err := saveQuestion(tx, electionID, question); errors.Is(err, errUnknownDistrict) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		} else if err != nil {
+			log.Println("Error saving question:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save question"})
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("Error committing election:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save election"})
+	}
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"id": electionID, "message": "Election created successfully"})
 }
 
@@ -118,38 +257,53 @@ func CreateElection(c *fiber.Ctx) error {
 func GetElection(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var election struct {
-		ID             int    `json:"id"`
-		Name           string `json:"name"`
-		Date           string `json:"date"`
-		TimeLimit      int    `json:"time_limit"`
-		Started        bool   `json:"started"`
+		ID             int     `json:"id"`
+		Name           string  `json:"name"`
+		Date           string  `json:"date"`
+		OpensAt        *string `json:"opens_at"`  // RFC 3339 in time_zone; null when polls open by hand
+		ClosesAt       *string `json:"closes_at"` // RFC 3339 in time_zone; null when polls close by hand
+		TimeZone       string  `json:"time_zone"`
+		Status         string  `json:"status"`
+		Encrypted      bool    `json:"encrypted"`
+		AllowRevote    bool    `json:"allow_revote"`
+		AllowNOTA      bool    `json:"allow_nota"`
+		LiveResults    bool    `json:"live_results"`
+		BallotType     string  `json:"ballot_type"`
+		STVTransfer    *string `json:"stv_transfer"` // Null unless ballot_type is "stv"
 		Constituencies []struct {
 			ID         int    `json:"id"`
 			Name       string `json:"name"`
+			Tier       string `json:"tier"`
+			Seats      int    `json:"seats"`
 			Candidates []struct {
-				PartyID   int `json:"party_id"`
-				CitizenID int `json:"citizen_id"`
+				ID        int  `json:"id"`
+				PartyID   *int `json:"party_id"` // Null for independents
+				CitizenID int  `json:"citizen_id"`
 			} `json:"candidates"`
 		} `json:"constituencies"`
 	}
 
 	// Fetch election details
 	query := `
-        SELECT id, name, date, time_limit, started
+        SELECT id, name, date, opens_at, closes_at, time_zone, status, encrypted, allow_revote, allow_nota, live_results, ballot_type, stv_transfer
         FROM elections
         WHERE id = $1
     `
-	if err := utils.DB.QueryRow(query, id).Scan(&election.ID, &election.Name, &election.Date, &election.TimeLimit, &election.Started); err != nil {
+	var opensAt, closesAt sql.NullTime
+	if err := utils.DB.QueryRow(query, id).Scan(&election.ID, &election.Name, &election.Date, &opensAt, &closesAt, &election.TimeZone, &election.Status, &election.Encrypted, &election.AllowRevote, &election.AllowNOTA, &election.LiveResults, &election.BallotType, &election.STVTransfer); err != nil {
 		log.Println("Error fetching election:", err)
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Election not found"})
 	}
+	election.OpensAt = scheduler.Format(opensAt, election.TimeZone)
+	election.ClosesAt = scheduler.Format(closesAt, election.TimeZone)
 
 	// Fetch constituencies for the election
 	constituencyQuery := `
-        SELECT c.id, c.name
+        SELECT c.id, c.name, c.tier, c.seats
         FROM constituencies c
         JOIN election_constituencies ec ON c.id = ec.constituency_id
         WHERE ec.election_id = $1
+        ORDER BY c.tier, c.id
     `
 	rows, err := utils.DB.Query(constituencyQuery, id)
 	if err != nil {
@@ -162,19 +316,22 @@ func GetElection(c *fiber.Ctx) error {
 		var constituency struct {
 			ID         int    `json:"id"`
 			Name       string `json:"name"`
+			Tier       string `json:"tier"`
+			Seats      int    `json:"seats"`
 			Candidates []struct {
-				PartyID   int `json:"party_id"`
-				CitizenID int `json:"citizen_id"`
+				ID        int  `json:"id"`
+				PartyID   *int `json:"party_id"` // Null for independents
+				CitizenID int  `json:"citizen_id"`
 			} `json:"candidates"`
 		}
-		if err := rows.Scan(&constituency.ID, &constituency.Name); err != nil {
+		if err := rows.Scan(&constituency.ID, &constituency.Name, &constituency.Tier, &constituency.Seats); err != nil {
 			log.Println("Error parsing constituency row:", err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to parse constituencies"})
 		}
 
 		// Fetch candidates for the constituency
 		candidateQuery := `
-            SELECT party_id, citizen_id
+            SELECT id, party_id, citizen_id
             FROM candidates
             WHERE constituency_id = $1
         `
@@ -187,10 +344,11 @@ func GetElection(c *fiber.Ctx) error {
 
 		for candidateRows.Next() {
 			var candidate struct {
-				PartyID   int `json:"party_id"`
-				CitizenID int `json:"citizen_id"`
+				ID        int  `json:"id"`
+				PartyID   *int `json:"party_id"` // Null for independents
+				CitizenID int  `json:"citizen_id"`
 			}
-			if err := candidateRows.Scan(&candidate.PartyID, &candidate.CitizenID); err != nil {
+			if err := candidateRows.Scan(&candidate.ID, &candidate.PartyID, &candidate.CitizenID); err != nil {
 				log.Println("Error parsing candidate row:", err)
 				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to parse candidates"})
 			}
@@ -208,13 +366,14 @@ func UpdateElection(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var request struct {
 		Name           string `json:"name"`
-		Date           string `json:"date"`
-		TimeLimit      int    `json:"time_limit"`
+		OpensAt        string `json:"opensAt"`  // As in CreateElection; empty clears the opening time
+		ClosesAt       string `json:"closesAt"` // Empty clears the closing time
+		TimeZone       string `json:"timeZone"`
 		Constituencies []struct {
 			ID         int `json:"id"` // Constituency ID
 			Candidates []struct {
-				PartyID   int `json:"party_id"`
-				CitizenID int `json:"citizen_id"`
+				PartyID   *int `json:"party_id"` // Omitted for independents
+				CitizenID int  `json:"citizen_id"`
 			} `json:"candidates"`
 		} `json:"constituencies"`
 	}
@@ -222,28 +381,71 @@ func UpdateElection(c *fiber.Ctx) error {
 	if err := c.BodyParser(&request); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
 	}
+	hours, err := scheduler.ParseHours(request.OpensAt, request.ClosesAt, request.TimeZone)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// The whole edit is one transaction under the election's row lock, so the
+	// scheduler cannot open polls while constituencies and candidates are replaced
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update election"})
+	}
+	defer tx.Rollback()
+
+	// Only drafts and scheduled elections may be edited
+	if _, err := lifecycle.Require(tx, id, lifecycle.OpEdit, "FOR UPDATE"); err != nil {
+		return lifecycleErrorResponse(c, err, "Failed to update election")
+	}
+
+	ballotType, err := ballot.BallotType(tx, id)
+	if err != nil {
+		log.Println("Error fetching ballot type:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update election"})
+	}
+	constituencyIDs := make([]int, 0, len(request.Constituencies))
+	for _, constituency := range request.Constituencies {
+		if ballotType != ballot.TypeSTV && partyStandsTwice(constituency.Candidates) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Only STV elections allow several candidates per party in a constituency"})
+		}
+		constituencyIDs = append(constituencyIDs, constituency.ID)
+	}
+
+	// A district may lie in only one of the election's constituencies per tier
+	var district, tier string
+	overlapQuery := `
+        SELECT d.name, c.tier
+        FROM constituency_districts cd
+        JOIN constituencies c ON c.id = cd.constituency_id
+        JOIN districts d ON d.id = cd.district_id
+        WHERE cd.constituency_id = ANY($1)
+        GROUP BY d.id, d.name, c.tier
+        HAVING COUNT(*) > 1
+        LIMIT 1
+    `
+	err = tx.QueryRow(overlapQuery, pq.Array(constituencyIDs)).Scan(&district, &tier)
+	if err == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "District " + district + " is in more than one " + strings.ToUpper(tier) + " constituency"})
+	} else if err != sql.ErrNoRows {
+		log.Println("Error checking constituency districts:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update election"})
+	}
 
 	// Update the election
 	query := `
         UPDATE elections
-        SET name = $1, date = $2, time_limit = $3
-        WHERE id = $4
+        SET name = $1, date = $2, opens_at = $3, closes_at = $4, time_zone = $5
+        WHERE id = $6
     `
-	if _, err := utils.DB.Exec(query, request.Name, request.Date, request.TimeLimit, id); err != nil {
+	if _, err := tx.Exec(query, request.Name, hours.Date(), hours.OpensAt, hours.ClosesAt, hours.Zone, id); err != nil {
 		log.Println("Error updating election:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update election"})
 	}
 
-	// Clear existing constituencies and candidates for the election
-	clearConstituenciesQuery := `
-        DELETE FROM election_constituencies
-        WHERE election_id = $1
-    `
-	if _, err := utils.DB.Exec(clearConstituenciesQuery, id); err != nil {
-		log.Println("Error clearing constituencies:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to clear constituencies"})
-	}
-
+	// Clear existing candidates and constituencies for the election; candidates
+	// go first, while the links that find them still exist
 	clearCandidatesQuery := `
         DELETE FROM candidates
         WHERE constituency_id IN (
@@ -252,11 +454,20 @@ func UpdateElection(c *fiber.Ctx) error {
             WHERE election_id = $1
         )
     `
-	if _, err := utils.DB.Exec(clearCandidatesQuery, id); err != nil {
+	if _, err := tx.Exec(clearCandidatesQuery, id); err != nil {
 		log.Println("Error clearing candidates:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to clear candidates"})
 	}
 
+	clearConstituenciesQuery := `
+        DELETE FROM election_constituencies
+        WHERE election_id = $1
+    `
+	if _, err := tx.Exec(clearConstituenciesQuery, id); err != nil {
+		log.Println("Error clearing constituencies:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to clear constituencies"})
+	}
+
 	// Insert updated constituencies and candidates
 	for _, constituency := range request.Constituencies {
 		// Link the election with the constituency
@@ -264,7 +475,7 @@ func UpdateElection(c *fiber.Ctx) error {
             INSERT INTO election_constituencies (election_id, constituency_id)
             VALUES ($1, $2)
         `
-		if _, err := utils.DB.Exec(constituencyQuery, id, constituency.ID); err != nil {
+		if _, err := tx.Exec(constituencyQuery, id, constituency.ID); err != nil {
 			log.Println("Error adding constituencies:", err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to add constituencies to election"})
 		}
@@ -275,87 +486,279 @@ func UpdateElection(c *fiber.Ctx) error {
                 INSERT INTO candidates (party_id, citizen_id, constituency_id)
                 VALUES ($1, $2, $3)
             `
-			if _, err := utils.DB.Exec(candidateQuery, candidate.PartyID, candidate.CitizenID, constituency.ID); err != nil {
+			if _, err := tx.Exec(candidateQuery, candidate.PartyID, candidate.CitizenID, constituency.ID); err != nil {
 				log.Println("Error adding candidates:", err)
 				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to add candidates to constituency"})
 			}
 		}
 	}
 
+	if err := tx.Commit(); err != nil {
+		log.Println("Error committing election update:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update election"})
+	}
 	return c.JSON(fiber.Map{"message": "Election updated successfully"})
 }
 
-// DeleteElection removes an election by ID
-func DeleteElection(c *fiber.Ctx) error {
-	id := c.Params("id")
-	query := "DELETE FROM elections WHERE id = $1"
-	if _, err := utils.DB.Exec(query, id); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete election"})
+// partyStandsTwice reports whether a party fields several candidates in one
+// constituency, which only STV can count without splitting the party's vote
+func partyStandsTwice(candidates []struct {
+	PartyID   *int `json:"party_id"` // Omitted for independents
+	CitizenID int  `json:"citizen_id"`
+}) bool {
+	standing := make(map[int]bool)
+	for _, candidate := range candidates {
+		if candidate.PartyID == nil {
+			continue
+		}
+		if standing[*candidate.PartyID] {
+			return true
+		}
+		standing[*candidate.PartyID] = true
 	}
+	return false
+}
 
-	return c.SendStatus(fiber.StatusNoContent)
+// DeleteElection proposes deleting an election, which also deletes its
+// constituency links and candidates. A second admin has to approve it.
+func DeleteElection(c *fiber.Ctx) error {
+	return proposeAction(c, approvals.ActionDelete, func(tx *sql.Tx, electionID int) error {
+		// Elections that have opened hold votes and cannot be deleted
+		_, err := lifecycle.Require(tx, electionID, lifecycle.OpDelete, "FOR SHARE")
+		return err
+	})
+}
+
+// deleteElection deletes an election once its deletion is approved
+func deleteElection(tx *sql.Tx, electionID int) error {
+	if _, err := lifecycle.Require(tx, electionID, lifecycle.OpDelete, "FOR UPDATE"); err != nil {
+		return err
+	}
+	_, err := tx.Exec("DELETE FROM elections WHERE id = $1", electionID)
+	return err
 }
 
-// StartElection starts an election
+// StartElection proposes opening polls for a scheduled election; it opens once
+// a second admin approves. Encrypted elections need their key, so trustee
+// elections cannot open before the key ceremony completes. The request records
+// the polling hours, as approving it approves the closing time.
 func StartElection(c *fiber.Ctx) error {
-	id := c.Params("id")
+	electionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid election ID"})
+	}
+	hours, err := currentHours(utils.DB, electionID)
+	if err != nil {
+		return lifecycleErrorResponse(c, err, "Failed to propose action")
+	}
+	return proposeActionWith(c, approvals.ActionStart, func(tx *sql.Tx, electionID int) error {
+		if err := requireTransition(tx, electionID, lifecycle.StatusOpen); err != nil {
+			return err
+		}
+		if err := hours.requireCurrent(tx, electionID); err != nil {
+			return err
+		}
+		return ballot.RequireElectionKey(tx, electionID)
+	}, hours)
+}
+
+// OpenElection opens polls inside tx for the poll scheduler. Like StartElection
+// it refuses to open an encrypted election that has no key yet.
+func OpenElection(tx *sql.Tx, electionID int, actor string) error {
+	if err := lifecycle.Transition(tx, electionID, lifecycle.StatusOpen, actor); err != nil {
+		return err
+	}
+	return ballot.RequireElectionKey(tx, electionID)
+}
+
+// EndElection proposes closing polls and calculating results, which happens
+// once a second admin approves
+func EndElection(c *fiber.Ctx) error {
+	return proposeAction(c, approvals.ActionEnd, func(tx *sql.Tx, electionID int) error {
+		return requireTransition(tx, electionID, lifecycle.StatusClosed)
+	})
+}
+
+// CloseElection closes polls inside tx, seals the bulletin board and runs the
+// tally. It reports false when an encrypted election must wait closed for its
+// trustees' partial decryptions. Approved end requests and the poll scheduler
+// both use it.
+func CloseElection(tx *sql.Tx, electionID int, actor string) (bool, error) {
+	if err := lifecycle.Transition(tx, electionID, lifecycle.StatusClosed, actor); err != nil {
+		return false, err
+	}
+
+	// Seal the bulletin board with a final root before counting
+	if err := publishPlaintext(tx, electionID); err != nil {
+		return false, fmt.Errorf("publishing plaintext ballots: %w", err)
+	}
+	if err := publishSuperseded(tx, electionID); err != nil {
+		return false, fmt.Errorf("publishing replaced ballots: %w", err)
+	}
+	if err := bulletin.PublishRoot(tx, electionID); err != nil {
+		return false, fmt.Errorf("publishing final bulletin root: %w", err)
+	}
+
+	// Question answers are plaintext even in encrypted elections, so they are
+	// counted now rather than waiting for any trustee decryption
+	if err := questions.TallyElection(tx, electionID); err != nil {
+		return false, fmt.Errorf("tallying ballot questions: %w", err)
+	}
+
+	encrypted, err := ballot.IsEncrypted(tx, electionID)
+	if err != nil {
+		return false, fmt.Errorf("reading election mode: %w", err)
+	}
+
+	if encrypted {
+		// Only the homomorphic per-constituency aggregates are decrypted
+		tallied, err := ballot.TallyEncrypted(tx, electionID)
+		if err != nil {
+			return false, fmt.Errorf("tallying encrypted ballots: %w", err)
+		}
+		if !tallied {
+			return false, nil
+		}
+	}
+
+	if _, err := tally.Run(tx, electionID, "Polls closed", actor); err != nil {
+		return false, fmt.Errorf("populating election results: %w", err)
+	}
+
+	if err := lifecycle.Transition(tx, electionID, lifecycle.StatusTallied, actor); err != nil {
+		return false, err
+	}
+	return true, nil
+}
 
-	// Update the started column to TRUE
+// publishPlaintext appends the counted plaintext ballots and the ballot
+// question answers of an election to its bulletin board. Ballot and answer IDs
+// are random, so ordering by them shuffles the batch and the board gives away
+// nothing about when each was cast. Replaced ballots are not published at all.
+func publishPlaintext(tx *sql.Tx, electionID int) error {
 	query := `
-        UPDATE elections
-        SET started = TRUE
-        WHERE id = $1
+        SELECT constituency_id, choice, candidate_id, ranking
+        FROM ballot_box
+        WHERE election_id = $1 AND choice IS NOT NULL AND NOT replaced
+        ORDER BY id
     `
-	if _, err := utils.DB.Exec(query, id); err != nil {
-		log.Println("Error starting election:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to start election"})
+	rows, err := tx.Query(query, electionID)
+	if err != nil {
+		return err
+	}
+	var payloads []bulletin.Payload
+	for rows.Next() {
+		var payload bulletin.Payload
+		var choice string
+		var ranking []byte
+		if err := rows.Scan(&payload.ConstituencyID, &choice, &payload.CandidateID, &ranking); err != nil {
+			rows.Close()
+			return err
+		}
+		switch choice {
+		case ballot.ChoiceNOTA:
+			payload.NOTA = true
+		case ballot.ChoiceRanked:
+			if err := json.Unmarshal(ranking, &payload.Ranking); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		payloads = append(payloads, payload)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
 	}
 
-	return c.JSON(fiber.Map{"message": "Election started successfully"})
+	answerQuery := `
+        SELECT a.question_id, a.option_id
+        FROM question_answers a
+        JOIN ballot_questions q ON q.id = a.question_id
+        WHERE q.election_id = $1
+        ORDER BY a.id
+    `
+	rows, err = tx.Query(answerQuery, electionID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var payload bulletin.Payload
+		if err := rows.Scan(&payload.QuestionID, &payload.OptionID); err != nil {
+			rows.Close()
+			return err
+		}
+		payloads = append(payloads, payload)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, payload := range payloads {
+		if _, err := bulletin.Append(tx, electionID, payload); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// EndElection ends an election and calculates results
-func EndElection(c *fiber.Ctx) error {
-	id := c.Params("id")
+// publishSuperseded appends the board positions of replaced encrypted ballots
+// to the bulletin board of a re-voting election, so verifiers can leave them out
+// too. They are only published once polls close, when knowing them no longer
+// helps a coercer. The set of replaced ballots is final then, so the re-vote
+// tags that found each voter's previous ballot are deleted.
+func publishSuperseded(tx *sql.Tx, electionID int) error {
+	if _, err := tx.Exec("DELETE FROM revote_tags WHERE election_id = $1", electionID); err != nil {
+		return err
+	}
 
-	// Update the started column to FALSE
+	var allowRevote bool
+	if err := tx.QueryRow("SELECT allow_revote FROM elections WHERE id = $1", electionID).Scan(&allowRevote); err != nil || !allowRevote {
+		return err
+	}
+
+	// Ballots keep no board position; an encrypted ballot's entry is the one
+	// carrying its ciphertext, which is freshly randomised for every ballot
 	query := `
-        UPDATE elections
-        SET started = FALSE,
-		ended = TRUE
-        WHERE id = $1
+        SELECT bb.position
+        FROM bulletin_board bb
+        JOIN ballot_box b ON b.election_id = bb.election_id AND b.replaced
+            AND b.encrypted_choice = (bb.payload::JSONB)->'encrypted_choice'
+        WHERE bb.election_id = $1
+        ORDER BY bb.position
     `
-	if _, err := utils.DB.Exec(query, id); err != nil {
-		log.Println("Error ending election:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to end election"})
-	}
-
-	// Calculate results and populate the election_results table
-	resultQuery := `
-        INSERT INTO election_results (election_id, constituency_id, party_id, total_votes)
-        SELECT
-            v.election_id,
-            v.constituency_id,
-            v.party_id,
-            COUNT(v.id) AS total_votes
-        FROM votes v
-        WHERE v.election_id = $1
-        GROUP BY v.election_id, v.constituency_id, v.party_id
-    `
-	if _, err := utils.DB.Exec(resultQuery, id); err != nil {
-		log.Println("Error populating election results:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to calculate election results"})
+	rows, err := tx.Query(query, electionID)
+	if err != nil {
+		return err
+	}
+	var superseded []int
+	for rows.Next() {
+		var position int
+		if err := rows.Scan(&position); err != nil {
+			rows.Close()
+			return err
+		}
+		superseded = append(superseded, position)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
 	}
 
-	return c.JSON(fiber.Map{"message": "Election ended and results calculated successfully"})
+	if len(superseded) == 0 {
+		return nil
+	}
+	_, err = bulletin.Append(tx, electionID, bulletin.Payload{Superseded: superseded})
+	return err
 }
 
-// GetUpcomingElections fetches all elections that have not happened yet (no entry in the election_results table)
+// GetUpcomingElections fetches all elections that have not been tallied yet
 func GetUpcomingElections(c *fiber.Ctx) error {
 	query := `
-        SELECT e.id, e.name, e.date, e.started
+        SELECT e.id, e.name, e.date, e.status
         FROM elections e
-        where e.ended = false
+        WHERE e.status NOT IN ('tallied', 'certified')
     `
 
 	rows, err := utils.DB.Query(query)
@@ -369,13 +772,15 @@ func GetUpcomingElections(c *fiber.Ctx) error {
 		ID             int    `json:"id"`
 		Name           string `json:"name"`
 		Date           string `json:"date"`
-		Started        bool   `json:"started"`
+		Status         string `json:"status"`
 		Constituencies []struct {
 			ID         int    `json:"id"`
 			Name       string `json:"name"`
+			Tier       string `json:"tier"`
 			Candidates []struct {
-				PartyID   int `json:"party_id"`
-				CitizenID int `json:"citizen_id"`
+				ID        int  `json:"id"`
+				PartyID   *int `json:"party_id"` // Null for independents
+				CitizenID int  `json:"citizen_id"`
 			} `json:"candidates"`
 		} `json:"constituencies"`
 	}
@@ -385,28 +790,31 @@ func GetUpcomingElections(c *fiber.Ctx) error {
 			ID             int    `json:"id"`
 			Name           string `json:"name"`
 			Date           string `json:"date"`
-			Started        bool   `json:"started"`
+			Status         string `json:"status"`
 			Constituencies []struct {
 				ID         int    `json:"id"`
 				Name       string `json:"name"`
+				Tier       string `json:"tier"`
 				Candidates []struct {
-					PartyID   int `json:"party_id"`
-					CitizenID int `json:"citizen_id"`
+					ID        int  `json:"id"`
+					PartyID   *int `json:"party_id"` // Null for independents
+					CitizenID int  `json:"citizen_id"`
 				} `json:"candidates"`
 			} `json:"constituencies"`
 		}
 
-		if err := rows.Scan(&election.ID, &election.Name, &election.Date, &election.Started); err != nil {
+		if err := rows.Scan(&election.ID, &election.Name, &election.Date, &election.Status); err != nil {
 			log.Println("Error scanning election row:", err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to parse upcoming elections"})
 		}
 
 		// Fetch constituencies for the election
 		constituencyQuery := `
-            SELECT c.id, c.name
+            SELECT c.id, c.name, c.tier
             FROM constituencies c
             JOIN election_constituencies ec ON c.id = ec.constituency_id
             WHERE ec.election_id = $1
+            ORDER BY c.tier, c.id
         `
 		constituencyRows, err := utils.DB.Query(constituencyQuery, election.ID)
 		if err != nil {
@@ -419,19 +827,21 @@ func GetUpcomingElections(c *fiber.Ctx) error {
 			var constituency struct {
 				ID         int    `json:"id"`
 				Name       string `json:"name"`
+				Tier       string `json:"tier"`
 				Candidates []struct {
-					PartyID   int `json:"party_id"`
-					CitizenID int `json:"citizen_id"`
+					ID        int  `json:"id"`
+					PartyID   *int `json:"party_id"` // Null for independents
+					CitizenID int  `json:"citizen_id"`
 				} `json:"candidates"`
 			}
-			if err := constituencyRows.Scan(&constituency.ID, &constituency.Name); err != nil {
+			if err := constituencyRows.Scan(&constituency.ID, &constituency.Name, &constituency.Tier); err != nil {
 				log.Println("Error parsing constituency row:", err)
 				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to parse constituencies"})
 			}
 
 			// Fetch candidates for the constituency
 			candidateQuery := `
-                SELECT party_id, citizen_id
+                SELECT id, party_id, citizen_id
                 FROM candidates
                 WHERE constituency_id = $1
             `
@@ -444,10 +854,11 @@ func GetUpcomingElections(c *fiber.Ctx) error {
 
 			for candidateRows.Next() {
 				var candidate struct {
-					PartyID   int `json:"party_id"`
-					CitizenID int `json:"citizen_id"`
+					ID        int  `json:"id"`
+					PartyID   *int `json:"party_id"` // Null for independents
+					CitizenID int  `json:"citizen_id"`
 				}
-				if err := candidateRows.Scan(&candidate.PartyID, &candidate.CitizenID); err != nil {
+				if err := candidateRows.Scan(&candidate.ID, &candidate.PartyID, &candidate.CitizenID); err != nil {
 					log.Println("Error parsing candidate row:", err)
 					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to parse candidates"})
 				}
@@ -466,9 +877,9 @@ func GetUpcomingElections(c *fiber.Ctx) error {
 // GetPastElections fetches all elections whose data is available in the election_results table
 func GetPastElections(c *fiber.Ctx) error {
 	query := `
-        SELECT e.id, e.name, e.date
+        SELECT e.id, e.name, e.date, e.status
         FROM elections e
-        WHERE e.ended = TRUE
+        WHERE e.status IN ('tallied', 'certified')
     `
 
 	rows, err := utils.DB.Query(query)
@@ -479,49 +890,88 @@ func GetPastElections(c *fiber.Ctx) error {
 	defer rows.Close()
 
 	var pastElections []struct {
-		ID             int    `json:"id"`
-		Name           string `json:"name"`
-		Date           string `json:"date"`
+		ID             int                `json:"id"`
+		Name           string             `json:"name"`
+		Date           string             `json:"date"`
+		Status         string             `json:"status"`
+		PartySeats     []seats.PartySeats `json:"party_seats"`    // General and reserved seats won by each party
+		Questions      []questions.Result `json:"questions"`      // Outcome of each ballot question
+		ResultsSigned  bool               `json:"results_signed"` // Every constituency's latest tally is signed by its returning officer
 		Constituencies []struct {
-			ID      int    `json:"id"`
-			Name    string `json:"name"`
-			Results []struct {
-				PartyID    int    `json:"party_id"`
-				PartyName  string `json:"party_name"`
-				Candidate  string `json:"candidate"`
-				TotalVotes int    `json:"total_votes"`
+			ID               int                  `json:"id"`
+			Name             string               `json:"name"`
+			Tier             string               `json:"tier"`
+			Seats            int                  `json:"seats"`
+			NOTAVotes        int                  `json:"nota_votes"`
+			Turnout          int                  `json:"turnout"` // Counted ballots, NOTA included
+			RegisteredVoters int                  `json:"registered_voters"`
+			CountReport      json.RawMessage      `json:"count_report,omitempty"` // Round-by-round report or STV count sheet of ranked counts
+			Certification    certification.Status `json:"certification"`          // Returning officer signature over the latest tally
+			Results          []struct {
+				CandidateID int    `json:"candidate_id"`
+				PartyID     *int   `json:"party_id"` // Null for independents
+				PartyName   string `json:"party_name"`
+				Candidate   string `json:"candidate"`
+				TotalVotes  int    `json:"total_votes"`
+				Elected     bool   `json:"elected"`
 			} `json:"results"`
 		} `json:"constituencies"`
 	}
 
 	for rows.Next() {
 		var election struct {
-			ID             int    `json:"id"`
-			Name           string `json:"name"`
-			Date           string `json:"date"`
+			ID             int                `json:"id"`
+			Name           string             `json:"name"`
+			Date           string             `json:"date"`
+			Status         string             `json:"status"`
+			PartySeats     []seats.PartySeats `json:"party_seats"`    // General and reserved seats won by each party
+			Questions      []questions.Result `json:"questions"`      // Outcome of each ballot question
+			ResultsSigned  bool               `json:"results_signed"` // Every constituency's latest tally is signed by its returning officer
 			Constituencies []struct {
-				ID      int    `json:"id"`
-				Name    string `json:"name"`
-				Results []struct {
-					PartyID    int    `json:"party_id"`
-					PartyName  string `json:"party_name"`
-					Candidate  string `json:"candidate"`
-					TotalVotes int    `json:"total_votes"`
+				ID               int                  `json:"id"`
+				Name             string               `json:"name"`
+				Tier             string               `json:"tier"`
+				Seats            int                  `json:"seats"`
+				NOTAVotes        int                  `json:"nota_votes"`
+				Turnout          int                  `json:"turnout"` // Counted ballots, NOTA included
+				RegisteredVoters int                  `json:"registered_voters"`
+				CountReport      json.RawMessage      `json:"count_report,omitempty"` // Round-by-round report or STV count sheet of ranked counts
+				Certification    certification.Status `json:"certification"`          // Returning officer signature over the latest tally
+				Results          []struct {
+					CandidateID int    `json:"candidate_id"`
+					PartyID     *int   `json:"party_id"` // Null for independents
+					PartyName   string `json:"party_name"`
+					Candidate   string `json:"candidate"`
+					TotalVotes  int    `json:"total_votes"`
+					Elected     bool   `json:"elected"`
 				} `json:"results"`
 			} `json:"constituencies"`
 		}
 
-		if err := rows.Scan(&election.ID, &election.Name, &election.Date); err != nil {
+		if err := rows.Scan(&election.ID, &election.Name, &election.Date, &election.Status); err != nil {
 			log.Println("Error scanning election row:", err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to parse past elections"})
 		}
 
+		certifications, err := certification.Statuses(utils.DB, election.ID)
+		if err != nil {
+			log.Println("Error fetching certifications:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch results"})
+		}
+		signed := make(map[int]certification.Status)
+		election.ResultsSigned = true
+		for _, status := range certifications {
+			signed[status.ConstituencyID] = status
+			election.ResultsSigned = election.ResultsSigned && status.Signed
+		}
+
 		// Fetch constituencies for the election
 		constituencyQuery := `
-            SELECT c.id, c.name
+            SELECT c.id, c.name, c.tier, c.seats
             FROM constituencies c
             JOIN election_constituencies ec ON c.id = ec.constituency_id
             WHERE ec.election_id = $1
+            ORDER BY c.tier, c.id
         `
 		constituencyRows, err := utils.DB.Query(constituencyQuery, election.ID)
 		if err != nil {
@@ -532,29 +982,63 @@ func GetPastElections(c *fiber.Ctx) error {
 
 		for constituencyRows.Next() {
 			var constituency struct {
-				ID      int    `json:"id"`
-				Name    string `json:"name"`
-				Results []struct {
-					PartyID    int    `json:"party_id"`
-					PartyName  string `json:"party_name"`
-					Candidate  string `json:"candidate"`
-					TotalVotes int    `json:"total_votes"`
+				ID               int                  `json:"id"`
+				Name             string               `json:"name"`
+				Tier             string               `json:"tier"`
+				Seats            int                  `json:"seats"`
+				NOTAVotes        int                  `json:"nota_votes"`
+				Turnout          int                  `json:"turnout"` // Counted ballots, NOTA included
+				RegisteredVoters int                  `json:"registered_voters"`
+				CountReport      json.RawMessage      `json:"count_report,omitempty"` // Round-by-round report or STV count sheet of ranked counts
+				Certification    certification.Status `json:"certification"`          // Returning officer signature over the latest tally
+				Results          []struct {
+					CandidateID int    `json:"candidate_id"`
+					PartyID     *int   `json:"party_id"` // Null for independents
+					PartyName   string `json:"party_name"`
+					Candidate   string `json:"candidate"`
+					TotalVotes  int    `json:"total_votes"`
+					Elected     bool   `json:"elected"`
 				} `json:"results"`
 			}
-			if err := constituencyRows.Scan(&constituency.ID, &constituency.Name); err != nil {
+			if err := constituencyRows.Scan(&constituency.ID, &constituency.Name, &constituency.Tier, &constituency.Seats); err != nil {
 				log.Println("Error parsing constituency row:", err)
 				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to parse constituencies"})
 			}
 
-			// Fetch results for the constituency
+			// NOTA is reported beside the candidates but counts towards turnout only
+			turnoutQuery := `
+                SELECT
+                    COALESCE(SUM(er.total_votes) FILTER (WHERE er.choice = 'nota'), 0),
+                    COALESCE(SUM(er.total_votes), 0),
+                    (SELECT COUNT(*) FROM citizens ci
+                     JOIN constituency_districts cd ON cd.district_id = ci.district_id
+                     WHERE cd.constituency_id = $2)
+                FROM election_results er
+                WHERE er.election_id = $1 AND er.constituency_id = $2
+            `
+			if err := utils.DB.QueryRow(turnoutQuery, election.ID, constituency.ID).Scan(&constituency.NOTAVotes, &constituency.Turnout, &constituency.RegisteredVoters); err != nil {
+				log.Println("Error fetching turnout:", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch results"})
+			}
+
+			var report []byte
+			reportQuery := "SELECT report FROM tabulation_reports WHERE election_id = $1 AND constituency_id = $2"
+			if err := utils.DB.QueryRow(reportQuery, election.ID, constituency.ID).Scan(&report); err != nil && err != sql.ErrNoRows {
+				log.Println("Error fetching count report:", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch results"})
+			}
+			constituency.CountReport = report
+			constituency.Certification = signed[constituency.ID]
+
+			// Fetch candidate results for the constituency, winners first
 			resultsQuery := `
-                SELECT er.party_id, p.name AS party_name, ci.name AS candidate_name, er.total_votes
+                SELECT er.candidate_id, c.party_id, COALESCE(p.name, 'Independent') AS party_name, ci.name AS candidate_name, er.total_votes, er.elected
                 FROM election_results er
-                JOIN parties p ON er.party_id = p.id
-                JOIN candidates c ON c.party_id = er.party_id AND c.constituency_id = er.constituency_id
+                JOIN candidates c ON c.id = er.candidate_id
                 JOIN citizens ci ON c.citizen_id = ci.id
-                WHERE er.election_id = $1 AND er.constituency_id = $2
-                ORDER BY er.total_votes DESC
+                LEFT JOIN parties p ON c.party_id = p.id
+                WHERE er.election_id = $1 AND er.constituency_id = $2 AND er.choice = 'candidate'
+                ORDER BY er.elected DESC, er.total_votes DESC
             `
 			resultsRows, err := utils.DB.Query(resultsQuery, election.ID, constituency.ID)
 			if err != nil {
@@ -565,12 +1049,14 @@ func GetPastElections(c *fiber.Ctx) error {
 
 			for resultsRows.Next() {
 				var result struct {
-					PartyID    int    `json:"party_id"`
-					PartyName  string `json:"party_name"`
-					Candidate  string `json:"candidate"`
-					TotalVotes int    `json:"total_votes"`
+					CandidateID int    `json:"candidate_id"`
+					PartyID     *int   `json:"party_id"` // Null for independents
+					PartyName   string `json:"party_name"`
+					Candidate   string `json:"candidate"`
+					TotalVotes  int    `json:"total_votes"`
+					Elected     bool   `json:"elected"`
 				}
-				if err := resultsRows.Scan(&result.PartyID, &result.PartyName, &result.Candidate, &result.TotalVotes); err != nil {
+				if err := resultsRows.Scan(&result.CandidateID, &result.PartyID, &result.PartyName, &result.Candidate, &result.TotalVotes, &result.Elected); err != nil {
 					log.Println("Error parsing results row:", err)
 					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to parse results"})
 				}
@@ -580,6 +1066,15 @@ func GetPastElections(c *fiber.Ctx) error {
 			election.Constituencies = append(election.Constituencies, constituency)
 		}
 
+		if election.PartySeats, err = seats.Summary(utils.DB, election.ID); err != nil {
+			log.Println("Error fetching seat totals:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch results"})
+		}
+		if election.Questions, err = questions.Results(utils.DB, election.ID); err != nil {
+			log.Println("Error fetching question results:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch results"})
+		}
+
 		pastElections = append(pastElections, election)
 	}
 
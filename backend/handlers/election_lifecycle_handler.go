@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Haste007/E-Voting/Backend/approvals"
+	"github.com/Haste007/E-Voting/Backend/ballot"
+	"github.com/Haste007/E-Voting/Backend/certification"
+	"github.com/Haste007/E-Voting/Backend/lifecycle"
+	"github.com/Haste007/E-Voting/Backend/middleware"
+	"github.com/Haste007/E-Voting/Backend/tally"
+	"github.com/Haste007/E-Voting/Backend/trustees"
+	"github.com/Haste007/E-Voting/Backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// adminActor returns the username of the admin making the request, for audit records
+func adminActor(c *fiber.Ctx) string {
+	if claims, ok := c.Locals(middleware.AdminLocalsKey).(*utils.AdminClaims); ok {
+		return claims.Username
+	}
+	return "unknown"
+}
+
+// lifecycleErrorResponse maps lifecycle errors to HTTP responses
+func lifecycleErrorResponse(c *fiber.Ctx, err error, fallback string) error {
+	var stateErr *lifecycle.StateError
+	switch {
+	case errors.Is(err, lifecycle.ErrElectionNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Election not found"})
+	case errors.As(err, &stateErr):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": stateErr.Error(), "status": stateErr.Status})
+	case errors.Is(err, ballot.ErrNoElectionKey), errors.Is(err, trustees.ErrNotEnoughTrustees),
+		errors.Is(err, ballot.ErrAggregateMismatch), errors.Is(err, tally.ErrNotDecrypted), errors.Is(err, certification.ErrIncomplete):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	default:
+		log.Println(fallback+":", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+	}
+}
+
+// transitionElection moves the election in the :id param to a new state in its own transaction.
+// If after is not nil it runs in the same transaction once the transition is applied.
+func transitionElection(c *fiber.Ctx, after func(tx *sql.Tx, id string) error, to, message, fallback string) error {
+	id := c.Params("id")
+
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+	}
+	defer tx.Rollback()
+
+	if err := lifecycle.Transition(tx, id, to, adminActor(c)); err != nil {
+		return lifecycleErrorResponse(c, err, fallback)
+	}
+
+	if after != nil {
+		if err := after(tx, id); err != nil {
+			return lifecycleErrorResponse(c, err, fallback)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("Error committing transition:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+	}
+
+	return c.JSON(fiber.Map{"message": message, "status": to})
+}
+
+// ScheduleElection freezes a draft election's setup so it can be opened.
+// Encrypted elections get their key pair here, or start their trustee key ceremony.
+func ScheduleElection(c *fiber.Ctx) error {
+	return transitionElection(c, func(tx *sql.Tx, id string) error {
+		if err := trustees.RequireEnrollment(tx, id); err != nil {
+			return err
+		}
+		return ballot.EnsureElectionKey(tx, id)
+	}, lifecycle.StatusScheduled, "Election scheduled successfully", "Failed to schedule election")
+}
+
+// ProposePollingHours asks a second admin to approve a draft or scheduled
+// election's current opening and closing times. The scheduler only opens and
+// closes polls at approved hours; editing either time afterwards needs a new approval.
+func ProposePollingHours(c *fiber.Ctx) error {
+	electionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid election ID"})
+	}
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+		}
+	}
+
+	hours, err := currentHours(utils.DB, electionID)
+	if err != nil {
+		return lifecycleErrorResponse(c, err, "Failed to propose polling hours")
+	}
+	return propose(c, approvals.ActionHours, func(tx *sql.Tx, electionID int) error {
+		if _, err := lifecycle.Require(tx, electionID, lifecycle.OpEdit, "FOR SHARE"); err != nil {
+			return err
+		}
+		if hours.OpensAt == nil && hours.ClosesAt == nil {
+			return &ballot.ValidationError{Code: codeNoPollingHours, Message: "Election has no polling hours; polls are opened and closed by hand", Status: http.StatusConflict}
+		}
+		return hours.requireCurrent(tx, electionID)
+	}, hours, strings.TrimSpace(body.Reason))
+}
+
+// pollingHours is the timetable an hours request asks a second admin to approve
+type pollingHours struct {
+	OpensAt  *time.Time `json:"opensAt"`
+	ClosesAt *time.Time `json:"closesAt"`
+}
+
+// currentHours reads an election's opening and closing times
+func currentHours(q ballot.Querier, electionID int) (pollingHours, error) {
+	var opensAt, closesAt sql.NullTime
+	err := q.QueryRow("SELECT opens_at, closes_at FROM elections WHERE id = $1", electionID).Scan(&opensAt, &closesAt)
+	if err == sql.ErrNoRows {
+		return pollingHours{}, lifecycle.ErrElectionNotFound
+	} else if err != nil {
+		return pollingHours{}, err
+	}
+	var hours pollingHours
+	if opensAt.Valid {
+		hours.OpensAt = &opensAt.Time
+	}
+	if closesAt.Valid {
+		hours.ClosesAt = &closesAt.Time
+	}
+	return hours, nil
+}
+
+// requireCurrent returns a *ballot.ValidationError unless the election's times are still these
+func (h pollingHours) requireCurrent(q ballot.Querier, electionID int) error {
+	current, err := currentHours(q, electionID)
+	if err != nil {
+		return err
+	}
+	same := func(a, b *time.Time) bool {
+		return (a == nil && b == nil) || (a != nil && b != nil && a.Equal(*b))
+	}
+	if !same(h.OpensAt, current.OpensAt) || !same(h.ClosesAt, current.ClosesAt) {
+		return &ballot.ValidationError{Code: codePollingHoursChanged, Message: "Polling hours were edited after they were proposed; propose them again", Status: http.StatusConflict}
+	}
+	return nil
+}
+
+// approve records the election's times as approved, provided they are still the proposed ones
+func (h pollingHours) approve(tx *sql.Tx, electionID int) error {
+	if _, err := lifecycle.Require(tx, electionID, lifecycle.OpEdit, "FOR UPDATE"); err != nil {
+		return err
+	}
+	if err := h.requireCurrent(tx, electionID); err != nil {
+		return err
+	}
+	_, err := tx.Exec("UPDATE elections SET approved_opens_at = opens_at, approved_closes_at = closes_at WHERE id = $1", electionID)
+	return err
+}
+
+// UnscheduleElection returns a scheduled election to draft for further edits;
+// any trustee key ceremony is discarded and has to be run again
+func UnscheduleElection(c *fiber.Ctx) error {
+	return transitionElection(c, trustees.ResetCeremony, lifecycle.StatusDraft, "Election returned to draft", "Failed to unschedule election")
+}
+
+// CertifyElection proposes marking a tallied election's results as final,
+// which happens once a second admin approves. Every constituency's result must
+// already be signed by its returning officer.
+func CertifyElection(c *fiber.Ctx) error {
+	return proposeAction(c, approvals.ActionCertify, func(tx *sql.Tx, electionID int) error {
+		if err := requireTransition(tx, electionID, lifecycle.StatusCertified); err != nil {
+			return err
+		}
+		return certification.RequireComplete(tx, electionID)
+	})
+}
+
+// requireTransition returns a *lifecycle.StateError unless the election may
+// move to state to now. The row is held FOR SHARE until tx ends.
+func requireTransition(tx *sql.Tx, electionID int, to string) error {
+	from, err := lifecycle.CurrentStatus(tx, electionID, "FOR SHARE")
+	if err != nil {
+		return err
+	}
+	if !lifecycle.CanTransition(from, to) {
+		return &lifecycle.StateError{Status: from, Action: "move to " + to}
+	}
+	return nil
+}
+
+// GetElectionHistory lists every state change of an election, oldest first
+func GetElectionHistory(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	if _, err := lifecycle.CurrentStatus(utils.DB, id, ""); err != nil {
+		return lifecycleErrorResponse(c, err, "Failed to fetch election history")
+	}
+
+	query := `
+        SELECT COALESCE(from_status, ''), to_status, actor, changed_at
+        FROM election_status_history
+        WHERE election_id = $1
+        ORDER BY changed_at, id
+    `
+	rows, err := utils.DB.Query(query, id)
+	if err != nil {
+		log.Println("Error fetching election history:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch election history"})
+	}
+	defer rows.Close()
+
+	var history []struct {
+		FromStatus string `json:"from_status"`
+		ToStatus   string `json:"to_status"`
+		Actor      string `json:"actor"`
+		ChangedAt  string `json:"changed_at"`
+	}
+
+	for rows.Next() {
+		var change struct {
+			FromStatus string `json:"from_status"`
+			ToStatus   string `json:"to_status"`
+			Actor      string `json:"actor"`
+			ChangedAt  string `json:"changed_at"`
+		}
+		if err := rows.Scan(&change.FromStatus, &change.ToStatus, &change.Actor, &change.ChangedAt); err != nil {
+			log.Println("Error parsing election history row:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to parse election history"})
+		}
+		history = append(history, change)
+	}
+
+	return c.JSON(history)
+}
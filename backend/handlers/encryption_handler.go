@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+
+	"github.com/Haste007/E-Voting/Backend/ballot"
+	"github.com/Haste007/E-Voting/Backend/elgamal"
+	"github.com/Haste007/E-Voting/Backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetElectionEncryption returns what a client needs to encrypt a ballot itself:
+// the group parameters, the election public key and the proof context format
+func GetElectionEncryption(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	encrypted, err := ballot.IsEncrypted(utils.DB, id)
+	if err == sql.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Election not found"})
+	} else if err != nil {
+		log.Println("Error reading election mode:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch election encryption"})
+	}
+	if !encrypted {
+		return c.JSON(fiber.Map{"encrypted": false})
+	}
+
+	publicKey, err := ballot.ElectionPublicKey(utils.DB, id)
+	if err == sql.ErrNoRows {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Election key has not been generated yet"})
+	} else if err != nil {
+		log.Println("Error fetching election public key:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch election encryption"})
+	}
+
+	return c.JSON(fiber.Map{
+		"encrypted":  true,
+		"group":      elgamal.DefaultGroup.Params(),
+		"public_key": publicKey,
+		"context":    "election:<electionId>/constituency:<constituencyId>",
+		"ordering":   "one choice per candidate, ordered by candidate_id, then one for None of the Above if the election offers it",
+	})
+}
+
+type encryptedTally struct {
+	ConstituencyID int                      `json:"constituency_id"`
+	OptionIndex    int                      `json:"option_index"`
+	Choice         string                   `json:"choice"`
+	CandidateID    *int64                   `json:"candidate_id"` // Null for NOTA
+	Aggregate      *elgamal.Ciphertext      `json:"aggregate"`
+	BallotCount    int                      `json:"ballot_count"`
+	Decryption     []ballot.TallyDecryption `json:"decryption"`  // Null until decrypted
+	TotalVotes     *int64                   `json:"total_votes"` // Null until decrypted
+}
+
+// GetEncryptedTallies returns each aggregate with the decryption shares behind its
+// count, so anyone can check the published counts against the encrypted ballots
+func GetEncryptedTallies(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	query := `
+        SELECT constituency_id, option_index, choice, candidate_id, aggregate, ballot_count, decryption, total_votes
+        FROM encrypted_tallies
+        WHERE election_id = $1
+        ORDER BY constituency_id, option_index
+    `
+	rows, err := utils.DB.Query(query, id)
+	if err != nil {
+		log.Println("Error fetching encrypted tallies:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch encrypted tallies"})
+	}
+	defer rows.Close()
+
+	var tallies []encryptedTally
+	for rows.Next() {
+		var tally encryptedTally
+		var aggregate, decryption []byte
+		var candidateID, totalVotes sql.NullInt64
+		if err := rows.Scan(&tally.ConstituencyID, &tally.OptionIndex, &tally.Choice, &candidateID, &aggregate, &tally.BallotCount, &decryption, &totalVotes); err != nil {
+			log.Println("Error parsing encrypted tally row:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to parse encrypted tallies"})
+		}
+		if err := json.Unmarshal(aggregate, &tally.Aggregate); err != nil {
+			log.Println("Error decoding tally aggregate:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to parse encrypted tallies"})
+		}
+		if decryption != nil {
+			if err := json.Unmarshal(decryption, &tally.Decryption); err != nil {
+				log.Println("Error decoding tally decryption:", err)
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to parse encrypted tallies"})
+			}
+		}
+		if candidateID.Valid {
+			tally.CandidateID = &candidateID.Int64
+		}
+		if totalVotes.Valid {
+			tally.TotalVotes = &totalVotes.Int64
+		}
+		tallies = append(tallies, tally)
+	}
+
+	return c.JSON(tallies)
+}
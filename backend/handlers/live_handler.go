@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Haste007/E-Voting/Backend/lifecycle"
+	"github.com/Haste007/E-Voting/Backend/live"
+	"github.com/Haste007/E-Voting/Backend/middleware"
+	"github.com/Haste007/E-Voting/Backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// liveHeartbeat keeps idle streams from being cut off by proxies
+const liveHeartbeat = 15 * time.Second
+
+// AdminWhilePollsOpen runs admin in front of the next handler while an
+// election's polls are open or paused, as turnout by district could then be
+// matched to voters walking in. Otherwise anyone is let through.
+func AdminWhilePollsOpen(admin fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		electionID, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid election ID"})
+		}
+		status, err := lifecycle.CurrentStatus(utils.DB, electionID, "")
+		if err != nil {
+			return lifecycleErrorResponse(c, err, "Failed to stream election")
+		}
+		if status == lifecycle.StatusOpen || status == lifecycle.StatusPaused {
+			return admin(c)
+		}
+		return c.Next()
+	}
+}
+
+// StreamElection streams an election's turnout, and its results once tallied,
+// as server-sent events. Each event carries the full figures, so a client that
+// reconnects only needs the next one. Streams opened without an admin token,
+// which AdminWhilePollsOpen allows, end when polls open.
+func StreamElection(c *fiber.Ctx) error {
+	electionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid election ID"})
+	}
+	_, admin := c.Locals(middleware.AdminLocalsKey).(*utils.AdminClaims)
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // Stop nginx holding events back
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		subscription := live.Subscribe(electionID, !admin)
+		defer subscription.Close()
+		heartbeat := time.NewTicker(liveHeartbeat)
+		defer heartbeat.Stop()
+
+		// Writes fail once the client has gone
+		fmt.Fprint(w, ": connected\n\n")
+		for w.Flush() == nil {
+			select {
+			case <-subscription.Ready():
+				for _, event := range subscription.Take() {
+					fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Name, event.Data)
+				}
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			case <-subscription.Done():
+				fmt.Fprint(w, ": polls are open; admins only\n\n")
+				w.Flush()
+				return
+			}
+		}
+	})
+	return nil
+}
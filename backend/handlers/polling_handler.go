@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/Haste007/E-Voting/Backend/ballot"
+	"github.com/Haste007/E-Voting/Backend/polling"
+	"github.com/Haste007/E-Voting/Backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// pollingRequest is the body of a pause, resume or extension. Without
+// constituencies or districts the action applies nationwide.
+type pollingRequest struct {
+	polling.Area
+	Reason   string `json:"reason"`   // Required, e.g. "Kiosk outage at polling station 12"
+	ClosesAt string `json:"closesAt"` // New closing time of an extension, in the same formats as CreateElection
+}
+
+// adjustPolling parses a polling request for the election in the :id param and
+// runs apply on it in a transaction
+func adjustPolling(c *fiber.Ctx, apply func(tx *sql.Tx, electionID int, request pollingRequest) error, message, fallback string) error {
+	electionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid election ID"})
+	}
+
+	var request pollingRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	request.Reason = strings.TrimSpace(request.Reason)
+	if request.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A reason is required"})
+	}
+
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+	}
+	defer tx.Rollback()
+
+	if err := apply(tx, electionID, request); err != nil {
+		return actionErrorResponse(c, err, fallback)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("Error committing polling action:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+	}
+	return c.JSON(fiber.Map{"message": message})
+}
+
+// actionErrorResponse maps refused polling actions and result corrections to
+// their status and error code, and lifecycle errors as lifecycleErrorResponse does
+func actionErrorResponse(c *fiber.Ctx, err error, fallback string) error {
+	var validationErr *ballot.ValidationError
+	if errors.As(err, &validationErr) {
+		return ballotErrorResponse(c, err)
+	}
+	return lifecycleErrorResponse(c, err, fallback)
+}
+
+// PauseElection stops voting nationwide, which pauses the election, or in the
+// given constituencies and districts
+func PauseElection(c *fiber.Ctx) error {
+	return adjustPolling(c, func(tx *sql.Tx, electionID int, request pollingRequest) error {
+		return polling.Pause(tx, electionID, request.Area, request.Reason, adminActor(c))
+	}, "Voting paused successfully", "Failed to pause voting")
+}
+
+// ResumeElection lifts a nationwide pause or the pauses of the given
+// constituencies and districts
+func ResumeElection(c *fiber.Ctx) error {
+	return adjustPolling(c, func(tx *sql.Tx, electionID int, request pollingRequest) error {
+		return polling.Resume(tx, electionID, request.Area, request.Reason, adminActor(c))
+	}, "Voting resumed successfully", "Failed to resume voting")
+}
+
+// ExtendPolling moves the closing time of the given constituencies, or of the
+// whole election, later
+func ExtendPolling(c *fiber.Ctx) error {
+	return adjustPolling(c, func(tx *sql.Tx, electionID int, request pollingRequest) error {
+		return polling.Extend(tx, electionID, request.Area, request.ClosesAt, request.Reason, adminActor(c))
+	}, "Polling hours extended successfully", "Failed to extend polling hours")
+}
+
+// GetPollingActions lists every pause, resumption and extension of an
+// election with its reason and the admin who made it
+func GetPollingActions(c *fiber.Ctx) error {
+	electionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid election ID"})
+	}
+
+	actions, err := polling.Actions(utils.DB, electionID)
+	if err != nil {
+		log.Println("Error fetching polling actions:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch polling actions"})
+	}
+	return c.JSON(actions)
+}
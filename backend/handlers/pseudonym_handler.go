@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/Haste007/E-Voting/Backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetPseudonymKeys lists the voter pseudonym key versions and how many elections use each
+func GetPseudonymKeys(c *fiber.Ctx) error {
+	query := `
+        SELECT k.version, k.fingerprint, k.created_at, COUNT(e.id) AS elections
+        FROM pseudonym_keys k
+        LEFT JOIN elections e ON e.pseudonym_key_version = k.version
+        GROUP BY k.version, k.fingerprint, k.created_at
+        ORDER BY k.version
+    `
+	rows, err := utils.DB.Query(query)
+	if err != nil {
+		log.Println("Error fetching pseudonym keys:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch pseudonym keys"})
+	}
+	defer rows.Close()
+
+	var keys []struct {
+		Version     int    `json:"version"`
+		Fingerprint string `json:"fingerprint"`
+		CreatedAt   string `json:"created_at"`
+		Elections   int    `json:"elections"`
+		Current     bool   `json:"current"`
+	}
+
+	for rows.Next() {
+		var key struct {
+			Version     int    `json:"version"`
+			Fingerprint string `json:"fingerprint"`
+			CreatedAt   string `json:"created_at"`
+			Elections   int    `json:"elections"`
+			Current     bool   `json:"current"`
+		}
+		if err := rows.Scan(&key.Version, &key.Fingerprint, &key.CreatedAt, &key.Elections); err != nil {
+			log.Println("Error parsing pseudonym key row:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to parse pseudonym keys"})
+		}
+		key.Current = key.Version == utils.CurrentVoterKeyVersion()
+		keys = append(keys, key)
+	}
+
+	return c.JSON(keys)
+}
+
+// VerifyVoterParticipation lets an auditor check whether a given NID took part in an
+// election, and in which tiers' contests. The pseudonym is recomputed with the key version recorded on the election,
+// so past elections stay checkable after key rotation and no NID is ever stored.
+func VerifyVoterParticipation(c *fiber.Ctx) error {
+	electionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid election ID"})
+	}
+
+	var request struct {
+		NID string `json:"nid"`
+	}
+	if err := c.BodyParser(&request); err != nil || request.NID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	var keyVersion int
+	if err := utils.DB.QueryRow("SELECT pseudonym_key_version FROM elections WHERE id = $1", electionID).Scan(&keyVersion); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Election not found"})
+	}
+
+	pseudonym, err := utils.HashVoterID(electionID, keyVersion, request.NID)
+	if err != nil {
+		log.Println("Error deriving voter pseudonym:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Pseudonym key for this election is not loaded"})
+	}
+
+	// Participation is recorded per contest; report the tiers the voter voted in
+	query := `
+        SELECT DISTINCT c.tier
+        FROM voter_participation vp
+        JOIN constituencies c ON c.id = vp.constituency_id
+        WHERE vp.election_id = $1 AND vp.voter_hash = $2
+        ORDER BY c.tier
+    `
+	rows, err := utils.DB.Query(query, electionID, pseudonym)
+	if err != nil {
+		log.Println("Error checking voter participation:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to check participation"})
+	}
+	defer rows.Close()
+
+	tiers := []string{}
+	for rows.Next() {
+		var tier string
+		if err := rows.Scan(&tier); err != nil {
+			log.Println("Error parsing voter participation:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to check participation"})
+		}
+		tiers = append(tiers, tier)
+	}
+
+	return c.JSON(fiber.Map{"participated": len(tiers) > 0, "tiers": tiers, "key_version": keyVersion})
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/Haste007/E-Voting/Backend/lifecycle"
+	"github.com/Haste007/E-Voting/Backend/middleware"
+	"github.com/Haste007/E-Voting/Backend/polling"
+	"github.com/Haste007/E-Voting/Backend/questions"
+	"github.com/Haste007/E-Voting/Backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// questionRequest is a ballot question as submitted by an election officer
+type questionRequest struct {
+	Kind          string           `json:"kind"`          // "yes_no" (default) or "multiple_choice"
+	Text          questions.Text   `json:"text"`          // By language code, e.g. {"en": "...", "ur": "..."}
+	Options       []questions.Text `json:"options"`       // Optional for yes/no, which defaults to Yes and No
+	Districts     []string         `json:"districts"`     // District names; empty asks the question nationwide
+	PassRule      string           `json:"passRule"`      // "majority" (default) or "supermajority"
+	Supermajority float64          `json:"supermajority"` // Percent of answers, for supermajority questions
+	MinTurnout    float64          `json:"minTurnout"`    // Percent of eligible voters that must answer
+}
+
+// Default option labels of yes/no questions
+var (
+	yesLabel = questions.Text{"en": "Yes", "ur": "ہاں"}
+	noLabel  = questions.Text{"en": "No", "ur": "نہیں"}
+)
+
+// check fills in a question's defaults and returns why it is invalid, if it is
+func (q *questionRequest) check() string {
+	if q.Kind == "" {
+		q.Kind = questions.KindYesNo
+	}
+	if q.PassRule == "" {
+		q.PassRule = questions.RuleMajority
+	}
+	if q.Kind == questions.KindYesNo && len(q.Options) == 0 {
+		q.Options = []questions.Text{yesLabel, noLabel}
+	}
+
+	switch {
+	case q.Kind != questions.KindYesNo && q.Kind != questions.KindMultipleChoice:
+		return "Question kind must be \"yes_no\" or \"multiple_choice\""
+	case !hasText(q.Text):
+		return "A question needs its text in at least one language"
+	case q.Kind == questions.KindYesNo && len(q.Options) != 2:
+		return "A yes/no question has exactly two options, Yes then No"
+	case len(q.Options) < 2:
+		return "A question needs at least two options"
+	case q.PassRule != questions.RuleMajority && q.PassRule != questions.RuleSupermajority:
+		return "Pass rule must be \"majority\" or \"supermajority\""
+	case q.PassRule == questions.RuleSupermajority && (q.Supermajority <= 50 || q.Supermajority > 100):
+		return "A supermajority must be over 50 and at most 100 percent"
+	case q.PassRule == questions.RuleMajority && q.Supermajority != 0:
+		return "A supermajority percentage needs the supermajority pass rule"
+	case q.MinTurnout < 0 || q.MinTurnout > 100:
+		return "Minimum turnout must be between 0 and 100 percent"
+	}
+	for _, option := range q.Options {
+		if !hasText(option) {
+			return "Every option needs a label in at least one language"
+		}
+	}
+	return ""
+}
+
+// hasText reports whether a multilingual text has at least one non-empty variant
+func hasText(text questions.Text) bool {
+	for _, variant := range text {
+		if variant != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// saveQuestion stores a checked question with its options and districts
+func saveQuestion(tx *sql.Tx, electionID int, q questionRequest) (int, error) {
+	text, err := json.Marshal(q.Text)
+	if err != nil {
+		return 0, err
+	}
+	var supermajority interface{}
+	if q.PassRule == questions.RuleSupermajority {
+		supermajority = q.Supermajority
+	}
+
+	var questionID int
+	query := `
+        INSERT INTO ballot_questions (election_id, kind, text, nationwide, pass_rule, supermajority, min_turnout)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id
+    `
+	if err := tx.QueryRow(query, electionID, q.Kind, string(text), len(q.Districts) == 0, q.PassRule, supermajority, q.MinTurnout).Scan(&questionID); err != nil {
+		return 0, err
+	}
+
+	for i, option := range q.Options {
+		label, err := json.Marshal(option)
+		if err != nil {
+			return 0, err
+		}
+		optionQuery := "INSERT INTO question_options (question_id, position, label) VALUES ($1, $2, $3)"
+		if _, err := tx.Exec(optionQuery, questionID, i+1, string(label)); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, districtName := range q.Districts {
+		var districtID int
+		if err := tx.QueryRow("SELECT id FROM districts WHERE name = $1", districtName).Scan(&districtID); err == sql.ErrNoRows {
+			return 0, fmt.Errorf("%w: %s", errUnknownDistrict, districtName)
+		} else if err != nil {
+			return 0, err
+		}
+		linkQuery := "INSERT INTO question_districts (question_id, district_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+		if _, err := tx.Exec(linkQuery, questionID, districtID); err != nil {
+			return 0, err
+		}
+	}
+	return questionID, nil
+}
+
+// errUnknownDistrict is returned by saveQuestion for a district name that does not exist
+var errUnknownDistrict = errors.New("invalid district name")
+
+// CreateBallotQuestion adds a referendum or other ballot question to an election
+func CreateBallotQuestion(c *fiber.Ctx) error {
+	electionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid election ID"})
+	}
+
+	var request questionRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	if problem := request.check(); problem != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": problem})
+	}
+
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save question"})
+	}
+	defer tx.Rollback()
+
+	// Questions are part of the election's set up and freeze with it
+	if _, err := lifecycle.Require(tx, electionID, lifecycle.OpEdit, "FOR SHARE"); err != nil {
+		return lifecycleErrorResponse(c, err, "Failed to save question")
+	}
+
+	questionID, err := saveQuestion(tx, electionID, request)
+	if errors.Is(err, errUnknownDistrict) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	} else if err != nil {
+		log.Println("Error saving question:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save question"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("Error committing question:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save question"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"id": questionID})
+}
+
+// GetBallotQuestions lists an election's questions with their options
+func GetBallotQuestions(c *fiber.Ctx) error {
+	list, err := questions.ForElection(utils.DB, c.Params("id"))
+	if err != nil {
+		log.Println("Error fetching ballot questions:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch ballot questions"})
+	}
+	return c.JSON(list)
+}
+
+// AnswerQuestion records the answer of the voter identified in the session
+// token to one ballot question. Each voter answers each question once, even in
+// elections that allow re-voting, and answers are published in the clear on
+// the bulletin board, including in encrypted elections.
+func AnswerQuestion(c *fiber.Ctx) error {
+	voter, ok := c.Locals(middleware.VoterLocalsKey).(*utils.VoterClaims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing voter token"})
+	}
+
+	var request struct {
+		ElectionID int `json:"electionId"`
+		QuestionID int `json:"questionId"`
+		OptionID   int `json:"optionId"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		log.Println("Error starting answer transaction:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record answer"})
+	}
+	defer tx.Rollback()
+
+	if err := questions.ValidateAnswer(tx, request.ElectionID, request.QuestionID, request.OptionID, voter.DistrictID); err != nil {
+		return ballotErrorResponse(c, err)
+	}
+	if err := polling.CheckDistrict(tx, request.ElectionID, voter.DistrictID); err != nil {
+		return ballotErrorResponse(c, err)
+	}
+
+	hashedVoterID, err := utils.VoterPseudonym(request.ElectionID, voter.NID)
+	if err != nil {
+		log.Println("Error deriving voter pseudonym:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record answer"})
+	}
+
+	// As with ballots, the primary key makes a second answer fail atomically
+	participationQuery := `
+        INSERT INTO question_participation (question_id, voter_hash)
+        VALUES ($1, $2)
+        ON CONFLICT (question_id, voter_hash) DO NOTHING
+    `
+	result, err := tx.Exec(participationQuery, request.QuestionID, hashedVoterID)
+	if err != nil {
+		log.Println("Error recording question participation:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record answer"})
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Println("Error checking rows affected:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record answer"})
+	}
+	if rowsAffected == 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Voter has already answered this question"})
+	}
+
+	// Answers are anonymous in the same way as plaintext ballots: random ID, hour
+	// only, and published on the bulletin board in a shuffled batch at close
+	answerQuery := `
+        INSERT INTO question_answers (question_id, option_id, cast_hour)
+        VALUES ($1, $2, date_trunc('hour', NOW()))
+    `
+	if _, err := tx.Exec(answerQuery, request.QuestionID, request.OptionID); err != nil {
+		log.Println("Error inserting answer:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record answer"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("Error committing answer:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to record answer"})
+	}
+	return c.JSON(fiber.Map{"message": "Answer recorded"})
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"strconv"
+
+	"github.com/Haste007/E-Voting/Backend/ballot"
+	"github.com/Haste007/E-Voting/Backend/lifecycle"
+	"github.com/Haste007/E-Voting/Backend/seats"
+	"github.com/Haste007/E-Voting/Backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// CreateReservedSeatPool adds a pool of reserved seats to an election. The
+// pool is shared among parties in proportion to their general seats when the
+// election is tallied.
+func CreateReservedSeatPool(c *fiber.Ctx) error {
+	electionID := c.Params("id")
+	var request struct {
+		Name            string  `json:"name"`
+		Tier            string  `json:"tier"` // "na" (default) or "pa"; the pool follows that tier's general seats
+		Seats           int     `json:"seats"`
+		Method          string  `json:"method"`          // "dhondt" (default) or "sainte_lague"
+		MinGeneralSeats *int    `json:"minGeneralSeats"` // Defaults to 1
+		MinVoteShare    float64 `json:"minVoteShare"`    // Percent of candidate votes, up to two decimals
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	if request.Method == "" {
+		request.Method = seats.MethodDHondt
+	}
+	if request.Tier == "" {
+		request.Tier = ballot.TierNA
+	}
+	minGeneralSeats := 1
+	if request.MinGeneralSeats != nil {
+		minGeneralSeats = *request.MinGeneralSeats
+	}
+	switch {
+	case request.Name == "" || request.Seats < 1:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A pool needs a name and at least one seat"})
+	case !ballot.IsTier(request.Tier):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tier must be \"na\" or \"pa\""})
+	case request.Method != seats.MethodDHondt && request.Method != seats.MethodSainteLague:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Method must be \"dhondt\" or \"sainte_lague\""})
+	case minGeneralSeats < 0 || request.MinVoteShare < 0 || request.MinVoteShare > 100:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid thresholds"})
+	}
+
+	// Pools are part of the election's set up and freeze with it
+	if _, err := lifecycle.Require(utils.DB, electionID, lifecycle.OpEdit, ""); err != nil {
+		return lifecycleErrorResponse(c, err, "Failed to save reserved seat pool")
+	}
+
+	var exists bool
+	if err := utils.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM reserved_seat_pools WHERE election_id = $1 AND tier = $2 AND name = $3)", electionID, request.Tier, request.Name).Scan(&exists); err != nil {
+		log.Println("Error checking reserved seat pools:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save reserved seat pool"})
+	}
+	if exists {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "The election already has a pool with this name in the tier"})
+	}
+
+	var poolID int
+	query := `
+        INSERT INTO reserved_seat_pools (election_id, name, tier, seats, method, min_general_seats, min_vote_share)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id
+    `
+	if err := utils.DB.QueryRow(query, electionID, request.Name, request.Tier, request.Seats, request.Method, minGeneralSeats, request.MinVoteShare).Scan(&poolID); err != nil {
+		log.Println("Error saving reserved seat pool:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save reserved seat pool"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"id": poolID})
+}
+
+// GetReservedSeatPools lists an election's reserved seat pools with the
+// priority list each party has submitted
+func GetReservedSeatPools(c *fiber.Ctx) error {
+	pools, err := seats.Pools(utils.DB, c.Params("id"))
+	if err != nil {
+		log.Println("Error fetching reserved seat pools:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch reserved seat pools"})
+	}
+
+	type poolLists struct {
+		seats.Pool
+		Lists map[int][]seats.Member `json:"lists"` // By party ID
+	}
+	response := make([]poolLists, 0, len(pools))
+	for _, pool := range pools {
+		lists, err := seats.Lists(utils.DB, pool.ID)
+		if err != nil {
+			log.Println("Error fetching party lists:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch reserved seat pools"})
+		}
+		response = append(response, poolLists{Pool: pool, Lists: lists})
+	}
+	return c.JSON(response)
+}
+
+// SubmitPartyList replaces a party's priority list for a reserved seat pool.
+// Members are seated in list order, so the first citizen takes the party's
+// first reserved seat.
+func SubmitPartyList(c *fiber.Ctx) error {
+	electionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid election ID"})
+	}
+	poolID, err := strconv.Atoi(c.Params("poolId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid pool ID"})
+	}
+	partyID, err := strconv.Atoi(c.Params("partyId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid party ID"})
+	}
+
+	var request struct {
+		CitizenIDs []int `json:"citizenIds"` // In priority order
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save party list"})
+	}
+	defer tx.Rollback()
+
+	if _, err := lifecycle.Require(tx, electionID, lifecycle.OpEdit, "FOR SHARE"); err != nil {
+		return lifecycleErrorResponse(c, err, "Failed to save party list")
+	}
+
+	var poolElection int
+	err = tx.QueryRow("SELECT election_id FROM reserved_seat_pools WHERE id = $1", poolID).Scan(&poolElection)
+	if err == sql.ErrNoRows || (err == nil && poolElection != electionID) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Reserved seat pool not found"})
+	} else if err != nil {
+		log.Println("Error fetching reserved seat pool:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save party list"})
+	}
+
+	// Only the party's own members may be on its list, each once
+	listed := make(map[int]bool, len(request.CitizenIDs))
+	for _, citizenID := range request.CitizenIDs {
+		if listed[citizenID] {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A citizen appears twice on the list"})
+		}
+		listed[citizenID] = true
+
+		var member bool
+		memberQuery := "SELECT EXISTS (SELECT 1 FROM party_members WHERE party_id = $1 AND citizen_id = $2)"
+		if err := tx.QueryRow(memberQuery, partyID, citizenID).Scan(&member); err != nil {
+			log.Println("Error checking party membership:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save party list"})
+		}
+		if !member {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Citizen " + strconv.Itoa(citizenID) + " is not a member of the party"})
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM party_lists WHERE pool_id = $1 AND party_id = $2", poolID, partyID); err != nil {
+		log.Println("Error clearing party list:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save party list"})
+	}
+	insertQuery := `
+        INSERT INTO party_lists (pool_id, party_id, position, citizen_id)
+        VALUES ($1, $2, $3, $4)
+    `
+	for i, citizenID := range request.CitizenIDs {
+		if _, err := tx.Exec(insertQuery, poolID, partyID, i+1, citizenID); err != nil {
+			log.Println("Error saving party list:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save party list"})
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("Error committing party list:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save party list"})
+	}
+	return c.JSON(fiber.Map{"message": "Party list saved", "members": len(request.CitizenIDs)})
+}
+
+// GetSeatAllocations returns each party's general and reserved seats and the
+// allocation record of every reserved seat pool; it is empty until the
+// election is tallied
+func GetSeatAllocations(c *fiber.Ctx) error {
+	electionID := c.Params("id")
+	parties, err := seats.Summary(utils.DB, electionID)
+	if err != nil {
+		log.Println("Error fetching seat totals:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch seat allocations"})
+	}
+	allocations, err := seats.Allocations(utils.DB, electionID)
+	if err != nil {
+		log.Println("Error fetching seat allocations:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch seat allocations"})
+	}
+	return c.JSON(fiber.Map{"parties": parties, "allocations": allocations})
+}
@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/Haste007/E-Voting/Backend/tabulation"
+	"github.com/Haste007/E-Voting/Backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetCountReports returns the round-by-round count report of every constituency
+// of a ranked election; it is empty until the election is tallied
+func GetCountReports(c *fiber.Ctx) error {
+	reports, err := tabulation.Reports(utils.DB, c.Params("id"))
+	if err != nil {
+		log.Println("Error fetching count reports:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch count reports"})
+	}
+	return c.JSON(reports)
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/Haste007/E-Voting/Backend/lifecycle"
+	"github.com/Haste007/E-Voting/Backend/tally"
+	"github.com/Haste007/E-Voting/Backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// runTally parses the election ID and required reason of a tally request and
+// runs count on them in a transaction, returning what it produced
+func runTally(c *fiber.Ctx, count func(tx *sql.Tx, electionID int, reason string) (interface{}, error), fallback string) error {
+	electionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid election ID"})
+	}
+
+	var request struct {
+		Reason string `json:"reason"` // Required, e.g. "Recount requested by candidate 12"
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	request.Reason = strings.TrimSpace(request.Reason)
+	if request.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A reason is required"})
+	}
+
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		log.Println("Error starting transaction:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+	}
+	defer tx.Rollback()
+
+	result, err := count(tx, electionID, request.Reason)
+	if err != nil {
+		return actionErrorResponse(c, err, fallback)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("Error committing tally:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+	}
+	return c.JSON(result)
+}
+
+// RerunTally counts every constituency of a tallied election again. The
+// results are replaced, not added to, and the run is kept as a new snapshot.
+func RerunTally(c *fiber.Ctx) error {
+	return runTally(c, func(tx *sql.Tx, electionID int, reason string) (interface{}, error) {
+		if _, err := lifecycle.Require(tx, electionID, lifecycle.OpRecount, "FOR UPDATE"); err != nil {
+			return nil, err
+		}
+		return tally.Run(tx, electionID, reason, adminActor(c))
+	}, "Failed to re-run tally")
+}
+
+// RecountConstituency runs an official recount of one constituency and
+// returns the new snapshot with its differences from the previous one
+func RecountConstituency(c *fiber.Ctx) error {
+	constituencyID, err := strconv.Atoi(c.Params("constituencyId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid constituency ID"})
+	}
+	return runTally(c, func(tx *sql.Tx, electionID int, reason string) (interface{}, error) {
+		snapshot, diff, err := tally.Recount(tx, electionID, constituencyID, reason, adminActor(c))
+		if err != nil {
+			return nil, err
+		}
+		return fiber.Map{"snapshot": snapshot, "diff": diff}, nil
+	}, "Failed to recount constituency")
+}
+
+// GetTallyRuns lists an election's tally runs, recounts and corrections, newest first
+func GetTallyRuns(c *fiber.Ctx) error {
+	electionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid election ID"})
+	}
+
+	snapshots, err := tally.List(utils.DB, electionID)
+	if err != nil {
+		log.Println("Error fetching tally runs:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch tally runs"})
+	}
+	return c.JSON(snapshots)
+}
+
+// GetTallyRun returns one snapshot with the result lines it recorded
+func GetTallyRun(c *fiber.Ctx) error {
+	electionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid election ID"})
+	}
+	version, err := strconv.Atoi(c.Params("version"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid version"})
+	}
+
+	snapshot, err := tally.Get(utils.DB, electionID, version)
+	if errors.Is(err, tally.ErrNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Tally run not found"})
+	} else if err != nil {
+		log.Println("Error fetching tally run:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch tally run"})
+	}
+	return c.JSON(snapshot)
+}
@@ -1,22 +1,38 @@
 package handlers
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"log"
-	"time"
 
+	"github.com/Haste007/E-Voting/Backend/ballot"
+	"github.com/Haste007/E-Voting/Backend/bulletin"
+	"github.com/Haste007/E-Voting/Backend/elgamal"
+	"github.com/Haste007/E-Voting/Backend/middleware"
+	"github.com/Haste007/E-Voting/Backend/polling"
+	"github.com/Haste007/E-Voting/Backend/questions"
+	"github.com/Haste007/E-Voting/Backend/scheduler"
 	"github.com/Haste007/E-Voting/Backend/utils"
 	"github.com/gofiber/fiber/v2"
 )
 
-// CastVote handles the casting of a vote
+// CastVote handles the casting of a vote by the voter identified in the session token
 func CastVote(c *fiber.Ctx) error {
 	type VoteRequest struct {
-		ElectionID     int    `json:"electionId"`
-		ConstituencyID int    `json:"constituencyId"`
-		PartyID        int    `json:"partyId"`
-		VoterID        string `json:"voterId"`
+		ElectionID      int                   `json:"electionId"`
+		ConstituencyID  int                   `json:"constituencyId"`
+		Choice          string                `json:"choice"` // "candidate" (default), "nota" or "ranked"
+		CandidateID     int                   `json:"candidateId"`
+		PartyID         int                   `json:"partyId"`         // Votes for the party's candidate when candidateId is not set
+		Ranking         []int                 `json:"ranking"`         // Candidate IDs in order of preference, for ranked elections
+		EncryptedBallot *elgamal.OneHotBallot `json:"encryptedBallot"` // Client-side encrypted choice, for encrypted elections
+	}
+
+	// The voter identity comes only from the token set by middleware.RequireVoter
+	voter, ok := c.Locals(middleware.VoterLocalsKey).(*utils.VoterClaims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing voter token"})
 	}
 
 	var voteRequest VoteRequest
@@ -24,53 +40,212 @@ func CastVote(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
 	}
 
-	// Hash the VoterID using SHA-256
-	hashedVoterID := hashVoterID(voteRequest.VoterID)
+	// Validation and insert share one transaction; the election row is held FOR SHARE
+	// so it cannot close underneath an accepted ballot
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		log.Println("Error starting vote transaction:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to cast vote"})
+	}
+	defer tx.Rollback()
+
+	// Reject ballots for closed elections, foreign constituencies or candidates not standing there.
+	// In encrypted elections this also verifies or produces the encrypted ballot.
+	cast := &ballot.Ballot{
+		ElectionID:     voteRequest.ElectionID,
+		ConstituencyID: voteRequest.ConstituencyID,
+		Choice:         voteRequest.Choice,
+		CandidateID:    voteRequest.CandidateID,
+		PartyID:        voteRequest.PartyID,
+		Ranking:        voteRequest.Ranking,
+		DistrictID:     voter.DistrictID,
+		Encrypted:      voteRequest.EncryptedBallot,
+	}
+	if err := ballot.Validate(tx, cast); err != nil {
+		return ballotErrorResponse(c, err)
+	}
+	// The constituency or the voter's district may be paused, or past its closing time
+	if err := polling.Check(tx, cast.ElectionID, cast.ConstituencyID, cast.DistrictID); err != nil {
+		return ballotErrorResponse(c, err)
+	}
+
+	// Derive the voter's keyed pseudonym for this election
+	hashedVoterID, err := utils.VoterPseudonym(voteRequest.ElectionID, voter.NID)
+	if err != nil {
+		log.Println("Error deriving voter pseudonym:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to cast vote"})
+	}
 
-	// Check if the voter has already voted in this election and constituency
-	var existingVoteCount int
-	checkVoteQuery := `
-        SELECT COUNT(*)
-        FROM votes
-        WHERE election_id = $1 AND constituency_id = $2 AND voter_hash = $3
+	// Record that the voter took part in this contest. The unique (election_id,
+	// constituency_id, voter_hash) constraint makes double voting atomic: of any number
+	// of concurrent inserts, exactly one succeeds. The voter's other contests, such as
+	// the provincial seat beside the national one, are unaffected.
+	participationQuery := `
+        INSERT INTO voter_participation (election_id, constituency_id, district_id, voter_hash)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (election_id, constituency_id, voter_hash) DO NOTHING
     `
-	err := utils.DB.QueryRow(checkVoteQuery, voteRequest.ElectionID, voteRequest.ConstituencyID, hashedVoterID).Scan(&existingVoteCount)
+	result, err := tx.Exec(participationQuery, voteRequest.ElectionID, cast.ConstituencyID, cast.DistrictID, hashedVoterID)
+	if err != nil {
+		log.Println("Error recording voter participation:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to cast vote"})
+	}
+
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		log.Println("Error checking existing vote:", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to check existing vote"})
+		log.Println("Error checking rows affected:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to cast vote"})
 	}
 
-	if existingVoteCount > 0 {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Voter has already cast a vote"})
+	var allowRevote bool
+	if err := tx.QueryRow("SELECT allow_revote FROM elections WHERE id = $1", cast.ElectionID).Scan(&allowRevote); err != nil {
+		log.Println("Error reading re-voting option:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to cast vote"})
+	}
+	if rowsAffected == 0 && !allowRevote {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Voter has already voted in this contest"})
 	}
 
-	// Insert the vote into the database
-	insertVoteQuery := `
-        INSERT INTO votes (election_id, constituency_id, party_id, voter_hash, vote_time)
-        VALUES ($1, $2, $3, $4, $5)
+	// In re-voting elections the new ballot replaces the voter's previous one in this
+	// contest. Locking the participation row records one voter's ballots strictly one
+	// after the other.
+	var revoteTag string
+	if allowRevote {
+		lockQuery := "SELECT 1 FROM voter_participation WHERE election_id = $1 AND constituency_id = $2 AND voter_hash = $3 FOR UPDATE"
+		if err := tx.QueryRow(lockQuery, cast.ElectionID, cast.ConstituencyID, hashedVoterID).Scan(new(int)); err != nil {
+			log.Println("Error locking voter participation:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to cast vote"})
+		}
+
+		revoteTag, err = utils.RevoteTag(cast.ElectionID, cast.ConstituencyID, voter.NID)
+		if err != nil {
+			log.Println("Error deriving re-vote tag:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to cast vote"})
+		}
+		replaceQuery := `
+            UPDATE ballot_box SET replaced = TRUE
+            WHERE id = (SELECT ballot_id FROM revote_tags WHERE election_id = $1 AND tag = $2)
+        `
+		if _, err := tx.Exec(replaceQuery, cast.ElectionID, revoteTag); err != nil {
+			log.Println("Error replacing previous ballot:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to cast vote"})
+		}
+	}
+
+	// Encrypted ballots are stored without any plaintext choice
+	var choice, candidateID, ranking, encryptedChoice interface{}
+	payload := bulletin.Payload{ConstituencyID: cast.ConstituencyID, EncryptedChoice: cast.Encrypted}
+	if cast.Encrypted != nil {
+		encoded, err := json.Marshal(cast.Encrypted)
+		if err != nil {
+			log.Println("Error encoding encrypted ballot:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to cast vote"})
+		}
+		encryptedChoice = string(encoded)
+	} else if cast.Choice == ballot.ChoiceRanked {
+		encoded, err := json.Marshal(cast.Ranking)
+		if err != nil {
+			log.Println("Error encoding ranking:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to cast vote"})
+		}
+		choice = ballot.ChoiceRanked
+		ranking = string(encoded)
+		payload.Ranking = cast.Ranking
+	} else if cast.Choice == ballot.ChoiceNOTA {
+		choice = ballot.ChoiceNOTA
+		payload.NOTA = true
+	} else {
+		choice = ballot.ChoiceCandidate
+		candidateID = cast.CandidateID
+		payload.CandidateID = &cast.CandidateID
+	}
+
+	// Publish an encrypted ballot on the public bulletin board in the same
+	// transaction. Plaintext ballots show their choice, so in cast order they
+	// could be matched to voters seen voting; they are published in a shuffled
+	// batch when polls close instead.
+	var entry *bulletin.Entry
+	if cast.Encrypted != nil {
+		entry, err = bulletin.Append(tx, cast.ElectionID, payload)
+		if err != nil {
+			log.Println("Error appending ballot to bulletin board:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to cast vote"})
+		}
+	}
+
+	// Drop the anonymous ballot into the ballot box. It carries no voter reference,
+	// a random ID and only the hour it was cast, so it cannot be joined back to
+	// voter_participation by key or timestamp.
+	ballotQuery := `
+        INSERT INTO ballot_box (election_id, constituency_id, choice, candidate_id, ranking, encrypted_choice, cast_hour)
+        VALUES ($1, $2, $3, $4, $5, $6, date_trunc('hour', NOW()))
+        RETURNING id
     `
-	_, err = utils.DB.Exec(insertVoteQuery, voteRequest.ElectionID, voteRequest.ConstituencyID, voteRequest.PartyID, hashedVoterID, time.Now())
+	var ballotID string
+	if err := tx.QueryRow(ballotQuery, cast.ElectionID, cast.ConstituencyID, choice, candidateID, ranking, encryptedChoice).Scan(&ballotID); err != nil {
+		log.Println("Error inserting ballot:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to cast vote"})
+	}
+
+	// The tag points at the voter's current ballot from a table of its own, which
+	// is emptied when polls close, so ballots never carry it
+	if allowRevote {
+		tagQuery := `
+            INSERT INTO revote_tags (election_id, tag, ballot_id)
+            VALUES ($1, $2, $3)
+            ON CONFLICT (election_id, tag) DO UPDATE SET ballot_id = EXCLUDED.ballot_id
+        `
+		if _, err := tx.Exec(tagQuery, cast.ElectionID, revoteTag, ballotID); err != nil {
+			log.Println("Error recording re-vote tag:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to cast vote"})
+		}
+	}
+
+	// Issue a random tracking code. Only encrypted ballots are linked to their board
+	// entry: a plaintext entry shows the choice, which would turn the receipt into
+	// proof of how the voter voted.
+	trackingCode, codeHash, err := ballot.NewTrackingCode()
 	if err != nil {
-		log.Println("Error inserting vote:", err)
+		log.Println("Error generating tracking code:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to cast vote"})
+	}
+	// In re-voting elections the entry is not linked either: with the closing
+	// entry's superseded positions it would tell the code's holder whether the voter recast
+	var entryHash interface{}
+	if entry != nil && !allowRevote {
+		entryHash = entry.EntryHash
+	}
+	receiptQuery := "INSERT INTO ballot_receipts (code_hash, ballot_id, entry_hash) VALUES ($1, $2, $3)"
+	if _, err := tx.Exec(receiptQuery, codeHash, ballotID, entryHash); err != nil {
+		log.Println("Error storing ballot receipt:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to cast vote"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println("Error committing vote:", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to cast vote"})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Vote cast successfully"})
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Vote cast successfully", "tracking_code": trackingCode})
 }
 
-// hashVoterID hashes the voter ID using SHA-256
-func hashVoterID(voterID string) string {
-	hash := sha256.New()
-	hash.Write([]byte(voterID))
-	return hex.EncodeToString(hash.Sum(nil))
+// ballotErrorResponse maps ballot validation failures to their status and error code
+func ballotErrorResponse(c *fiber.Ctx, err error) error {
+	var validationErr *ballot.ValidationError
+	if errors.As(err, &validationErr) {
+		return c.Status(validationErr.Status).JSON(fiber.Map{"error": validationErr.Message, "code": validationErr.Code})
+	}
+	log.Println("Error validating ballot:", err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to validate ballot"})
 }
 
-// GetOngoingElections fetches all elections where started = true and ended = false
+// GetOngoingElections fetches all elections whose polls are open or paused,
+// with whether each of their constituencies is taking votes
 func GetOngoingElections(c *fiber.Ctx) error {
 	query := `
-        SELECT id, name, date
+        SELECT id, name, date, status, closes_at, time_zone, allow_revote, ballot_type
         FROM elections
-        WHERE started = TRUE AND ended = FALSE
+        WHERE status IN ('open', 'paused')
     `
 
 	rows, err := utils.DB.Query(query)
@@ -80,29 +255,46 @@ func GetOngoingElections(c *fiber.Ctx) error {
 	}
 	defer rows.Close()
 
-	var elections []struct {
-		ID   int    `json:"id"`
-		Name string `json:"name"`
-		Date string `json:"date"`
+	type ongoingElection struct {
+		ID             int                          `json:"id"`
+		Name           string                       `json:"name"`
+		Date           string                       `json:"date"`
+		Status         string                       `json:"status"`    // "paused" while voting is paused nationwide
+		ClosesAt       *string                      `json:"closes_at"` // Before any constituency's extension
+		AllowRevote    bool                         `json:"allow_revote"`
+		BallotType     string                       `json:"ballot_type"`
+		Constituencies []polling.ConstituencyStatus `json:"constituencies"`
 	}
+	var elections []ongoingElection
 
 	for rows.Next() {
-		var election struct {
-			ID   int    `json:"id"`
-			Name string `json:"name"`
-			Date string `json:"date"`
-		}
-		if err := rows.Scan(&election.ID, &election.Name, &election.Date); err != nil {
+		var election ongoingElection
+		var closesAt sql.NullTime
+		var zone string
+		if err := rows.Scan(&election.ID, &election.Name, &election.Date, &election.Status, &closesAt, &zone, &election.AllowRevote, &election.BallotType); err != nil {
 			log.Println("Error scanning election row:", err)
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to parse ongoing elections"})
 		}
+		election.ClosesAt = scheduler.Format(closesAt, zone)
 		elections = append(elections, election)
 	}
+	rows.Close()
+
+	for i := range elections {
+		statuses, err := polling.Constituencies(utils.DB, elections[i].ID)
+		if err != nil {
+			log.Println("Error fetching polling status:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch ongoing elections"})
+		}
+		elections[i].Constituencies = statuses
+	}
 
 	return c.JSON(elections)
 }
 
-// GetConstituencyData fetches constituency data for a specific election and district
+// GetConstituencyData returns every contest a voter of a district may take part
+// in: the candidate race of the constituency covering the district in each tier,
+// National Assembly first, then each ballot question asked there
 func GetConstituencyData(c *fiber.Ctx) error {
 	electionID := c.Params("electionId")
 	districtID := c.Params("districtId")
@@ -111,22 +303,25 @@ func GetConstituencyData(c *fiber.Ctx) error {
         SELECT 
             c.id AS constituency_id, 
             c.name AS constituency_name, 
+            c.tier,
+            c.seats,
             p.id AS party_id, 
-            p.name AS party_name, 
-            p.logo AS party_logo, 
+            COALESCE(p.name, 'Independent') AS party_name, 
+            COALESCE(p.logo, ct.symbol, '') AS party_logo, -- Independents show their own symbol
             ct.id AS candidate_id,
             ci.name AS candidate_name
         FROM constituencies c
         JOIN constituency_districts cd ON c.id = cd.constituency_id
         JOIN candidates ct ON c.id = ct.constituency_id
         JOIN citizens ci ON ct.citizen_id = ci.id
-        JOIN parties p ON ct.party_id = p.id
+        LEFT JOIN parties p ON ct.party_id = p.id
         WHERE cd.district_id = $1 
           AND c.id IN (
               SELECT constituency_id
               FROM election_constituencies
               WHERE election_id = $2
           )
+        ORDER BY c.tier, c.id, ct.id -- Ballot order; encrypted ballots list choices in this order, then NOTA
     `
 
 	rows, err := utils.DB.Query(query, districtID, electionID)
@@ -136,29 +331,28 @@ func GetConstituencyData(c *fiber.Ctx) error {
 	}
 	defer rows.Close()
 
-	var candidates []struct {
+	// One line of the ballot; NOTA lines carry only the constituency
+	type ballotLine struct {
 		ConstituencyID   int    `json:"constituency_id"`
 		ConstituencyName string `json:"constituency_name"`
-		PartyID          int    `json:"party_id"`
+		Tier             string `json:"tier"`     // "na" or "pa"
+		Seats            int    `json:"seats"`    // Candidates the constituency elects
+		Choice           string `json:"choice"`   // "candidate" or "nota"
+		PartyID          *int   `json:"party_id"` // Null for independents and NOTA
 		PartyName        string `json:"party_name"`
 		PartyLogo        string `json:"party_logo"`
 		CandidateID      int    `json:"candidate_id"`
 		CandidateName    string `json:"candidate_name"`
 	}
 
+	var candidates []ballotLine
 	for rows.Next() {
-		var candidate struct {
-			ConstituencyID   int    `json:"constituency_id"`
-			ConstituencyName string `json:"constituency_name"`
-			PartyID          int    `json:"party_id"`
-			PartyName        string `json:"party_name"`
-			PartyLogo        string `json:"party_logo"`
-			CandidateID      int    `json:"candidate_id"`
-			CandidateName    string `json:"candidate_name"`
-		}
+		candidate := ballotLine{Choice: ballot.ChoiceCandidate}
 		if err := rows.Scan(
 			&candidate.ConstituencyID,
 			&candidate.ConstituencyName,
+			&candidate.Tier,
+			&candidate.Seats,
 			&candidate.PartyID,
 			&candidate.PartyName,
 			&candidate.PartyLogo,
@@ -171,5 +365,111 @@ func GetConstituencyData(c *fiber.Ctx) error {
 		candidates = append(candidates, candidate)
 	}
 
-	return c.JSON(candidates)
+	// NOTA closes each constituency's ballot, matching the encrypted ballot order
+	allowNOTA, err := ballot.OffersNOTA(utils.DB, electionID)
+	if err != nil {
+		log.Println("Error reading NOTA option:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch constituency data"})
+	}
+	if allowNOTA && len(candidates) > 0 {
+		var lines []ballotLine
+		for i, candidate := range candidates {
+			lines = append(lines, candidate)
+			if i == len(candidates)-1 || candidates[i+1].ConstituencyID != candidate.ConstituencyID {
+				lines = append(lines, ballotLine{
+					ConstituencyID:   candidate.ConstituencyID,
+					ConstituencyName: candidate.ConstituencyName,
+					Tier:             candidate.Tier,
+					Seats:            candidate.Seats,
+					Choice:           ballot.ChoiceNOTA,
+					CandidateName:    "None of the Above",
+				})
+			}
+		}
+		candidates = lines
+	}
+
+	// One contest per constituency race or ballot question
+	type contest struct {
+		Type             string              `json:"type"` // "candidate" or "question"
+		ConstituencyID   int                 `json:"constituency_id,omitempty"`
+		ConstituencyName string              `json:"constituency_name,omitempty"`
+		Tier             string              `json:"tier,omitempty"` // "na" or "pa"; one vote per tier
+		Seats            int                 `json:"seats,omitempty"`
+		Lines            []ballotLine        `json:"lines,omitempty"`
+		Question         *questions.Question `json:"question,omitempty"`
+	}
+
+	contests := []contest{}
+	for _, line := range candidates {
+		if len(contests) == 0 || contests[len(contests)-1].ConstituencyID != line.ConstituencyID {
+			contests = append(contests, contest{
+				Type:             "candidate",
+				ConstituencyID:   line.ConstituencyID,
+				ConstituencyName: line.ConstituencyName,
+				Tier:             line.Tier,
+				Seats:            line.Seats,
+			})
+		}
+		contests[len(contests)-1].Lines = append(contests[len(contests)-1].Lines, line)
+	}
+
+	asked, err := questions.ForDistrict(utils.DB, electionID, districtID)
+	if err != nil {
+		log.Println("Error fetching ballot questions:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch constituency data"})
+	}
+	for i := range asked {
+		contests = append(contests, contest{Type: "question", Question: &asked[i]})
+	}
+
+	return c.JSON(fiber.Map{"contests": contests})
+}
+
+// LookupReceipt confirms whether a tracking code belongs to a ballot recorded, and
+// counted, in an election. It reports the constituency but never the choice, so a receipt
+// cannot show anyone how its holder voted.
+func LookupReceipt(c *fiber.Ctx) error {
+	electionID := c.Params("id")
+	codeHash := ballot.HashTrackingCode(c.Params("code"))
+
+	var receipt struct {
+		ElectionID       int     `json:"election_id"`
+		ConstituencyID   int     `json:"constituency_id"`
+		ConstituencyName string  `json:"constituency_name"`
+		ElectionStatus   string  `json:"election_status"`
+		Recorded         bool    `json:"recorded"`
+		Counted          *bool   `json:"counted"`              // Always withheld in re-voting elections
+		EntryHash        *string `json:"entry_hash,omitempty"` // Board entry of an encrypted ballot, outside re-voting elections
+	}
+	var replaced, allowRevote bool
+
+	query := `
+        SELECT b.election_id, b.constituency_id, c.name, e.status, r.entry_hash, b.replaced, e.allow_revote
+        FROM ballot_receipts r
+        JOIN ballot_box b ON b.id = r.ballot_id
+        JOIN constituencies c ON c.id = b.constituency_id
+        JOIN elections e ON e.id = b.election_id
+        WHERE r.code_hash = $1 AND b.election_id = $2
+    `
+	err := utils.DB.QueryRow(query, codeHash, electionID).Scan(&receipt.ElectionID, &receipt.ConstituencyID,
+		&receipt.ConstituencyName, &receipt.ElectionStatus, &receipt.EntryHash, &replaced, &allowRevote)
+	if err == sql.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Tracking code not found in this election", "counted": false})
+	} else if err != nil {
+		log.Println("Error looking up receipt:", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to look up tracking code"})
+	}
+	receipt.Recorded = true
+
+	// Revealing a replaced ballot, during polls or after, would let a coercer
+	// holding the receipt check whether the voter voted again
+	if allowRevote {
+		receipt.EntryHash = nil
+	} else {
+		counted := !replaced
+		receipt.Counted = &counted
+	}
+
+	return c.JSON(receipt)
 }
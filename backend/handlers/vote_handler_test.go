@@ -0,0 +1,153 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/Haste007/E-Voting/Backend/handlers"
+	"github.com/Haste007/E-Voting/Backend/middleware"
+	"github.com/Haste007/E-Voting/Backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// connectTestDB points utils.DB at TEST_DB_CONN_STRING and recreates the schema.
+// The database is wiped, so never point it at real data.
+func connectTestDB(t *testing.T) {
+	t.Helper()
+
+	connStr := os.Getenv("TEST_DB_CONN_STRING")
+	if connStr == "" {
+		t.Skip("TEST_DB_CONN_STRING not set; skipping database test")
+	}
+	t.Setenv("DB_CONN_STRING", connStr)
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("VOTER_KEY_FILE", "")
+	t.Setenv("VOTER_KEY", "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+
+	if err := utils.ConnectDB(); err != nil {
+		t.Fatalf("connecting to test database: %v", err)
+	}
+	utils.DB.SetMaxOpenConns(50)
+
+	schema, err := os.ReadFile("../../database/tables.sql")
+	if err != nil {
+		t.Fatalf("reading schema: %v", err)
+	}
+	if _, err := utils.DB.Exec(string(schema)); err != nil {
+		t.Fatalf("creating schema: %v", err)
+	}
+	if err := utils.LoadVoterKeys(); err != nil {
+		t.Fatalf("loading voter keys: %v", err)
+	}
+}
+
+// openElectionFixture creates an open election with one constituency, one party
+// candidate and one voter, and returns their IDs
+func openElectionFixture(t *testing.T) (electionID, constituencyID, partyID, districtID, citizenID int) {
+	t.Helper()
+
+	mustScan := func(dest *int, query string, args ...interface{}) {
+		if err := utils.DB.QueryRow(query, args...).Scan(dest); err != nil {
+			t.Fatalf("fixture %q: %v", query, err)
+		}
+	}
+	mustExec := func(query string, args ...interface{}) {
+		if _, err := utils.DB.Exec(query, args...); err != nil {
+			t.Fatalf("fixture %q: %v", query, err)
+		}
+	}
+
+	mustScan(&districtID, "INSERT INTO districts (name) VALUES ('Test District') RETURNING id")
+	mustScan(&citizenID, "INSERT INTO citizens (name, nid, district_id) VALUES ('Test Voter', '11111-2222222-3', $1) RETURNING id", districtID)
+	mustScan(&partyID, "INSERT INTO parties (name, president) VALUES ('Test Party', $1) RETURNING id", citizenID)
+	mustScan(&constituencyID, "INSERT INTO constituencies (name) VALUES ('NA-1') RETURNING id")
+	mustScan(&electionID, "INSERT INTO elections (name, date, status, pseudonym_key_version) VALUES ('Test Election', NOW(), 'open', $1) RETURNING id", utils.CurrentVoterKeyVersion())
+	mustExec("INSERT INTO constituency_districts (constituency_id, district_id) VALUES ($1, $2)", constituencyID, districtID)
+	mustExec("INSERT INTO election_constituencies (election_id, constituency_id) VALUES ($1, $2)", electionID, constituencyID)
+	mustExec("INSERT INTO candidates (party_id, citizen_id, constituency_id) VALUES ($1, $2, $3)", partyID, citizenID, constituencyID)
+
+	return electionID, constituencyID, partyID, districtID, citizenID
+}
+
+// TestCastVoteConcurrentSingleVoter fires many parallel votes for one voter and
+// checks that exactly one is accepted and counted
+func TestCastVoteConcurrentSingleVoter(t *testing.T) {
+	connectTestDB(t)
+	electionID, constituencyID, partyID, districtID, citizenID := openElectionFixture(t)
+
+	token, err := utils.GenerateVoterToken(citizenID, "11111-2222222-3", districtID)
+	if err != nil {
+		t.Fatalf("generating voter token: %v", err)
+	}
+
+	app := fiber.New()
+	app.Post("/api/votes", middleware.RequireVoter, handlers.CastVote)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	go app.Listener(ln)
+	defer app.Shutdown()
+
+	body, _ := json.Marshal(map[string]int{
+		"electionId":     electionID,
+		"constituencyId": constituencyID,
+		"partyId":        partyID,
+	})
+	url := fmt.Sprintf("http://%s/api/votes", ln.Addr())
+
+	const requests = 300
+	statuses := make(chan int, requests)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			req, _ := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Errorf("request failed: %v", err)
+				return
+			}
+			resp.Body.Close()
+			statuses <- resp.StatusCode
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(statuses)
+
+	counts := map[int]int{}
+	for status := range statuses {
+		counts[status]++
+	}
+	if counts[http.StatusOK] != 1 {
+		t.Errorf("expected exactly one accepted vote, got %d (statuses %v)", counts[http.StatusOK], counts)
+	}
+	if counts[http.StatusConflict] != requests-1 {
+		t.Errorf("expected %d conflicts, got %d (statuses %v)", requests-1, counts[http.StatusConflict], counts)
+	}
+
+	// Plaintext ballots reach the bulletin board only when polls close
+	for table, want := range map[string]int{"voter_participation": 1, "ballot_box": 1, "bulletin_board": 0} {
+		var stored int
+		if err := utils.DB.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE election_id = $1", electionID).Scan(&stored); err != nil {
+			t.Fatalf("counting %s rows: %v", table, err)
+		}
+		if stored != want {
+			t.Errorf("expected %d rows in %s, got %d", want, table, stored)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package lifecycle
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Election lifecycle states, stored in elections.status
+const (
+	StatusDraft     = "draft"     // Being set up; constituencies and candidates may change
+	StatusScheduled = "scheduled" // Set up is frozen, waiting for polls to open
+	StatusOpen      = "open"      // Polls are open and votes are accepted
+	StatusPaused    = "paused"    // Polls are temporarily suspended
+	StatusClosed    = "closed"    // Polls are closed, tally not yet run
+	StatusTallied   = "tallied"   // Results have been calculated
+	StatusCertified = "certified" // Results are final
+)
+
+// transitions lists the states each state may move to
+var transitions = map[string][]string{
+	StatusDraft:     {StatusScheduled},
+	StatusScheduled: {StatusDraft, StatusOpen},
+	StatusOpen:      {StatusPaused, StatusClosed},
+	StatusPaused:    {StatusOpen, StatusClosed},
+	StatusClosed:    {StatusTallied},
+	StatusTallied:   {StatusCertified},
+	StatusCertified: {},
+}
+
+// Operations that are only allowed in some states
+const (
+	OpEdit          = "edit"
+	OpDelete        = "delete"
+	OpVote          = "vote"
+	OpAdjustPolling = "adjust polling" // Pause, resume or extend single constituencies and districts
+	OpCorrectResult = "correct results"
+	OpRecount       = "recount" // Re-run the tally, or recount one constituency
+	OpAssignOfficer = "assign returning officers"
+	OpSignResult    = "sign results"
+)
+
+// operationStatuses lists the states in which each operation is allowed
+var operationStatuses = map[string][]string{
+	OpEdit:          {StatusDraft, StatusScheduled},
+	OpDelete:        {StatusDraft, StatusScheduled},
+	OpVote:          {StatusOpen},
+	OpAdjustPolling: {StatusOpen, StatusPaused},
+	OpCorrectResult: {StatusTallied},
+	OpRecount:       {StatusTallied},
+	OpAssignOfficer: {StatusDraft, StatusScheduled, StatusOpen, StatusPaused, StatusClosed, StatusTallied},
+	OpSignResult:    {StatusTallied},
+}
+
+// ErrElectionNotFound is returned when the election does not exist
+var ErrElectionNotFound = errors.New("election not found")
+
+// StateError reports an operation or transition that the election's current state does not allow
+type StateError struct {
+	Status string // Current state of the election
+	Action string // Target state or operation that was refused
+}
+
+func (e *StateError) Error() string {
+	return fmt.Sprintf("election is %s and cannot %s", e.Status, e.Action)
+}
+
+// Queryer is satisfied by both *sql.DB and *sql.Tx
+type Queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// CanTransition reports whether an election may move from one state to another
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether op may run while an election is in status
+func Allows(op, status string) bool {
+	for _, s := range operationStatuses[op] {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// CurrentStatus returns the election's state. Pass a *sql.Tx and lock
+// ("FOR UPDATE" or "FOR SHARE") to hold the row until the transaction ends.
+func CurrentStatus(q Queryer, electionID interface{}, lock string) (string, error) {
+	var status string
+	query := "SELECT status FROM elections WHERE id = $1 " + lock
+	if err := q.QueryRow(query, electionID).Scan(&status); err == sql.ErrNoRows {
+		return "", ErrElectionNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return status, nil
+}
+
+// Require returns a *StateError unless op is allowed in the election's current state
+func Require(q Queryer, electionID interface{}, op, lock string) (string, error) {
+	status, err := CurrentStatus(q, electionID, lock)
+	if err != nil {
+		return "", err
+	}
+	if !Allows(op, status) {
+		return status, &StateError{Status: status, Action: op}
+	}
+	return status, nil
+}
+
+// Transition validates and applies a state change inside tx and records it in
+// election_status_history together with the actor that requested it
+func Transition(tx *sql.Tx, electionID interface{}, to, actor string) error {
+	from, err := CurrentStatus(tx, electionID, "FOR UPDATE")
+	if err != nil {
+		return err
+	}
+	if !CanTransition(from, to) {
+		return &StateError{Status: from, Action: "move to " + to}
+	}
+
+	if _, err := tx.Exec("UPDATE elections SET status = $1 WHERE id = $2", to, electionID); err != nil {
+		return err
+	}
+
+	historyQuery := `
+        INSERT INTO election_status_history (election_id, from_status, to_status, actor)
+        VALUES ($1, $2, $3, $4)
+    `
+	if _, err := tx.Exec(historyQuery, electionID, from, to, actor); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,241 @@
+// Package live streams an election's turnout, and its results once tallied,
+// to dashboards as they change.
+//
+// However many clients watch an election, the backend reads its figures from
+// the database once per LIVE_INTERVAL, and only for elections somebody is
+// watching. Each watcher is sent an event only when the figures differ from
+// the last ones it was sent; a watcher that falls behind skips straight to the
+// newest figures instead of queueing every intermediate one.
+//
+// Turnout by district while polls are open could be matched to voters walking
+// in, so public watchers are dropped as soon as an election's polls open.
+package live
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultInterval is how often watched elections are refreshed unless
+// LIVE_INTERVAL is set
+const DefaultInterval = 5 * time.Second
+
+// Event names
+const (
+	EventTurnout = "turnout"
+	EventResults = "results"
+)
+
+// Event is one named JSON payload sent to watchers
+type Event struct {
+	Name string
+	Data []byte
+}
+
+// Subscription receives the events of one election
+type Subscription struct {
+	electionID int
+	public     bool // Not an admin; dropped while polls are open
+	ready      chan struct{}
+	done       chan struct{}
+	pending    []Event // Newest event of each name not yet taken
+}
+
+// feed holds the watchers of one election and the last event of each name
+type feed struct {
+	subscriptions map[*Subscription]struct{}
+	last          map[string][]byte
+	version       int  // Tally snapshot the last results event came from
+	pollsOpen     bool // As of the last refresh
+}
+
+var (
+	mu       sync.Mutex
+	feeds    = make(map[int]*feed)
+	requests = make(chan int, 64) // Newly watched elections to refresh straight away
+)
+
+// Start refreshes watched elections every LIVE_INTERVAL (a Go duration such as
+// "5s") in the background
+func Start() error {
+	interval := DefaultInterval
+	if value := os.Getenv("LIVE_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		interval = parsed
+	}
+
+	go func() {
+		tick := time.Tick(interval)
+		for {
+			select {
+			case <-tick:
+				for _, electionID := range watched() {
+					refreshLogged(electionID)
+				}
+			case electionID := <-requests:
+				refreshLogged(electionID)
+			}
+		}
+	}()
+	return nil
+}
+
+// Subscribe starts watching an election. The latest figures already known are
+// ready at once; the first watcher of an election has them fetched. A public
+// subscription is done at once if the election's polls are known to be open.
+func Subscribe(electionID int, public bool) *Subscription {
+	mu.Lock()
+	defer mu.Unlock()
+
+	s := &Subscription{electionID: electionID, public: public, ready: make(chan struct{}, 1), done: make(chan struct{})}
+	f, ok := feeds[electionID]
+	if ok && public && f.pollsOpen {
+		close(s.done)
+		return s
+	}
+	if !ok {
+		f = &feed{subscriptions: make(map[*Subscription]struct{}), last: make(map[string][]byte)}
+		feeds[electionID] = f
+		select {
+		case requests <- electionID:
+		default: // Picked up on the next tick instead
+		}
+	}
+	f.subscriptions[s] = struct{}{}
+	for _, name := range []string{EventTurnout, EventResults} {
+		if data, ok := f.last[name]; ok {
+			s.push(Event{Name: name, Data: data})
+		}
+	}
+	return s
+}
+
+// Ready is signalled whenever new events can be taken
+func (s *Subscription) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Done is closed when the subscription is dropped because polls opened
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Take returns the events that arrived since it was last called
+func (s *Subscription) Take() []Event {
+	mu.Lock()
+	defer mu.Unlock()
+	events := s.pending
+	s.pending = nil
+	return events
+}
+
+// Close stops watching the election
+func (s *Subscription) Close() {
+	mu.Lock()
+	defer mu.Unlock()
+	if f, ok := feeds[s.electionID]; ok {
+		delete(f.subscriptions, s)
+		if len(f.subscriptions) == 0 {
+			delete(feeds, s.electionID)
+		}
+	}
+}
+
+// push queues an event, replacing an untaken one of the same name; the caller holds mu
+func (s *Subscription) push(event Event) {
+	replaced := false
+	for i := range s.pending {
+		if s.pending[i].Name == event.Name {
+			s.pending[i] = event
+			replaced = true
+		}
+	}
+	if !replaced {
+		s.pending = append(s.pending, event)
+	}
+	select {
+	case s.ready <- struct{}{}:
+	default: // Already signalled
+	}
+}
+
+// publish sends an event to an election's watchers unless it repeats the last one
+func publish(electionID int, name string, data []byte) {
+	mu.Lock()
+	defer mu.Unlock()
+	f, ok := feeds[electionID]
+	if !ok || bytes.Equal(f.last[name], data) {
+		return
+	}
+	f.last[name] = data
+	for s := range f.subscriptions {
+		s.push(Event{Name: name, Data: data})
+	}
+}
+
+// setPollsOpen records whether an election's polls are open and drops its
+// public watchers if they are
+func setPollsOpen(electionID int, open bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	f, ok := feeds[electionID]
+	if !ok {
+		return
+	}
+	f.pollsOpen = open
+	if !open {
+		return
+	}
+	for s := range f.subscriptions {
+		if s.public {
+			delete(f.subscriptions, s)
+			close(s.done)
+		}
+	}
+	if len(f.subscriptions) == 0 {
+		delete(feeds, electionID)
+	}
+}
+
+// watched lists the elections somebody is watching
+func watched() []int {
+	mu.Lock()
+	defer mu.Unlock()
+	ids := make([]int, 0, len(feeds))
+	for electionID := range feeds {
+		ids = append(ids, electionID)
+	}
+	return ids
+}
+
+// resultsVersion returns the tally snapshot of the last results event sent for an election
+func resultsVersion(electionID int) int {
+	mu.Lock()
+	defer mu.Unlock()
+	if f, ok := feeds[electionID]; ok {
+		return f.version
+	}
+	return 0
+}
+
+// setResultsVersion records the snapshot of the results event just sent
+func setResultsVersion(electionID, version int) {
+	mu.Lock()
+	defer mu.Unlock()
+	if f, ok := feeds[electionID]; ok {
+		f.version = version
+	}
+}
+
+// refreshLogged refreshes an election, logging any failure; the next tick retries
+func refreshLogged(electionID int) {
+	if err := refresh(electionID); err != nil {
+		log.Printf("Error refreshing live figures of election %d: %v", electionID, err)
+	}
+}
@@ -0,0 +1,252 @@
+package live
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/Haste007/E-Voting/Backend/ballot"
+	"github.com/Haste007/E-Voting/Backend/lifecycle"
+	"github.com/Haste007/E-Voting/Backend/tally"
+	"github.com/Haste007/E-Voting/Backend/utils"
+)
+
+// Turnout is the payload of a turnout event
+type Turnout struct {
+	ElectionID     int                   `json:"election_id"`
+	Status         string                `json:"status"`
+	SharesVisible  bool                  `json:"shares_visible"` // Running vote shares are included
+	Constituencies []ConstituencyTurnout `json:"constituencies"`
+	Districts      []DistrictTurnout     `json:"districts"`
+}
+
+// ConstituencyTurnout counts the voters who took part in one contest
+type ConstituencyTurnout struct {
+	ConstituencyID int     `json:"constituency_id"`
+	Name           string  `json:"name"`
+	Tier           string  `json:"tier"`
+	Voted          int     `json:"voted"`
+	Electorate     int     `json:"electorate"` // Citizens living in the constituency's districts
+	Shares         []Share `json:"shares"`     // Null unless shares_visible
+}
+
+// DistrictTurnout counts the voters of one district who took part in any contest
+type DistrictTurnout struct {
+	DistrictID int    `json:"district_id"`
+	Name       string `json:"name"`
+	Voted      int    `json:"voted"`
+	Electorate int    `json:"electorate"`
+}
+
+// Share is the running count of one choice; ranked ballots count first preferences
+type Share struct {
+	Choice      string `json:"choice"`
+	CandidateID *int   `json:"candidate_id"` // NULL for NOTA
+	Votes       int    `json:"votes"`
+}
+
+// Results is the payload of a results event
+type Results struct {
+	ElectionID     int                  `json:"election_id"`
+	TallyVersion   int                  `json:"tally_version"` // Latest snapshot in tally_runs; 0 if tallied before snapshots were kept
+	Constituencies []ConstituencyResult `json:"constituencies"`
+}
+
+// ConstituencyResult is the current result of one constituency
+type ConstituencyResult struct {
+	ConstituencyID int          `json:"constituency_id"`
+	Lines          []tally.Line `json:"lines"`
+}
+
+// refresh reads an election's figures and publishes those that changed
+func refresh(electionID int) error {
+	var status string
+	var liveResults, encrypted bool
+	query := "SELECT status, live_results, encrypted FROM elections WHERE id = $1"
+	err := utils.DB.QueryRow(query, electionID).Scan(&status, &liveResults, &encrypted)
+	if err == sql.ErrNoRows {
+		return nil // Deleted; its watchers simply stop hearing about it
+	} else if err != nil {
+		return err
+	}
+
+	// Public watchers go before any figures from open polls are published
+	setPollsOpen(electionID, status == lifecycle.StatusOpen || status == lifecycle.StatusPaused)
+
+	// Shares stay hidden while polls are open unless the election allows them,
+	// and once tallied the results event carries them instead
+	sharesVisible := false
+	switch status {
+	case lifecycle.StatusOpen, lifecycle.StatusPaused:
+		sharesVisible = liveResults && !encrypted
+	case lifecycle.StatusClosed:
+		sharesVisible = !encrypted
+	}
+	turnout, err := readTurnout(utils.DB, electionID, status, sharesVisible)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(turnout)
+	if err != nil {
+		return err
+	}
+	publish(electionID, EventTurnout, data)
+
+	if status != lifecycle.StatusTallied && status != lifecycle.StatusCertified {
+		return nil
+	}
+	// Results are only read again when a new snapshot is recorded
+	var version int
+	if err := utils.DB.QueryRow("SELECT COALESCE(MAX(version), 0) FROM tally_runs WHERE election_id = $1", electionID).Scan(&version); err != nil {
+		return err
+	}
+	if version != 0 && version == resultsVersion(electionID) {
+		return nil
+	}
+	results, err := readResults(utils.DB, electionID, version)
+	if err != nil {
+		return err
+	}
+	if data, err = json.Marshal(results); err != nil {
+		return err
+	}
+	publish(electionID, EventResults, data)
+	setResultsVersion(electionID, version)
+	return nil
+}
+
+// readTurnout counts the voters of each constituency and district of an election
+func readTurnout(q ballot.Querier, electionID int, status string, sharesVisible bool) (Turnout, error) {
+	turnout := Turnout{ElectionID: electionID, Status: status, SharesVisible: sharesVisible, Constituencies: []ConstituencyTurnout{}, Districts: []DistrictTurnout{}}
+
+	constituencyQuery := `
+        SELECT c.id, c.name, c.tier,
+               (SELECT COUNT(*) FROM voter_participation vp WHERE vp.election_id = ec.election_id AND vp.constituency_id = c.id),
+               (SELECT COUNT(*) FROM citizens ci JOIN constituency_districts cd ON cd.district_id = ci.district_id WHERE cd.constituency_id = c.id)
+        FROM election_constituencies ec
+        JOIN constituencies c ON c.id = ec.constituency_id
+        WHERE ec.election_id = $1
+        ORDER BY c.tier, c.id
+    `
+	rows, err := q.Query(constituencyQuery, electionID)
+	if err != nil {
+		return Turnout{}, err
+	}
+	defer rows.Close()
+	index := make(map[int]int)
+	for rows.Next() {
+		var constituency ConstituencyTurnout
+		if err := rows.Scan(&constituency.ConstituencyID, &constituency.Name, &constituency.Tier, &constituency.Voted, &constituency.Electorate); err != nil {
+			return Turnout{}, err
+		}
+		if sharesVisible {
+			constituency.Shares = []Share{}
+		}
+		index[constituency.ConstituencyID] = len(turnout.Constituencies)
+		turnout.Constituencies = append(turnout.Constituencies, constituency)
+	}
+	if err := rows.Err(); err != nil {
+		return Turnout{}, err
+	}
+
+	// A voter counts once in their district however many contests they voted in
+	districtQuery := `
+        SELECT d.id, d.name, COUNT(DISTINCT vp.voter_hash),
+               (SELECT COUNT(*) FROM citizens ci WHERE ci.district_id = d.id)
+        FROM districts d
+        LEFT JOIN voter_participation vp ON vp.election_id = $1 AND vp.district_id = d.id
+        WHERE d.id IN (
+            SELECT cd.district_id
+            FROM constituency_districts cd
+            JOIN election_constituencies ec ON ec.constituency_id = cd.constituency_id
+            WHERE ec.election_id = $1
+        )
+        GROUP BY d.id, d.name
+        ORDER BY d.name
+    `
+	districtRows, err := q.Query(districtQuery, electionID)
+	if err != nil {
+		return Turnout{}, err
+	}
+	defer districtRows.Close()
+	for districtRows.Next() {
+		var district DistrictTurnout
+		if err := districtRows.Scan(&district.DistrictID, &district.Name, &district.Voted, &district.Electorate); err != nil {
+			return Turnout{}, err
+		}
+		turnout.Districts = append(turnout.Districts, district)
+	}
+	if err := districtRows.Err(); err != nil {
+		return Turnout{}, err
+	}
+
+	if !sharesVisible {
+		return turnout, nil
+	}
+	shareQuery := `
+        SELECT constituency_id,
+               CASE WHEN choice = 'nota' THEN 'nota' ELSE 'candidate' END,
+               CASE WHEN choice = 'ranked' THEN (ranking->>0)::INT ELSE candidate_id END,
+               COUNT(*)
+        FROM ballot_box
+        WHERE election_id = $1 AND NOT replaced AND choice IS NOT NULL
+        GROUP BY 1, 2, 3
+        ORDER BY 1, 3 NULLS LAST
+    `
+	shareRows, err := q.Query(shareQuery, electionID)
+	if err != nil {
+		return Turnout{}, err
+	}
+	defer shareRows.Close()
+	for shareRows.Next() {
+		var constituencyID int
+		var share Share
+		if err := shareRows.Scan(&constituencyID, &share.Choice, &share.CandidateID, &share.Votes); err != nil {
+			return Turnout{}, err
+		}
+		if i, ok := index[constituencyID]; ok {
+			turnout.Constituencies[i].Shares = append(turnout.Constituencies[i].Shares, share)
+		}
+	}
+	return turnout, shareRows.Err()
+}
+
+// readResults reads the result lines of every constituency of a tallied election
+func readResults(q ballot.Querier, electionID, version int) (Results, error) {
+	results := Results{ElectionID: electionID, TallyVersion: version, Constituencies: []ConstituencyResult{}}
+	query := `
+        SELECT ec.constituency_id, er.choice, er.candidate_id, er.total_votes, er.elected
+        FROM election_constituencies ec
+        LEFT JOIN election_results er ON er.election_id = ec.election_id AND er.constituency_id = ec.constituency_id
+        WHERE ec.election_id = $1
+        ORDER BY ec.constituency_id, er.candidate_id NULLS LAST
+    `
+	rows, err := q.Query(query, electionID)
+	if err != nil {
+		return Results{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var constituencyID int
+		var choice sql.NullString
+		var line tally.Line
+		var votes sql.NullInt64
+		var elected sql.NullBool
+		if err := rows.Scan(&constituencyID, &choice, &line.CandidateID, &votes, &elected); err != nil {
+			return Results{}, err
+		}
+		last := len(results.Constituencies) - 1
+		if last < 0 || results.Constituencies[last].ConstituencyID != constituencyID {
+			results.Constituencies = append(results.Constituencies, ConstituencyResult{ConstituencyID: constituencyID, Lines: []tally.Line{}})
+			last++
+		}
+		// A constituency without result lines comes back as one row of NULLs
+		if !choice.Valid {
+			continue
+		}
+		line.Choice = choice.String
+		line.Votes = int(votes.Int64)
+		line.Elected = elected.Bool
+		results.Constituencies[last].Lines = append(results.Constituencies[last].Lines, line)
+	}
+	return results, rows.Err()
+}
@@ -8,7 +8,12 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/joho/godotenv"
 
+	"github.com/Haste007/E-Voting/Backend/bulletin"
+	"github.com/Haste007/E-Voting/Backend/handlers"
+	"github.com/Haste007/E-Voting/Backend/live"
 	"github.com/Haste007/E-Voting/Backend/routes"
+	"github.com/Haste007/E-Voting/Backend/scheduler"
+	"github.com/Haste007/E-Voting/Backend/trustees"
 	"github.com/Haste007/E-Voting/Backend/utils"
 )
 
@@ -31,6 +36,36 @@ func main() {
 		log.Fatalf("Failed to connect to the database: %v", err)
 	}
 
+	// Load the voter pseudonym keys and record their versions
+	if err := utils.LoadVoterKeys(); err != nil {
+		log.Fatalf("Failed to load voter keys: %v", err)
+	}
+
+	// Make sure at least one super admin can log in
+	if err := utils.SeedSuperAdmin(); err != nil {
+		log.Fatalf("Failed to seed the admin account: %v", err)
+	}
+
+	// Publish bulletin board roots periodically
+	if err := bulletin.StartRootPublisher(); err != nil {
+		log.Fatalf("Failed to start the bulletin board publisher: %v", err)
+	}
+
+	// Open and close polls at their scheduled times
+	if err := scheduler.Start(handlers.OpenElection, handlers.CloseElection); err != nil {
+		log.Fatalf("Failed to start the poll scheduler: %v", err)
+	}
+
+	// Publish trustee election keys once their complaint windows end
+	if err := trustees.Start(); err != nil {
+		log.Fatalf("Failed to start the key ceremony completer: %v", err)
+	}
+
+	// Refresh live turnout for the elections being watched
+	if err := live.Start(); err != nil {
+		log.Fatalf("Failed to start live turnout: %v", err)
+	}
+
 	// Serve static files (frontend)
 	// app.Static("/", "./public")
 	app.Static("/images", "./images")
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/Haste007/E-Voting/Backend/models"
+	"github.com/Haste007/E-Voting/Backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminLocalsKey is the fiber.Ctx locals key holding the *utils.AdminClaims of an authenticated admin
+const AdminLocalsKey = "admin"
+
+// AnyAdmin lists the admin roles allowed on read-only admin routes. Trustee
+// accounts only take part in key ceremonies and are left out.
+var AnyAdmin = []string{models.RoleSuperAdmin, models.RoleElectionOfficer, models.RoleRegistrar, models.RoleAuditor}
+
+// RequireAdmin returns a handler that only lets through admins holding one of the given roles.
+// Super admins are always allowed. The role is re-read from the admins table so that
+// deleted accounts and role changes take effect before the token expires.
+func RequireAdmin(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tokenString := bearerToken(c)
+		if tokenString == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing admin token"})
+		}
+
+		claims, err := utils.ParseAdminToken(tokenString)
+		if err != nil {
+			log.Println("Rejected admin token:", err)
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired admin token"})
+		}
+
+		var role string
+		query := "SELECT role FROM admins WHERE id = $1"
+		if err := utils.DB.QueryRow(query, claims.AdminID).Scan(&role); err == sql.ErrNoRows {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Admin account no longer exists"})
+		} else if err != nil {
+			log.Println("Error fetching admin role:", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to verify admin"})
+		}
+		claims.Role = role
+
+		if !hasRole(role, roles) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Insufficient permissions"})
+		}
+
+		c.Locals(AdminLocalsKey, claims)
+		return c.Next()
+	}
+}
+
+func hasRole(role string, allowed []string) bool {
+	if role == models.RoleSuperAdmin {
+		return true
+	}
+	for _, r := range allowed {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"log"
+	"strings"
+
+	"github.com/Haste007/E-Voting/Backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// VoterLocalsKey is the fiber.Ctx locals key holding the *utils.VoterClaims of an authenticated voter
+const VoterLocalsKey = "voter"
+
+// RequireVoter rejects requests that do not carry a valid voter session token
+func RequireVoter(c *fiber.Ctx) error {
+	tokenString := bearerToken(c)
+	if tokenString == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Missing voter token"})
+	}
+
+	claims, err := utils.ParseVoterToken(tokenString)
+	if err != nil {
+		log.Println("Rejected voter token:", err)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired voter token"})
+	}
+
+	c.Locals(VoterLocalsKey, claims)
+	return c.Next()
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header
+func bearerToken(c *fiber.Ctx) string {
+	header := c.Get(fiber.HeaderAuthorization)
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(header[7:])
+}
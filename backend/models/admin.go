@@ -0,0 +1,31 @@
+package models
+
+// Admin roles, stored in admins.role
+const (
+	RoleSuperAdmin       = "super_admin"       // Full access, including admin account management
+	RoleElectionOfficer  = "election_officer"  // Creates, runs and closes elections
+	RoleRegistrar        = "registrar"         // Registers citizens and parties
+	RoleAuditor          = "auditor"           // Read-only access to admin data
+	RoleTrustee          = "trustee"           // Holds a decryption key share for encrypted elections
+	RoleReturningOfficer = "returning_officer" // Signs the results of the constituencies assigned to them
+)
+
+// AdminRoles lists every valid admin role
+var AdminRoles = []string{RoleSuperAdmin, RoleElectionOfficer, RoleRegistrar, RoleAuditor, RoleTrustee, RoleReturningOfficer}
+
+type Admin struct {
+	ID        int    `json:"id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`       // One of AdminRoles
+	CreatedAt string `json:"created_at"` // Timestamp the account was created
+}
+
+// IsValidAdminRole reports whether role is one of AdminRoles
+func IsValidAdminRole(role string) bool {
+	for _, r := range AdminRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,247 @@
+// Package polling pauses, resumes and extends voting while an election is
+// open, either nationwide or in single constituencies and districts. Every
+// action needs a reason and is recorded in polling_actions with the admin who
+// took it.
+//
+// A nationwide pause is the election's paused state. Pauses of constituencies
+// and districts are rows in polling_pauses, and a constituency whose closing
+// time is extended has a row in polling_extensions; the election stays open
+// until the last of these closing times.
+package polling
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/Haste007/E-Voting/Backend/ballot"
+	"github.com/Haste007/E-Voting/Backend/lifecycle"
+	"github.com/Haste007/E-Voting/Backend/scheduler"
+)
+
+// Actions recorded in polling_actions
+const (
+	ActionPause  = "pause"
+	ActionResume = "resume"
+	ActionExtend = "extend"
+)
+
+// Scopes of an action
+const (
+	ScopeNationwide   = "nationwide"
+	ScopeConstituency = "constituency"
+	ScopeDistrict     = "district"
+)
+
+// Error codes returned when an action or a ballot is refused
+const (
+	CodeAreaNotInElection  = "AREA_NOT_IN_ELECTION"
+	CodeAlreadyPaused      = "ALREADY_PAUSED"
+	CodeNotPaused          = "NOT_PAUSED"
+	CodeNoClosingTime      = "NO_CLOSING_TIME"
+	CodeInvalidClosingTime = "INVALID_CLOSING_TIME"
+	CodeClosingTimeEarlier = "CLOSING_TIME_NOT_LATER"
+	CodeDistrictExtension  = "DISTRICT_EXTENSION_NOT_SUPPORTED"
+	CodePollingPaused      = "POLLING_PAUSED"
+	CodePollingClosed      = "POLLING_CLOSED"
+)
+
+// Area is where an action applies. With no constituencies and no districts it
+// applies nationwide.
+type Area struct {
+	ConstituencyIDs []int `json:"constituencyIds"`
+	DistrictIDs     []int `json:"districtIds"`
+}
+
+// Nationwide reports whether the area is the whole election
+func (a Area) Nationwide() bool {
+	return len(a.ConstituencyIDs) == 0 && len(a.DistrictIDs) == 0
+}
+
+// Pause stops voting in area. A nationwide pause moves the election to paused;
+// constituencies and districts may be paused while it is open or paused.
+func Pause(tx *sql.Tx, electionID int, area Area, reason, actor string) error {
+	if area.Nationwide() {
+		if err := lifecycle.Transition(tx, electionID, lifecycle.StatusPaused, actor); err != nil {
+			return err
+		}
+		return record(tx, electionID, ActionPause, ScopeNationwide, nil, nil, nil, reason, actor)
+	}
+
+	// The row lock waits for ballots being cast, so none is accepted after the pause
+	if _, err := lifecycle.Require(tx, electionID, lifecycle.OpAdjustPolling, "FOR UPDATE"); err != nil {
+		return err
+	}
+	return eachArea(tx, electionID, area, func(scope string, constituencyID, districtID interface{}) error {
+		query := `
+            INSERT INTO polling_pauses (election_id, constituency_id, district_id, reason, paused_by)
+            VALUES ($1, $2, $3, $4, $5)
+            ON CONFLICT DO NOTHING
+        `
+		result, err := tx.Exec(query, electionID, constituencyID, districtID, reason, actor)
+		if err != nil {
+			return err
+		}
+		if rows, err := result.RowsAffected(); err != nil {
+			return err
+		} else if rows == 0 {
+			return &ballot.ValidationError{Code: CodeAlreadyPaused, Message: "Voting is already paused in this " + scope, Status: http.StatusConflict}
+		}
+		return record(tx, electionID, ActionPause, scope, constituencyID, districtID, nil, reason, actor)
+	})
+}
+
+// Resume lifts a pause of area. Resuming nationwide reopens a paused election
+// but leaves the pauses of single constituencies and districts in place.
+func Resume(tx *sql.Tx, electionID int, area Area, reason, actor string) error {
+	if area.Nationwide() {
+		if err := lifecycle.Transition(tx, electionID, lifecycle.StatusOpen, actor); err != nil {
+			return err
+		}
+		return record(tx, electionID, ActionResume, ScopeNationwide, nil, nil, nil, reason, actor)
+	}
+
+	if _, err := lifecycle.Require(tx, electionID, lifecycle.OpAdjustPolling, "FOR UPDATE"); err != nil {
+		return err
+	}
+	return eachArea(tx, electionID, area, func(scope string, constituencyID, districtID interface{}) error {
+		query := `
+            DELETE FROM polling_pauses
+            WHERE election_id = $1 AND (constituency_id = $2 OR district_id = $3)
+        `
+		result, err := tx.Exec(query, electionID, constituencyID, districtID)
+		if err != nil {
+			return err
+		}
+		if rows, err := result.RowsAffected(); err != nil {
+			return err
+		} else if rows == 0 {
+			return &ballot.ValidationError{Code: CodeNotPaused, Message: "Voting is not paused in this " + scope, Status: http.StatusConflict}
+		}
+		return record(tx, electionID, ActionResume, scope, constituencyID, districtID, nil, reason, actor)
+	})
+}
+
+// Extend moves the closing time of the constituencies of area, or of the whole
+// election, to value, which is read by scheduler.ParseTime in the election's
+// time zone. Only elections with a scheduled closing time can be extended, and
+// the new time must be later than the current one. Districts cannot be
+// extended on their own.
+func Extend(tx *sql.Tx, electionID int, area Area, value, reason, actor string) error {
+	if len(area.DistrictIDs) > 0 {
+		return &ballot.ValidationError{Code: CodeDistrictExtension, Message: "Polling hours are extended per constituency, not per district", Status: http.StatusUnprocessableEntity}
+	}
+	if _, err := lifecycle.Require(tx, electionID, lifecycle.OpAdjustPolling, "FOR UPDATE"); err != nil {
+		return err
+	}
+
+	var current sql.NullTime
+	var zone string
+	if err := tx.QueryRow("SELECT closes_at, time_zone FROM elections WHERE id = $1", electionID).Scan(&current, &zone); err != nil {
+		return err
+	}
+	closesAt, err := scheduler.ParseTime(value, zone)
+	if err != nil {
+		return &ballot.ValidationError{Code: CodeInvalidClosingTime, Message: "Closing time: " + err.Error(), Status: http.StatusBadRequest}
+	}
+	if !current.Valid {
+		return &ballot.ValidationError{Code: CodeNoClosingTime, Message: "Election has no scheduled closing time; end it by hand instead", Status: http.StatusConflict}
+	}
+	notLater := &ballot.ValidationError{Code: CodeClosingTimeEarlier, Message: "New closing time must be later than the current one and in the future", Status: http.StatusUnprocessableEntity}
+	if !closesAt.After(time.Now()) {
+		return notLater
+	}
+
+	if area.Nationwide() {
+		if !closesAt.After(current.Time) {
+			return notLater
+		}
+		// Extensions only delay a closing time, so an approved one stays approved
+		extendQuery := `
+            UPDATE elections
+            SET closes_at = $1,
+                approved_closes_at = CASE WHEN approved_closes_at = closes_at THEN $1 ELSE approved_closes_at END
+            WHERE id = $2
+        `
+		if _, err := tx.Exec(extendQuery, closesAt, electionID); err != nil {
+			return err
+		}
+		return record(tx, electionID, ActionExtend, ScopeNationwide, nil, nil, closesAt, reason, actor)
+	}
+
+	return eachArea(tx, electionID, area, func(scope string, constituencyID, _ interface{}) error {
+		var later bool
+		laterQuery := `
+            SELECT $3 > GREATEST(e.closes_at, x.closes_at)
+            FROM elections e
+            LEFT JOIN polling_extensions x ON x.election_id = e.id AND x.constituency_id = $2
+            WHERE e.id = $1
+        `
+		if err := tx.QueryRow(laterQuery, electionID, constituencyID, closesAt).Scan(&later); err != nil {
+			return err
+		}
+		if !later {
+			return notLater
+		}
+
+		query := `
+            INSERT INTO polling_extensions (election_id, constituency_id, closes_at)
+            VALUES ($1, $2, $3)
+            ON CONFLICT (election_id, constituency_id) DO UPDATE SET closes_at = EXCLUDED.closes_at
+        `
+		if _, err := tx.Exec(query, electionID, constituencyID, closesAt); err != nil {
+			return err
+		}
+		return record(tx, electionID, ActionExtend, scope, constituencyID, nil, closesAt, reason, actor)
+	})
+}
+
+// eachArea checks that every constituency and district of area belongs to the
+// election and calls apply for each, with the other ID nil
+func eachArea(tx *sql.Tx, electionID int, area Area, apply func(scope string, constituencyID, districtID interface{}) error) error {
+	for _, constituencyID := range area.ConstituencyIDs {
+		var ok bool
+		query := "SELECT EXISTS (SELECT 1 FROM election_constituencies WHERE election_id = $1 AND constituency_id = $2)"
+		if err := tx.QueryRow(query, electionID, constituencyID).Scan(&ok); err != nil {
+			return err
+		}
+		if !ok {
+			return &ballot.ValidationError{Code: CodeAreaNotInElection, Message: "Constituency is not part of this election", Status: http.StatusUnprocessableEntity}
+		}
+		if err := apply(ScopeConstituency, constituencyID, nil); err != nil {
+			return err
+		}
+	}
+
+	for _, districtID := range area.DistrictIDs {
+		var ok bool
+		query := `
+            SELECT EXISTS (
+                SELECT 1
+                FROM election_constituencies ec
+                JOIN constituency_districts cd ON cd.constituency_id = ec.constituency_id
+                WHERE ec.election_id = $1 AND cd.district_id = $2
+            )
+        `
+		if err := tx.QueryRow(query, electionID, districtID).Scan(&ok); err != nil {
+			return err
+		}
+		if !ok {
+			return &ballot.ValidationError{Code: CodeAreaNotInElection, Message: "District is not part of this election", Status: http.StatusUnprocessableEntity}
+		}
+		if err := apply(ScopeDistrict, nil, districtID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record adds an action to the polling_actions audit log
+func record(tx *sql.Tx, electionID int, action, scope string, constituencyID, districtID, closesAt interface{}, reason, actor string) error {
+	query := `
+        INSERT INTO polling_actions (election_id, action, scope, constituency_id, district_id, closes_at, reason, actor)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    `
+	_, err := tx.Exec(query, electionID, action, scope, constituencyID, districtID, closesAt, reason, actor)
+	return err
+}
@@ -0,0 +1,201 @@
+package polling
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/Haste007/E-Voting/Backend/ballot"
+	"github.com/Haste007/E-Voting/Backend/scheduler"
+)
+
+var (
+	errPaused = &ballot.ValidationError{Code: CodePollingPaused, Message: "Voting is paused in this constituency or district", Status: http.StatusConflict}
+	errClosed = &ballot.ValidationError{Code: CodePollingClosed, Message: "Polls have closed in this constituency", Status: http.StatusConflict}
+)
+
+// Check refuses a ballot for a constituency, cast by a voter of a district,
+// when either is paused or the constituency's closing time has passed. The
+// nationwide state is checked by ballot.Validate.
+func Check(q ballot.Querier, electionID, constituencyID, districtID int) error {
+	var paused, closed bool
+	query := `
+        SELECT
+            EXISTS (
+                SELECT 1 FROM polling_pauses
+                WHERE election_id = $1 AND (constituency_id = $2 OR district_id = $3)
+            ),
+            COALESCE(GREATEST(e.closes_at, x.closes_at) <= NOW(), FALSE)
+        FROM elections e
+        LEFT JOIN polling_extensions x ON x.election_id = e.id AND x.constituency_id = $2
+        WHERE e.id = $1
+    `
+	if err := q.QueryRow(query, electionID, constituencyID, districtID).Scan(&paused, &closed); err != nil {
+		return err
+	}
+	return refusal(paused, closed)
+}
+
+// CheckDistrict is Check for ballot questions, which belong to a district
+// rather than a constituency: a pause of any of the election's constituencies
+// covering the district applies, and so does the latest of their closing times.
+func CheckDistrict(q ballot.Querier, electionID, districtID int) error {
+	var paused, closed bool
+	query := `
+        WITH covering AS (
+            SELECT cd.constituency_id
+            FROM constituency_districts cd
+            JOIN election_constituencies ec ON ec.constituency_id = cd.constituency_id
+            WHERE ec.election_id = $1 AND cd.district_id = $2
+        )
+        SELECT
+            EXISTS (
+                SELECT 1 FROM polling_pauses
+                WHERE election_id = $1 AND (district_id = $2 OR constituency_id IN (SELECT constituency_id FROM covering))
+            ),
+            COALESCE(GREATEST(e.closes_at, (
+                SELECT MAX(closes_at) FROM polling_extensions
+                WHERE election_id = $1 AND constituency_id IN (SELECT constituency_id FROM covering)
+            )) <= NOW(), FALSE)
+        FROM elections e
+        WHERE e.id = $1
+    `
+	if err := q.QueryRow(query, electionID, districtID).Scan(&paused, &closed); err != nil {
+		return err
+	}
+	return refusal(paused, closed)
+}
+
+// refusal turns the paused and closed flags into a validation error, or nil
+func refusal(paused, closed bool) error {
+	switch {
+	case closed:
+		return errClosed
+	case paused:
+		return errPaused
+	}
+	return nil
+}
+
+// ConstituencyStatus says whether one of an election's constituencies is taking votes
+type ConstituencyStatus struct {
+	ConstituencyID  int     `json:"constituency_id"`
+	Name            string  `json:"name"`
+	Tier            string  `json:"tier"`
+	Paused          bool    `json:"paused"`           // The constituency itself is paused
+	PausedDistricts []int   `json:"paused_districts"` // Districts of the constituency that are paused
+	ClosesAt        *string `json:"closes_at"`        // RFC 3339 in the election's time zone; null when closed by hand
+	Extended        bool    `json:"extended"`         // ClosesAt is later than the election's closing time
+	AcceptingVotes  bool    `json:"accepting_votes"`  // The election is open, and the constituency is neither paused nor past its closing time
+}
+
+// Constituencies returns the polling state of each of an election's constituencies
+func Constituencies(q ballot.Querier, electionID int) ([]ConstituencyStatus, error) {
+	query := `
+        SELECT
+            c.id, c.name, c.tier,
+            EXISTS (SELECT 1 FROM polling_pauses p WHERE p.election_id = e.id AND p.constituency_id = c.id),
+            GREATEST(e.closes_at, x.closes_at),
+            COALESCE(x.closes_at > e.closes_at, FALSE),
+            e.status = 'open' AND COALESCE(GREATEST(e.closes_at, x.closes_at) > NOW(), TRUE),
+            e.time_zone
+        FROM election_constituencies ec
+        JOIN elections e ON e.id = ec.election_id
+        JOIN constituencies c ON c.id = ec.constituency_id
+        LEFT JOIN polling_extensions x ON x.election_id = e.id AND x.constituency_id = c.id
+        WHERE e.id = $1
+        ORDER BY c.tier, c.id
+    `
+	rows, err := q.Query(query, electionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var statuses []ConstituencyStatus
+	index := make(map[int]int)
+	for rows.Next() {
+		var status ConstituencyStatus
+		var closesAt sql.NullTime
+		var zone string
+		if err := rows.Scan(&status.ConstituencyID, &status.Name, &status.Tier, &status.Paused, &closesAt, &status.Extended, &status.AcceptingVotes, &zone); err != nil {
+			return nil, err
+		}
+		status.ClosesAt = scheduler.Format(closesAt, zone)
+		status.PausedDistricts = []int{}
+		if status.Paused {
+			status.AcceptingVotes = false
+		}
+		index[status.ConstituencyID] = len(statuses)
+		statuses = append(statuses, status)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	districtQuery := `
+        SELECT cd.constituency_id, p.district_id
+        FROM polling_pauses p
+        JOIN constituency_districts cd ON cd.district_id = p.district_id
+        WHERE p.election_id = $1
+        ORDER BY p.district_id
+    `
+	districtRows, err := q.Query(districtQuery, electionID)
+	if err != nil {
+		return nil, err
+	}
+	defer districtRows.Close()
+
+	for districtRows.Next() {
+		var constituencyID, districtID int
+		if err := districtRows.Scan(&constituencyID, &districtID); err != nil {
+			return nil, err
+		}
+		// Other elections' constituencies can share the district
+		if i, ok := index[constituencyID]; ok {
+			statuses[i].PausedDistricts = append(statuses[i].PausedDistricts, districtID)
+		}
+	}
+	return statuses, districtRows.Err()
+}
+
+// Action is one entry of the polling_actions audit log
+type Action struct {
+	ID             int     `json:"id"`
+	Action         string  `json:"action"`
+	Scope          string  `json:"scope"`
+	ConstituencyID *int    `json:"constituency_id"`
+	DistrictID     *int    `json:"district_id"`
+	ClosesAt       *string `json:"closes_at"` // New closing time of an extension
+	Reason         string  `json:"reason"`
+	Actor          string  `json:"actor"`
+	ActedAt        string  `json:"acted_at"`
+}
+
+// Actions lists an election's pauses, resumptions and extensions, oldest first
+func Actions(q ballot.Querier, electionID int) ([]Action, error) {
+	query := `
+        SELECT a.id, a.action, a.scope, a.constituency_id, a.district_id, a.closes_at, a.reason, a.actor, a.acted_at, e.time_zone
+        FROM polling_actions a
+        JOIN elections e ON e.id = a.election_id
+        WHERE a.election_id = $1
+        ORDER BY a.acted_at, a.id
+    `
+	rows, err := q.Query(query, electionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	actions := []Action{}
+	for rows.Next() {
+		var action Action
+		var closesAt sql.NullTime
+		var zone string
+		if err := rows.Scan(&action.ID, &action.Action, &action.Scope, &action.ConstituencyID, &action.DistrictID, &closesAt, &action.Reason, &action.Actor, &action.ActedAt, &zone); err != nil {
+			return nil, err
+		}
+		action.ClosesAt = scheduler.Format(closesAt, zone)
+		actions = append(actions, action)
+	}
+	return actions, rows.Err()
+}
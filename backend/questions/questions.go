@@ -0,0 +1,203 @@
+// Package questions handles ballot questions: referendums and other yes/no or
+// multiple-choice questions put to voters alongside the candidate races of an
+// election.
+//
+// A question is asked nationwide or only in selected districts, carries its
+// text in several languages and is answered at most once per voter. Answers
+// are published on the election's bulletin board and tallied when polls close;
+// Decide turns the tally into an outcome under the question's pass rule.
+package questions
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/Haste007/E-Voting/Backend/ballot"
+	"github.com/Haste007/E-Voting/Backend/lifecycle"
+)
+
+// Question kinds, stored in ballot_questions.kind
+const (
+	KindYesNo          = "yes_no"          // Two options, Yes then No
+	KindMultipleChoice = "multiple_choice" // Two or more options
+)
+
+// Pass rules, stored in ballot_questions.pass_rule
+const (
+	RuleMajority      = "majority"      // More than half of the answers
+	RuleSupermajority = "supermajority" // At least the question's supermajority percentage of the answers
+)
+
+// Error codes returned to the client when an answer is rejected
+const (
+	CodeQuestionNotFound = "QUESTION_NOT_FOUND"
+	CodeNotAskedHere     = "QUESTION_NOT_ASKED_IN_DISTRICT"
+	CodeInvalidOption    = "INVALID_OPTION"
+)
+
+// Text holds a question or option text by language code, e.g. "en" or "ur"
+type Text map[string]string
+
+// String returns the English text, or the first language's when there is none
+func (t Text) String() string {
+	if text, ok := t["en"]; ok {
+		return text
+	}
+	languages := make([]string, 0, len(t))
+	for language := range t {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	if len(languages) == 0 {
+		return ""
+	}
+	return t[languages[0]]
+}
+
+// Option is one answer a question offers
+type Option struct {
+	OptionID int  `json:"option_id"`
+	Position int  `json:"position"`
+	Label    Text `json:"label"`
+}
+
+// Question is a ballot question with its options in ballot order
+type Question struct {
+	QuestionID    int      `json:"question_id"`
+	Kind          string   `json:"kind"`
+	Text          Text     `json:"text"`
+	Nationwide    bool     `json:"nationwide"`
+	PassRule      string   `json:"pass_rule"`
+	Supermajority *float64 `json:"supermajority,omitempty"` // Percent of answers, for RuleSupermajority
+	MinTurnout    float64  `json:"min_turnout"`             // Percent of eligible voters
+	Options       []Option `json:"options"`
+}
+
+// questionColumns are the ballot_questions columns scanQuestion reads
+const questionColumns = "q.id, q.kind, q.text, q.nationwide, q.pass_rule, q.supermajority, q.min_turnout"
+
+// scanQuestion reads one row of questionColumns
+func scanQuestion(rows *sql.Rows) (Question, error) {
+	var question Question
+	var text []byte
+	if err := rows.Scan(&question.QuestionID, &question.Kind, &text, &question.Nationwide, &question.PassRule, &question.Supermajority, &question.MinTurnout); err != nil {
+		return question, err
+	}
+	return question, json.Unmarshal(text, &question.Text)
+}
+
+// load runs a query over questionColumns and attaches each question's options
+func load(q ballot.Querier, query string, args ...interface{}) ([]Question, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	questions := []Question{}
+	for rows.Next() {
+		question, err := scanQuestion(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		questions = append(questions, question)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range questions {
+		if questions[i].Options, err = options(q, questions[i].QuestionID); err != nil {
+			return nil, err
+		}
+	}
+	return questions, nil
+}
+
+// options returns a question's options in ballot order
+func options(q ballot.Querier, questionID int) ([]Option, error) {
+	rows, err := q.Query("SELECT id, position, label FROM question_options WHERE question_id = $1 ORDER BY position", questionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	options := []Option{}
+	for rows.Next() {
+		var option Option
+		var label []byte
+		if err := rows.Scan(&option.OptionID, &option.Position, &label); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(label, &option.Label); err != nil {
+			return nil, err
+		}
+		options = append(options, option)
+	}
+	return options, rows.Err()
+}
+
+// ForElection returns every question of an election
+func ForElection(q ballot.Querier, electionID interface{}) ([]Question, error) {
+	return load(q, "SELECT "+questionColumns+" FROM ballot_questions q WHERE q.election_id = $1 ORDER BY q.id", electionID)
+}
+
+// ForDistrict returns the questions of an election that voters of a district may answer
+func ForDistrict(q ballot.Querier, electionID, districtID interface{}) ([]Question, error) {
+	query := `
+        SELECT ` + questionColumns + `
+        FROM ballot_questions q
+        WHERE q.election_id = $1
+          AND (q.nationwide OR EXISTS (
+              SELECT 1 FROM question_districts qd WHERE qd.question_id = q.id AND qd.district_id = $2
+          ))
+        ORDER BY q.id
+    `
+	return load(q, query, electionID, districtID)
+}
+
+// ValidateAnswer checks that an election is open, that the question belongs
+// to it and is asked in the voter's district, and that the option is one of
+// the question's. The election row is read FOR SHARE, so when q is a
+// transaction the election cannot close until the answer is committed.
+func ValidateAnswer(q ballot.Querier, electionID, questionID, optionID, districtID int) error {
+	status, err := lifecycle.CurrentStatus(q, electionID, "FOR SHARE")
+	if errors.Is(err, lifecycle.ErrElectionNotFound) {
+		return &ballot.ValidationError{Code: ballot.CodeElectionNotFound, Message: "Election not found", Status: http.StatusNotFound}
+	} else if err != nil {
+		return err
+	}
+	if !lifecycle.Allows(lifecycle.OpVote, status) {
+		return &ballot.ValidationError{Code: ballot.CodeElectionNotOpen, Message: "Election is not open for voting", Status: http.StatusConflict}
+	}
+
+	var asked sql.NullBool
+	query := `
+        SELECT q.nationwide OR EXISTS (
+            SELECT 1 FROM question_districts qd WHERE qd.question_id = q.id AND qd.district_id = $3
+        )
+        FROM ballot_questions q
+        WHERE q.id = $1 AND q.election_id = $2
+    `
+	err = q.QueryRow(query, questionID, electionID, districtID).Scan(&asked)
+	if err == sql.ErrNoRows {
+		return &ballot.ValidationError{Code: CodeQuestionNotFound, Message: "Question is not part of this election", Status: http.StatusNotFound}
+	} else if err != nil {
+		return err
+	}
+	if !asked.Bool {
+		return &ballot.ValidationError{Code: CodeNotAskedHere, Message: "Question is not asked in the voter's district", Status: http.StatusForbidden}
+	}
+
+	var offered bool
+	if err := q.QueryRow("SELECT EXISTS (SELECT 1 FROM question_options WHERE id = $1 AND question_id = $2)", optionID, questionID).Scan(&offered); err != nil {
+		return err
+	}
+	if !offered {
+		return &ballot.ValidationError{Code: CodeInvalidOption, Message: "Option is not one of the question's", Status: http.StatusUnprocessableEntity}
+	}
+	return nil
+}
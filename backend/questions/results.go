@@ -0,0 +1,195 @@
+package questions
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/Haste007/E-Voting/Backend/ballot"
+)
+
+// OptionResult is the tally of one option
+type OptionResult struct {
+	Option
+	Votes int `json:"votes"`
+}
+
+// Result is the outcome of a question
+type Result struct {
+	QuestionID     int            `json:"question_id"`
+	Kind           string         `json:"kind"`
+	Text           Text           `json:"text"`
+	PassRule       string         `json:"pass_rule"`
+	Supermajority  *float64       `json:"supermajority,omitempty"`
+	MinTurnout     float64        `json:"min_turnout"`
+	Options        []OptionResult `json:"options"`
+	Answers        int            `json:"answers"`
+	EligibleVoters int            `json:"eligible_voters"`
+	TurnoutMet     bool           `json:"turnout_met"`
+	Carried        int            `json:"carried,omitempty"` // Option that met the pass rule
+	Passed         bool           `json:"passed"`
+	Outcome        string         `json:"outcome"` // Plain-language explanation of the decision
+}
+
+// hundredths converts a percentage stored with two decimals to an exact integer
+func hundredths(percent float64) int64 {
+	return int64(math.Round(percent * 100))
+}
+
+// Decide applies a question's pass rule to its tally. votes is keyed by
+// option ID and eligible is the number of voters the question was put to.
+//
+// An option carries when it wins more than half of the answers, or at least
+// the supermajority percentage of them, and the answers reach the minimum
+// turnout. A yes/no question passes when Yes carries; a multiple-choice
+// question passes when any option carries. Percentages are compared in
+// hundredths so the thresholds are exact.
+func Decide(question Question, votes map[int]int, eligible int) *Result {
+	result := &Result{
+		QuestionID:     question.QuestionID,
+		Kind:           question.Kind,
+		Text:           question.Text,
+		PassRule:       question.PassRule,
+		Supermajority:  question.Supermajority,
+		MinTurnout:     question.MinTurnout,
+		Options:        make([]OptionResult, 0, len(question.Options)),
+		EligibleVoters: eligible,
+	}
+	for _, option := range question.Options {
+		result.Options = append(result.Options, OptionResult{Option: option, Votes: votes[option.OptionID]})
+		result.Answers += votes[option.OptionID]
+	}
+
+	answers := int64(result.Answers)
+	result.TurnoutMet = answers*10000 >= hundredths(question.MinTurnout)*int64(eligible)
+	if !result.TurnoutMet {
+		result.Outcome = fmt.Sprintf("Failed: %d of %d eligible voters answered, under the %.2f%% turnout required", result.Answers, eligible, question.MinTurnout)
+		return result
+	}
+
+	var carried string
+	for _, option := range result.Options {
+		count := int64(option.Votes)
+		var carries bool
+		if question.PassRule == RuleSupermajority && question.Supermajority != nil {
+			carries = count > 0 && count*10000 >= hundredths(*question.Supermajority)*answers
+		} else {
+			carries = count*2 > answers
+		}
+		if carries {
+			result.Carried = option.OptionID
+			carried = option.Label.String()
+			break
+		}
+	}
+
+	required := "a majority"
+	if question.PassRule == RuleSupermajority && question.Supermajority != nil {
+		required = fmt.Sprintf("%.2f%%", *question.Supermajority)
+	}
+	switch {
+	case result.Carried == 0:
+		result.Outcome = fmt.Sprintf("Failed: no option won %s of the %d answers", required, result.Answers)
+	case question.Kind == KindYesNo && result.Carried != question.Options[0].OptionID:
+		result.Outcome = fmt.Sprintf("Rejected: No won %s of the %d answers", required, result.Answers)
+	default:
+		result.Passed = true
+		result.Outcome = fmt.Sprintf("Passed: %q won %s of the %d answers", carried, required, result.Answers)
+	}
+	return result
+}
+
+// eligibleVoters counts the citizens a question was put to
+func eligibleVoters(tx *sql.Tx, question Question) (int, error) {
+	var count int
+	if question.Nationwide {
+		err := tx.QueryRow("SELECT COUNT(*) FROM citizens").Scan(&count)
+		return count, err
+	}
+	query := `
+        SELECT COUNT(*)
+        FROM citizens ci
+        JOIN question_districts qd ON qd.district_id = ci.district_id
+        WHERE qd.question_id = $1
+    `
+	err := tx.QueryRow(query, question.QuestionID).Scan(&count)
+	return count, err
+}
+
+// TallyElection counts the answers to every question of an election and
+// stores each outcome. Running it again replaces them.
+func TallyElection(tx *sql.Tx, electionID int) error {
+	questions, err := ForElection(tx, electionID)
+	if err != nil {
+		return err
+	}
+
+	for _, question := range questions {
+		rows, err := tx.Query("SELECT option_id, COUNT(*) FROM question_answers WHERE question_id = $1 GROUP BY option_id", question.QuestionID)
+		if err != nil {
+			return err
+		}
+		votes := make(map[int]int)
+		for rows.Next() {
+			var optionID, count int
+			if err := rows.Scan(&optionID, &count); err != nil {
+				rows.Close()
+				return err
+			}
+			votes[optionID] = count
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		eligible, err := eligibleVoters(tx, question)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(Decide(question, votes, eligible))
+		if err != nil {
+			return err
+		}
+		query := `
+            INSERT INTO question_results (question_id, result)
+            VALUES ($1, $2)
+            ON CONFLICT (question_id) DO UPDATE SET result = EXCLUDED.result, tallied_at = NOW()
+        `
+		if _, err := tx.Exec(query, question.QuestionID, string(data)); err != nil {
+			return fmt.Errorf("storing result of question %d: %w", question.QuestionID, err)
+		}
+	}
+	return nil
+}
+
+// Results returns the stored outcomes of an election's questions
+func Results(q ballot.Querier, electionID interface{}) ([]Result, error) {
+	query := `
+        SELECT qr.result
+        FROM question_results qr
+        JOIN ballot_questions q ON q.id = qr.question_id
+        WHERE q.election_id = $1
+        ORDER BY q.id
+    `
+	rows, err := q.Query(query, electionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []Result{}
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var result Result
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
@@ -2,46 +2,123 @@ package routes
 
 import (
 	"github.com/Haste007/E-Voting/Backend/handlers"
+	"github.com/Haste007/E-Voting/Backend/middleware"
+	"github.com/Haste007/E-Voting/Backend/models"
+	"github.com/Haste007/E-Voting/Backend/trustees"
 	"github.com/gofiber/fiber/v2"
 )
 
 func RegisterRoutes(app *fiber.App) {
+	// Every admin route declares the roles allowed to call it; super admins may call all of them
+	admin := middleware.RequireAdmin
+	anyAdmin := admin(middleware.AnyAdmin...)
+	registrar := admin(models.RoleRegistrar)
+	electionOfficer := admin(models.RoleElectionOfficer)
+	superAdmin := admin(models.RoleSuperAdmin)
+	auditor := admin(models.RoleAuditor)
+	trustee := admin(models.RoleTrustee)
+	returningOfficer := admin(models.RoleReturningOfficer)
+
 	// Citizen routes
-	app.Post("/api/citizens", handlers.CreateCitizen)
-	app.Get("/api/citizens/:nid", handlers.GetCitizen)       // Use NID instead of ID
-	app.Put("/api/citizens/:nid", handlers.UpdateCitizen)    // Use NID instead of ID
-	app.Delete("/api/citizens/:nid", handlers.DeleteCitizen) // Use NID instead of ID
-	app.Get("/api/citizens", handlers.GetAllCitizens)
-	app.Get("/api/get-unassigned-citizens", handlers.GetUnassignedCitizens)
+	app.Post("/api/citizens", registrar, handlers.CreateCitizen)
+	app.Get("/api/citizens/:nid", anyAdmin, handlers.GetCitizen)        // Use NID instead of ID
+	app.Put("/api/citizens/:nid", registrar, handlers.UpdateCitizen)    // Use NID instead of ID
+	app.Delete("/api/citizens/:nid", registrar, handlers.DeleteCitizen) // Use NID instead of ID
+	app.Get("/api/citizens", anyAdmin, handlers.GetAllCitizens)
+	app.Get("/api/get-unassigned-citizens", anyAdmin, handlers.GetUnassignedCitizens)
 
 	// Party routes
-	app.Post("/api/parties", handlers.CreateParty)
-	app.Get("/api/parties/:id", handlers.GetParty)
-	app.Put("/api/parties/:id", handlers.UpdateParty)
-	app.Delete("/api/parties/:id", handlers.DeleteParty)
-	app.Post("/api/parties/add-citizen", handlers.AddCitizenToParty)
-	app.Get("/api/parties", handlers.GetParties)
+	app.Post("/api/parties", registrar, handlers.CreateParty)
+	app.Get("/api/parties/:id", anyAdmin, handlers.GetParty)
+	app.Put("/api/parties/:id", registrar, handlers.UpdateParty)
+	app.Delete("/api/parties/:id", registrar, handlers.DeleteParty)
+	app.Post("/api/parties/add-citizen", registrar, handlers.AddCitizenToParty)
+	app.Get("/api/parties", anyAdmin, handlers.GetParties)
 
 	// Election routes
-	app.Post("/api/elections", handlers.CreateElection)
-	app.Get("/api/elections/:id", handlers.GetElection)
-	app.Get("/api/upcomming-elections", handlers.GetUpcomingElections)
-	app.Get("/api/past-elections", handlers.GetPastElections) // New route for past elections
-	app.Put("/api/elections/:id", handlers.UpdateElection)
-	app.Delete("/api/elections/:id", handlers.DeleteElection)
-	app.Post("/api/elections/:id/start", handlers.StartElection)
-	app.Post("/api/elections/:id/end", handlers.EndElection)
+	app.Post("/api/elections", electionOfficer, handlers.CreateElection)
+	app.Get("/api/elections/:id", anyAdmin, handlers.GetElection)
+	app.Get("/api/upcomming-elections", anyAdmin, handlers.GetUpcomingElections)
+	app.Get("/api/past-elections", handlers.GetPastElections) // Published results are public
+	app.Put("/api/elections/:id", electionOfficer, handlers.UpdateElection)
+	app.Delete("/api/elections/:id", electionOfficer, handlers.DeleteElection)
+	app.Post("/api/elections/:id/start", electionOfficer, handlers.StartElection)
+	app.Post("/api/elections/:id/end", electionOfficer, handlers.EndElection)
+	app.Post("/api/elections/:id/schedule", electionOfficer, handlers.ScheduleElection)
+	app.Post("/api/elections/:id/unschedule", electionOfficer, handlers.UnscheduleElection)
+	app.Post("/api/elections/:id/polling-hours", electionOfficer, handlers.ProposePollingHours) // Second admin approves the hours the scheduler acts on
+	app.Post("/api/elections/:id/pause", electionOfficer, handlers.PauseElection)
+	app.Post("/api/elections/:id/resume", electionOfficer, handlers.ResumeElection)
+	app.Post("/api/elections/:id/extend", electionOfficer, handlers.ExtendPolling)
+	app.Get("/api/elections/:id/polling-actions", anyAdmin, handlers.GetPollingActions)
+	app.Post("/api/elections/:id/certify", electionOfficer, handlers.CertifyElection)
+	app.Get("/api/elections/:id/history", anyAdmin, handlers.GetElectionHistory)
+	app.Post("/api/elections/:id/verify-participation", auditor, handlers.VerifyVoterParticipation)
+	app.Get("/api/elections/:id/encrypted-tallies", handlers.GetEncryptedTallies) // Public so anyone can check the decryption proofs
+	app.Get("/api/elections/:id/count-reports", handlers.GetCountReports)         // Round-by-round reports of ranked counts
+	app.Get("/api/elections/:id/seat-allocations", handlers.GetSeatAllocations)   // Party seat totals and reserved seat allocations
+	app.Post("/api/elections/:id/result-corrections", electionOfficer, handlers.ProposeResultCorrection)
+	app.Post("/api/elections/:id/tally", electionOfficer, handlers.RerunTally)
+	app.Post("/api/elections/:id/constituencies/:constituencyId/recount", electionOfficer, handlers.RecountConstituency)
+	app.Get("/api/elections/:id/tally-runs", anyAdmin, handlers.GetTallyRuns)
+	app.Get("/api/elections/:id/tally-runs/:version", anyAdmin, handlers.GetTallyRun)
+	app.Put("/api/elections/:id/constituencies/:constituencyId/returning-officer", electionOfficer, handlers.AssignReturningOfficer)
+	app.Get("/api/elections/:id/certifications", handlers.GetCertifications)                             // Public so anyone can see which results are signed
+	app.Get("/api/elections/:id/constituencies/:constituencyId/signed-result", handlers.GetSignedResult) // Signed result document for offline verification
+	app.Get("/api/elections/:id/live", handlers.AdminWhilePollsOpen(anyAdmin), handlers.StreamElection)  // Server-sent turnout, and results once tallied
+
+	// Two-person rule: start, end, delete, certify and result corrections wait for a second admin
+	app.Get("/api/approvals", anyAdmin, handlers.GetApprovalRequests)
+	app.Post("/api/approvals/:id/approve", electionOfficer, handlers.ApproveRequest)
+	app.Post("/api/approvals/:id/reject", electionOfficer, handlers.RejectRequest)
+
+	// Reserved seat pools and party lists
+	app.Post("/api/elections/:id/reserved-seat-pools", electionOfficer, handlers.CreateReservedSeatPool)
+	app.Get("/api/elections/:id/reserved-seat-pools", anyAdmin, handlers.GetReservedSeatPools)
+	app.Put("/api/elections/:id/reserved-seat-pools/:poolId/lists/:partyId", registrar, handlers.SubmitPartyList)
+
+	// Referendums and other ballot questions
+	app.Post("/api/elections/:id/questions", electionOfficer, handlers.CreateBallotQuestion)
+	app.Get("/api/elections/:id/questions", handlers.GetBallotQuestions) // Public so voters can read the questions in advance
+
+	// Candidate routes
+	app.Put("/api/candidates/:id/symbol", electionOfficer, handlers.UploadCandidateSymbol) // Ballot symbol of an independent candidate
+
+	// Trustee key ceremony
+	app.Get("/api/elections/:id/trustees", trustees.GetCeremony) // Public so anyone can check the joint key
+	app.Delete("/api/elections/:id/trustees/:index", electionOfficer, trustees.RemoveTrustee)
+	app.Post("/api/trustee/elections/:id/enroll", trustee, trustees.EnrollTrustee)
+	app.Post("/api/trustee/elections/:id/dealing", trustee, trustees.SubmitDealing)
+	app.Get("/api/trustee/elections/:id/shares", trustee, trustees.GetReceivedShares)
+	app.Post("/api/trustee/elections/:id/shares/:dealer/complaint", trustee, trustees.FileComplaint)
+	app.Post("/api/trustee/elections/:id/complaints/:complainant/reveal", trustee, trustees.RevealShare)
+	app.Post("/api/trustee/elections/:id/partial-decryptions", trustee, trustees.SubmitPartialDecryption)
+
+	// Returning officers sign constituency results
+	app.Get("/api/returning-officer/elections/:id/constituencies/:constituencyId/document", returningOfficer, handlers.GetResultDocument)
+	app.Post("/api/returning-officer/elections/:id/constituencies/:constituencyId/sign", returningOfficer, handlers.SignResult)
+
+	// Voter pseudonym key versions
+	app.Get("/api/pseudonym-keys", auditor, handlers.GetPseudonymKeys)
 
 	// Constituency routes
-	app.Post("/api/constituencies", handlers.CreateConstituency)
-	app.Get("/api/constituencies/:id", handlers.GetConstituency)
-	app.Put("/api/constituencies/:id", handlers.UpdateConstituency)
-	app.Delete("/api/constituencies/:id", handlers.DeleteConstituency)
+	app.Post("/api/constituencies", electionOfficer, handlers.CreateConstituency)
+	app.Get("/api/constituencies/:id", anyAdmin, handlers.GetConstituency)
+	app.Put("/api/constituencies/:id", electionOfficer, handlers.UpdateConstituency)
+	app.Delete("/api/constituencies/:id", electionOfficer, handlers.DeleteConstituency)
 
 	// Vote routes
-	app.Post("/api/votes", handlers.CastVote)
+	app.Post("/api/votes", middleware.RequireVoter, handlers.CastVote)                        // Voter identity comes from the session token
+	app.Post("/api/votes/questions", middleware.RequireVoter, handlers.AnswerQuestion)        // One answer per voter per ballot question
 	app.Get("/api/voting/ongoing-elections", handlers.GetOngoingElections)                    // Get all ongoing elections
 	app.Get("/api/voting/constituency/:electionId/:districtId", handlers.GetConstituencyData) // Get constituency data for a specific election and district
+	app.Get("/api/voting/elections/:id/encryption", handlers.GetElectionEncryption)           // Public key for client-side ballot encryption
+	app.Get("/api/voting/elections/:id/receipts/:code", handlers.LookupReceipt)               // Check that a tracking code was counted
+
+	// Public bulletin board of accepted ballots
+	app.Get("/api/bulletin/:id", handlers.GetBulletinBoard)
+	app.Get("/api/bulletin/:id/roots", handlers.GetBulletinRoots)
+	app.Get("/api/bulletin/:id/proof/:position", handlers.GetInclusionProof)
 
 	// Authentication route
 	app.Post("/api/authenticate", handlers.AuthenticateCitizen) // New route for authentication
@@ -49,4 +126,10 @@ func RegisterRoutes(app *fiber.App) {
 	// Register admin login route
 	app.Post("/api/admin/login", handlers.AdminLoginHandler)
 
+	// Admin account management
+	app.Post("/api/admin/accounts", superAdmin, handlers.CreateAdmin)
+	app.Get("/api/admin/accounts", superAdmin, handlers.GetAdmins)
+	app.Delete("/api/admin/accounts/:id", superAdmin, handlers.DeleteAdmin)
+	app.Put("/api/admin/accounts/:id/signing-key", superAdmin, handlers.RegisterSigningKey) // Ed25519 key of a returning officer
+
 }
@@ -0,0 +1,148 @@
+// Package scheduler opens and closes polls at each election's opens_at and
+// closes_at times, and runs the tally at close.
+//
+// All schedule state lives in the elections table, so a restarted process
+// simply catches up on any opening or closing it missed. Each due election is
+// handled in its own transaction under a transaction-level advisory lock, and
+// the due condition is checked again under the election's row lock, so when
+// several backend replicas run the scheduler only one of them acts and the
+// others find the election already moved on.
+package scheduler
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Haste007/E-Voting/Backend/utils"
+)
+
+// Actor is recorded in election_status_history for scheduled transitions
+const Actor = "system"
+
+// DefaultInterval is how often due elections are looked for unless
+// SCHEDULER_INTERVAL is set
+const DefaultInterval = 30 * time.Second
+
+// Conditions that make an election due; only scheduled elections open on
+// time, so a draft whose opening time passes stays closed, and elections stay
+// open while any constituency's polling hours are extended. Polls are only
+// opened and closed at hours a second admin approved, so a timetable edited
+// since its approval is left alone.
+const (
+	dueToOpen = `status = 'scheduled' AND opens_at <= NOW()
+        AND opens_at = approved_opens_at AND closes_at IS NOT DISTINCT FROM approved_closes_at`
+	dueToClose = `status IN ('open', 'paused') AND closes_at <= NOW() AND closes_at = approved_closes_at
+        AND NOT EXISTS (SELECT 1 FROM polling_extensions x WHERE x.election_id = elections.id AND x.closes_at > NOW())`
+)
+
+// Open moves a scheduled election to open inside tx
+type Open func(tx *sql.Tx, electionID int, actor string) error
+
+// Close closes polls inside tx and runs the tally; it reports whether results
+// were published or the election waits for its trustees
+type Close func(tx *sql.Tx, electionID int, actor string) (bool, error)
+
+// Start runs the scheduler every SCHEDULER_INTERVAL (a Go duration such as
+// "15s") in the background. It is safe to start on every replica.
+func Start(open Open, close Close) error {
+	interval := DefaultInterval
+	if value := os.Getenv("SCHEDULER_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		interval = parsed
+	}
+
+	go func() {
+		for range time.Tick(interval) {
+			RunDue(open, close)
+		}
+	}()
+	return nil
+}
+
+// RunDue opens and closes every election whose time has come. Failures are
+// logged and retried on the next run.
+func RunDue(open Open, close Close) {
+	runDue("open", dueToOpen, func(tx *sql.Tx, electionID int) error {
+		return open(tx, electionID, Actor)
+	})
+	runDue("close", dueToClose, func(tx *sql.Tx, electionID int) error {
+		tallied, err := close(tx, electionID, Actor)
+		if err == nil && !tallied {
+			log.Printf("Election %d closed on schedule; waiting for trustee decryptions", electionID)
+		}
+		return err
+	})
+}
+
+// runDue applies action to each election matching condition
+func runDue(name, condition string, action func(tx *sql.Tx, electionID int) error) {
+	ids, err := due(condition)
+	if err != nil {
+		log.Printf("Error finding elections to %s: %v", name, err)
+		return
+	}
+	for _, electionID := range ids {
+		if err := runOne(electionID, condition, action); err != nil {
+			log.Printf("Error trying to %s election %d on schedule: %v", name, electionID, err)
+		}
+	}
+}
+
+// due returns the IDs of the elections matching condition
+func due(condition string) ([]int, error) {
+	rows, err := utils.DB.Query("SELECT id FROM elections WHERE " + condition + " ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// runOne applies action to one election in its own transaction. The advisory
+// lock keeps other replicas out while this one works; the row lock and the
+// repeated condition make the action a no-op once another replica has run it.
+func runOne(electionID int, condition string, action func(tx *sql.Tx, electionID int) error) error {
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var acquired bool
+	lockQuery := "SELECT pg_try_advisory_xact_lock(hashtext('evoting-poll-scheduler'), $1)"
+	if err := tx.QueryRow(lockQuery, electionID).Scan(&acquired); err != nil {
+		return fmt.Errorf("taking scheduler lock: %w", err)
+	}
+	if !acquired {
+		return nil
+	}
+
+	// Waits for ballots being cast to commit, then holds off new ones
+	var id int
+	err = tx.QueryRow("SELECT id FROM elections WHERE id = $1 AND "+condition+" FOR UPDATE", electionID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := action(tx, electionID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,98 @@
+package scheduler
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+	_ "time/tzdata" // Time zones must resolve even where the host has no zoneinfo
+)
+
+// DefaultTimeZone is used for elections created without a time zone
+const DefaultTimeZone = "Asia/Karachi"
+
+// Layouts accepted for times without a UTC offset, read in the election's time zone
+var localLayouts = []string{"2006-01-02T15:04", "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02 15:04:05"}
+
+// ParseTime reads a polling time. RFC 3339 times carry their own offset;
+// local times such as "2026-02-08T08:00" are read in the named IANA zone.
+func ParseTime(value, zone string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	location, err := time.LoadLocation(zone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("time must be RFC 3339 or local \"YYYY-MM-DDTHH:MM\"")
+}
+
+// Hours are an election's polling hours as stored: both times optional, in
+// UTC, and the zone they are shown in
+type Hours struct {
+	OpensAt  *time.Time
+	ClosesAt *time.Time
+	Zone     string
+}
+
+// ParseHours validates polling hours as submitted. Empty times are left unset,
+// and an empty zone means DefaultTimeZone.
+func ParseHours(opensAt, closesAt, zone string) (Hours, error) {
+	hours := Hours{Zone: zone}
+	if hours.Zone == "" {
+		hours.Zone = DefaultTimeZone
+	}
+	if _, err := time.LoadLocation(hours.Zone); err != nil {
+		return hours, errors.New("unknown time zone " + hours.Zone)
+	}
+
+	for _, field := range []struct {
+		value string
+		dest  **time.Time
+	}{{opensAt, &hours.OpensAt}, {closesAt, &hours.ClosesAt}} {
+		if field.value == "" {
+			continue
+		}
+		t, err := ParseTime(field.value, hours.Zone)
+		if err != nil {
+			return hours, err
+		}
+		t = t.UTC()
+		*field.dest = &t
+	}
+
+	if hours.OpensAt != nil && hours.ClosesAt != nil && !hours.ClosesAt.After(*hours.OpensAt) {
+		return hours, errors.New("polls must close after they open")
+	}
+	return hours, nil
+}
+
+// Date is the election day in the election's zone, or today when no opening
+// time is set; it fills the elections.date column
+func (h Hours) Date() string {
+	location, err := time.LoadLocation(h.Zone)
+	if err != nil {
+		location = time.UTC
+	}
+	if h.OpensAt == nil {
+		return time.Now().In(location).Format("2006-01-02")
+	}
+	return h.OpensAt.In(location).Format("2006-01-02")
+}
+
+// Format renders a stored time in an election's zone, or nil when it is unset
+func Format(t sql.NullTime, zone string) *string {
+	if !t.Valid {
+		return nil
+	}
+	location, err := time.LoadLocation(zone)
+	if err != nil {
+		location = time.UTC
+	}
+	formatted := t.Time.In(location).Format(time.RFC3339)
+	return &formatted
+}
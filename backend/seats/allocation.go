@@ -0,0 +1,169 @@
+// Package seats allocates reserved seats (for women, minorities and the like)
+// to parties in proportion to the general seats they won.
+//
+// Allocate is a pure function of a pool's rules and the parties' general-seat
+// standings, so anyone can replay an allocation from the published results.
+// AllocateElection runs it for every pool of an election when the election is
+// tallied and seats each party's share from its priority list.
+package seats
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Highest-averages methods of a reserved seat pool
+const (
+	MethodDHondt      = "dhondt"       // Divisors 1, 2, 3, ...
+	MethodSainteLague = "sainte_lague" // Divisors 1, 3, 5, ...
+)
+
+// TieBreak documents how equal quotients are resolved; it is stored in every allocation
+const TieBreak = "Each seat goes to the eligible party with the highest quotient of general seats won over its divisor. " +
+	"Equal quotients go to the party with more candidate votes, then to the lowest party ID."
+
+// Party is a party's standing after the general seats have been counted
+type Party struct {
+	PartyID      int    `json:"party_id"`
+	Name         string `json:"name"`
+	GeneralSeats int    `json:"general_seats"`
+	Votes        int    `json:"votes"` // Candidate votes across every constituency
+}
+
+// Pool is a reserved seat pool and its eligibility thresholds
+type Pool struct {
+	ID              int     `json:"pool_id"`
+	Name            string  `json:"name"`
+	Tier            string  `json:"tier"` // Assembly whose general seats the pool follows
+	Seats           int     `json:"seats"`
+	Method          string  `json:"method"`
+	MinGeneralSeats int     `json:"min_general_seats"`
+	MinVoteShare    float64 `json:"min_vote_share"` // Percent of all candidate votes
+}
+
+// Member is one entry of a party's priority list
+type Member struct {
+	Position  int    `json:"position"`
+	CitizenID int    `json:"citizen_id"`
+	Name      string `json:"name"`
+}
+
+// PartyAllocation is a party's share of a pool
+type PartyAllocation struct {
+	Party
+	Eligible   bool     `json:"eligible"`
+	Ineligible string   `json:"ineligible,omitempty"` // Threshold the party missed
+	Seats      int      `json:"seats"`
+	Members    []Member `json:"members"`  // Seated from the party's list, in list order
+	Unfilled   int      `json:"unfilled"` // Seats won that the list was too short to fill
+}
+
+// Award is one seat of a pool and the quotient that won it
+type Award struct {
+	Seat      int    `json:"seat"`
+	PartyID   int    `json:"party_id"`
+	Quotient  string `json:"quotient"` // General seats over divisor, e.g. "12/3"
+	TieBroken bool   `json:"tie_broken,omitempty"`
+}
+
+// Allocation is the record of one pool's allocation
+type Allocation struct {
+	Pool
+	TotalVotes  int               `json:"total_votes"`
+	Parties     []PartyAllocation `json:"parties"`
+	Awards      []Award           `json:"awards"`
+	Unallocated int               `json:"unallocated"` // Seats no eligible party could take
+	TieBreak    string            `json:"tie_break"`
+}
+
+// divisor is the divisor of a party that has already won n seats of the pool
+func divisor(method string, n int) int {
+	if method == MethodSainteLague {
+		return 2*n + 1
+	}
+	return n + 1
+}
+
+// Allocate shares a pool's seats among the parties that meet its thresholds,
+// one seat at a time to the highest quotient of general seats over divisor.
+// totalVotes is every candidate vote cast, independents included, and is the
+// base of the vote share threshold.
+func Allocate(pool Pool, parties []Party, totalVotes int) *Allocation {
+	allocation := &Allocation{Pool: pool, TotalVotes: totalVotes, Parties: []PartyAllocation{}, Awards: []Award{}, TieBreak: TieBreak}
+
+	sorted := append([]Party(nil), parties...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].PartyID < sorted[j].PartyID })
+
+	// The vote share threshold is compared in hundredths of a percent so that
+	// it is exact for the two decimal places the pool stores
+	minShare := int64(math.Round(pool.MinVoteShare * 100))
+	for _, party := range sorted {
+		share := PartyAllocation{Party: party, Members: []Member{}}
+		switch {
+		case party.GeneralSeats < pool.MinGeneralSeats:
+			share.Ineligible = fmt.Sprintf("won %d of the %d general seats required", party.GeneralSeats, pool.MinGeneralSeats)
+		case int64(party.Votes)*10000 < minShare*int64(totalVotes):
+			share.Ineligible = fmt.Sprintf("polled under %.2f%% of candidate votes", pool.MinVoteShare)
+		default:
+			share.Eligible = true
+		}
+		allocation.Parties = append(allocation.Parties, share)
+	}
+
+	for seat := 1; seat <= pool.Seats; seat++ {
+		best := -1
+		tieBroken := false
+		for i, share := range allocation.Parties {
+			if !share.Eligible || share.GeneralSeats == 0 {
+				continue
+			}
+			if best < 0 {
+				best = i
+				continue
+			}
+			// Compare a/b with c/d as a*d against c*b to stay exact
+			leader := allocation.Parties[best]
+			a, b := int64(share.GeneralSeats), int64(divisor(pool.Method, share.Seats))
+			c, d := int64(leader.GeneralSeats), int64(divisor(pool.Method, leader.Seats))
+			switch {
+			case a*d > c*b:
+				best, tieBroken = i, false
+			case a*d == c*b:
+				tieBroken = true
+				if share.Votes > leader.Votes {
+					best = i
+				}
+			}
+		}
+		if best < 0 {
+			allocation.Unallocated = pool.Seats - seat + 1
+			break
+		}
+
+		winner := &allocation.Parties[best]
+		allocation.Awards = append(allocation.Awards, Award{
+			Seat:      seat,
+			PartyID:   winner.PartyID,
+			Quotient:  fmt.Sprintf("%d/%d", winner.GeneralSeats, divisor(pool.Method, winner.Seats)),
+			TieBroken: tieBroken,
+		})
+		winner.Seats++
+	}
+	return allocation
+}
+
+// Seat fills each party's seats from its priority list, by party ID, in list
+// order. Seats beyond the end of a list are left unfilled.
+func (a *Allocation) Seat(lists map[int][]Member) {
+	for i := range a.Parties {
+		share := &a.Parties[i]
+		list := lists[share.PartyID]
+		n := share.Seats
+		if n > len(list) {
+			n = len(list)
+		}
+		share.Members = append([]Member{}, list[:n]...)
+		share.Unfilled = share.Seats - n
+	}
+}
@@ -0,0 +1,204 @@
+package seats
+
+import (
+	"reflect"
+	"testing"
+)
+
+// seatsWon maps each party that won reserved seats to their number
+func seatsWon(allocation *Allocation) map[int]int {
+	won := make(map[int]int)
+	for _, share := range allocation.Parties {
+		if share.Seats > 0 {
+			won[share.PartyID] = share.Seats
+		}
+	}
+	return won
+}
+
+// quotients lists the winning quotient of every award in seat order
+func quotients(allocation *Allocation) []string {
+	list := make([]string, len(allocation.Awards))
+	for i, award := range allocation.Awards {
+		list[i] = award.Quotient
+	}
+	return list
+}
+
+// TestAllocate checks both highest-averages methods, the eligibility
+// thresholds and the tie-break against allocations worked out by hand
+func TestAllocate(t *testing.T) {
+	const a, b, c = 1, 2, 3
+	// General seats 11, 7 and 4 give D'Hondt quotients 11, 7, 5.5, 4, 3.67 and
+	// Sainte-Laguë quotients 11, 7, 4, 3.67, 2.33 for the first five seats
+	standings := []Party{
+		{PartyID: c, GeneralSeats: 4, Votes: 200},
+		{PartyID: a, GeneralSeats: 11, Votes: 500},
+		{PartyID: b, GeneralSeats: 7, Votes: 300},
+	}
+
+	tests := []struct {
+		name        string
+		pool        Pool
+		parties     []Party
+		totalVotes  int
+		won         map[int]int
+		quotients   []string
+		tieBroken   []int // Seats whose award needed the tie-break
+		ineligible  []int
+		unallocated int
+	}{
+		{
+			name:       "dhondt",
+			pool:       Pool{Seats: 5, Method: MethodDHondt},
+			parties:    standings,
+			totalVotes: 1000,
+			won:        map[int]int{a: 3, b: 1, c: 1},
+			quotients:  []string{"11/1", "7/1", "11/2", "4/1", "11/3"},
+		},
+		{
+			name:       "sainte lague",
+			pool:       Pool{Seats: 5, Method: MethodSainteLague},
+			parties:    standings,
+			totalVotes: 1000,
+			won:        map[int]int{a: 2, b: 2, c: 1},
+			quotients:  []string{"11/1", "7/1", "4/1", "11/3", "7/3"},
+		},
+		{
+			name:       "min general seats",
+			pool:       Pool{Seats: 5, Method: MethodDHondt, MinGeneralSeats: 5},
+			parties:    standings,
+			totalVotes: 1000,
+			won:        map[int]int{a: 3, b: 2},
+			quotients:  []string{"11/1", "7/1", "11/2", "11/3", "7/2"},
+			ineligible: []int{c},
+		},
+		{
+			// 200 of 1000 votes is exactly 20%, so only 20.01% shuts C out
+			name:       "vote share on the threshold",
+			pool:       Pool{Seats: 3, Method: MethodDHondt, MinVoteShare: 20},
+			parties:    standings,
+			totalVotes: 1000,
+			won:        map[int]int{a: 2, b: 1},
+			quotients:  []string{"11/1", "7/1", "11/2"},
+		},
+		{
+			name:       "vote share under the threshold",
+			pool:       Pool{Seats: 4, Method: MethodDHondt, MinVoteShare: 20.01},
+			parties:    standings,
+			totalVotes: 1000,
+			won:        map[int]int{a: 3, b: 1},
+			quotients:  []string{"11/1", "7/1", "11/2", "11/3"},
+			ineligible: []int{c},
+		},
+		{
+			name:       "tie goes to more votes",
+			pool:       Pool{Seats: 1, Method: MethodDHondt},
+			parties:    []Party{{PartyID: a, GeneralSeats: 6, Votes: 100}, {PartyID: b, GeneralSeats: 6, Votes: 200}},
+			totalVotes: 300,
+			won:        map[int]int{b: 1},
+			quotients:  []string{"6/1"},
+			tieBroken:  []int{1},
+		},
+		{
+			name:       "tie on votes goes to the lowest party ID",
+			pool:       Pool{Seats: 2, Method: MethodSainteLague},
+			parties:    []Party{{PartyID: c, GeneralSeats: 6, Votes: 100}, {PartyID: b, GeneralSeats: 6, Votes: 100}},
+			totalVotes: 200,
+			won:        map[int]int{b: 1, c: 1},
+			quotients:  []string{"6/1", "6/1"},
+			tieBroken:  []int{1},
+		},
+		{
+			name:        "no eligible party",
+			pool:        Pool{Seats: 3, Method: MethodDHondt, MinGeneralSeats: 20},
+			parties:     standings,
+			totalVotes:  1000,
+			won:         map[int]int{},
+			quotients:   []string{},
+			ineligible:  []int{a, b, c},
+			unallocated: 3,
+		},
+		{
+			name:        "only parties without general seats",
+			pool:        Pool{Seats: 2, Method: MethodDHondt},
+			parties:     []Party{{PartyID: a, Votes: 10}, {PartyID: b, Votes: 5}},
+			totalVotes:  15,
+			won:         map[int]int{},
+			quotients:   []string{},
+			unallocated: 2,
+		},
+		{
+			name:       "parties without general seats",
+			pool:       Pool{Seats: 2, Method: MethodDHondt},
+			parties:    []Party{{PartyID: a, Votes: 10}, {PartyID: b, GeneralSeats: 1, Votes: 5}},
+			totalVotes: 15,
+			won:        map[int]int{b: 2},
+			quotients:  []string{"1/1", "1/2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allocation := Allocate(tt.pool, tt.parties, tt.totalVotes)
+
+			if got := seatsWon(allocation); !reflect.DeepEqual(got, tt.won) {
+				t.Errorf("seats won %v, want %v", got, tt.won)
+			}
+			if got := quotients(allocation); !reflect.DeepEqual(got, tt.quotients) {
+				t.Errorf("quotients %v, want %v", got, tt.quotients)
+			}
+			if allocation.Unallocated != tt.unallocated {
+				t.Errorf("unallocated = %d, want %d", allocation.Unallocated, tt.unallocated)
+			}
+
+			var tieBroken, ineligible []int
+			for _, award := range allocation.Awards {
+				if award.TieBroken {
+					tieBroken = append(tieBroken, award.Seat)
+				}
+			}
+			for i, share := range allocation.Parties {
+				if i > 0 && allocation.Parties[i-1].PartyID > share.PartyID {
+					t.Errorf("parties not in ID order: %d before %d", allocation.Parties[i-1].PartyID, share.PartyID)
+				}
+				if share.Eligible == (share.Ineligible != "") {
+					t.Errorf("party %d eligible = %v with reason %q", share.PartyID, share.Eligible, share.Ineligible)
+				}
+				if !share.Eligible {
+					ineligible = append(ineligible, share.PartyID)
+				}
+			}
+			if !reflect.DeepEqual(tieBroken, tt.tieBroken) {
+				t.Errorf("ties broken for seats %v, want %v", tieBroken, tt.tieBroken)
+			}
+			if !reflect.DeepEqual(ineligible, tt.ineligible) {
+				t.Errorf("ineligible parties %v, want %v", ineligible, tt.ineligible)
+			}
+		})
+	}
+}
+
+// TestSeat checks that seats are filled from the top of each list and that a
+// short list leaves the rest unfilled
+func TestSeat(t *testing.T) {
+	allocation := Allocate(Pool{Seats: 4, Method: MethodDHondt}, []Party{
+		{PartyID: 1, GeneralSeats: 9},
+		{PartyID: 2, GeneralSeats: 4},
+	}, 0)
+	allocation.Seat(map[int][]Member{
+		1: {{Position: 1, CitizenID: 10}, {Position: 2, CitizenID: 11}, {Position: 3, CitizenID: 12}, {Position: 4, CitizenID: 13}},
+	})
+
+	first, second := allocation.Parties[0], allocation.Parties[1]
+	if first.Seats != 3 || second.Seats != 1 {
+		t.Fatalf("seats %d and %d, want 3 and 1", first.Seats, second.Seats)
+	}
+	want := []Member{{Position: 1, CitizenID: 10}, {Position: 2, CitizenID: 11}, {Position: 3, CitizenID: 12}}
+	if !reflect.DeepEqual(first.Members, want) || first.Unfilled != 0 {
+		t.Errorf("party 1 seated %v with %d unfilled, want %v", first.Members, first.Unfilled, want)
+	}
+	if len(second.Members) != 0 || second.Unfilled != 1 {
+		t.Errorf("party 2 seated %v with %d unfilled, want none and 1", second.Members, second.Unfilled)
+	}
+}
@@ -0,0 +1,223 @@
+package seats
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Haste007/E-Voting/Backend/ballot"
+)
+
+// Standings returns every party that stood in one tier of an election with the
+// general seats it won and its candidate votes, and the candidate votes of all
+// candidates in the tier, independents included
+func Standings(q ballot.Querier, electionID interface{}, tier string) ([]Party, int, error) {
+	query := `
+        SELECT p.id, p.name, COUNT(*) FILTER (WHERE er.elected), COALESCE(SUM(er.total_votes), 0)
+        FROM election_results er
+        JOIN constituencies co ON co.id = er.constituency_id
+        JOIN candidates c ON c.id = er.candidate_id
+        JOIN parties p ON p.id = c.party_id
+        WHERE er.election_id = $1 AND er.choice = 'candidate' AND co.tier = $2
+        GROUP BY p.id, p.name
+        ORDER BY p.id
+    `
+	rows, err := q.Query(query, electionID, tier)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	parties := []Party{}
+	for rows.Next() {
+		var party Party
+		if err := rows.Scan(&party.PartyID, &party.Name, &party.GeneralSeats, &party.Votes); err != nil {
+			return nil, 0, err
+		}
+		parties = append(parties, party)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var totalVotes int
+	totalQuery := `
+        SELECT COALESCE(SUM(er.total_votes), 0)
+        FROM election_results er
+        JOIN constituencies co ON co.id = er.constituency_id
+        WHERE er.election_id = $1 AND er.choice = 'candidate' AND co.tier = $2
+    `
+	if err := q.QueryRow(totalQuery, electionID, tier).Scan(&totalVotes); err != nil {
+		return nil, 0, err
+	}
+	return parties, totalVotes, nil
+}
+
+// Pools returns the reserved seat pools of an election
+func Pools(q ballot.Querier, electionID interface{}) ([]Pool, error) {
+	query := `
+        SELECT id, name, tier, seats, method, min_general_seats, min_vote_share
+        FROM reserved_seat_pools
+        WHERE election_id = $1
+        ORDER BY tier, id
+    `
+	rows, err := q.Query(query, electionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pools := []Pool{}
+	for rows.Next() {
+		var pool Pool
+		if err := rows.Scan(&pool.ID, &pool.Name, &pool.Tier, &pool.Seats, &pool.Method, &pool.MinGeneralSeats, &pool.MinVoteShare); err != nil {
+			return nil, err
+		}
+		pools = append(pools, pool)
+	}
+	return pools, rows.Err()
+}
+
+// Lists returns the priority lists submitted for a pool by party, in list order
+func Lists(q ballot.Querier, poolID int) (map[int][]Member, error) {
+	query := `
+        SELECT pl.party_id, pl.position, pl.citizen_id, ci.name
+        FROM party_lists pl
+        JOIN citizens ci ON ci.id = pl.citizen_id
+        WHERE pl.pool_id = $1
+        ORDER BY pl.party_id, pl.position
+    `
+	rows, err := q.Query(query, poolID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := make(map[int][]Member)
+	for rows.Next() {
+		var partyID int
+		var member Member
+		if err := rows.Scan(&partyID, &member.Position, &member.CitizenID, &member.Name); err != nil {
+			return nil, err
+		}
+		lists[partyID] = append(lists[partyID], member)
+	}
+	return lists, rows.Err()
+}
+
+// AllocateElection allocates every reserved seat pool of a tallied election
+// from its election_results and stores the allocations. Running it again
+// replaces them.
+func AllocateElection(tx *sql.Tx, electionID int) error {
+	pools, err := Pools(tx, electionID)
+	if err != nil || len(pools) == 0 {
+		return err
+	}
+
+	for _, pool := range pools {
+		// A pool follows the general seats of its own tier only
+		parties, totalVotes, err := Standings(tx, electionID, pool.Tier)
+		if err != nil {
+			return err
+		}
+		allocation := Allocate(pool, parties, totalVotes)
+		lists, err := Lists(tx, pool.ID)
+		if err != nil {
+			return err
+		}
+		allocation.Seat(lists)
+
+		data, err := json.Marshal(allocation)
+		if err != nil {
+			return err
+		}
+		query := `
+            INSERT INTO seat_allocations (pool_id, allocation)
+            VALUES ($1, $2)
+            ON CONFLICT (pool_id) DO UPDATE SET allocation = EXCLUDED.allocation, allocated_at = NOW()
+        `
+		if _, err := tx.Exec(query, pool.ID, string(data)); err != nil {
+			return fmt.Errorf("storing allocation of pool %d: %w", pool.ID, err)
+		}
+	}
+	return nil
+}
+
+// Allocations returns the stored allocations of an election's pools
+func Allocations(q ballot.Querier, electionID interface{}) ([]Allocation, error) {
+	query := `
+        SELECT sa.allocation
+        FROM seat_allocations sa
+        JOIN reserved_seat_pools rp ON rp.id = sa.pool_id
+        WHERE rp.election_id = $1
+        ORDER BY rp.tier, rp.id
+    `
+	rows, err := q.Query(query, electionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	allocations := []Allocation{}
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var allocation Allocation
+		if err := json.Unmarshal(data, &allocation); err != nil {
+			return nil, err
+		}
+		allocations = append(allocations, allocation)
+	}
+	return allocations, rows.Err()
+}
+
+// PartySeats is a party's seat total in one tier across general and reserved seats
+type PartySeats struct {
+	Tier          string         `json:"tier"`
+	PartyID       int            `json:"party_id"`
+	Name          string         `json:"name"`
+	GeneralSeats  int            `json:"general_seats"`
+	ReservedSeats map[string]int `json:"reserved_seats"` // By pool name
+	TotalSeats    int            `json:"total_seats"`
+}
+
+// Totals adds each party's reserved seats in a tier to its general seats there
+func Totals(tier string, parties []Party, allocations []Allocation) []PartySeats {
+	totals := make([]PartySeats, 0, len(parties))
+	for _, party := range parties {
+		seats := PartySeats{Tier: tier, PartyID: party.PartyID, Name: party.Name, GeneralSeats: party.GeneralSeats, ReservedSeats: map[string]int{}, TotalSeats: party.GeneralSeats}
+		for _, allocation := range allocations {
+			if allocation.Tier != tier {
+				continue
+			}
+			for _, share := range allocation.Parties {
+				if share.PartyID == party.PartyID {
+					seats.ReservedSeats[allocation.Name] = share.Seats
+					seats.TotalSeats += share.Seats
+				}
+			}
+		}
+		totals = append(totals, seats)
+	}
+	return totals
+}
+
+// Summary returns the seat totals of a tallied election, National Assembly first
+func Summary(q ballot.Querier, electionID interface{}) ([]PartySeats, error) {
+	allocations, err := Allocations(q, electionID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := []PartySeats{}
+	for _, tier := range []string{ballot.TierNA, ballot.TierPA} {
+		parties, _, err := Standings(q, electionID, tier)
+		if err != nil {
+			return nil, err
+		}
+		summary = append(summary, Totals(tier, parties, allocations)...)
+	}
+	return summary, nil
+}
@@ -0,0 +1,77 @@
+package tabulation
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// fixedDigits is the number of decimal places STV vote values are kept to
+const fixedDigits = 9
+
+// Fixed is a non-negative vote value with fixedDigits decimal places. STV
+// counts use it instead of floats so that every count is exactly reproducible.
+type Fixed int64
+
+const fixedOne Fixed = 1_000_000_000
+
+// fixedInt converts a whole number of votes
+func fixedInt(n int) Fixed {
+	return Fixed(n) * fixedOne
+}
+
+// mulDiv returns a*b/c, truncated or, if up is set, rounded up
+func mulDiv(a, b, c Fixed, up bool) Fixed {
+	hi, lo := bits.Mul64(uint64(a), uint64(b))
+	quotient, remainder := bits.Div64(hi, lo, uint64(c))
+	if up && remainder != 0 {
+		quotient++
+	}
+	return Fixed(quotient)
+}
+
+// mul multiplies two values, truncating
+func (a Fixed) mul(b Fixed) Fixed {
+	return mulDiv(a, b, fixedOne, false)
+}
+
+// div divides two values, truncating
+func (a Fixed) div(b Fixed) Fixed {
+	return mulDiv(a, fixedOne, b, false)
+}
+
+// Int returns the whole votes of a value
+func (a Fixed) Int() int {
+	return int(a / fixedOne)
+}
+
+func (a Fixed) String() string {
+	s := fmt.Sprintf("%d.%0*d", a/fixedOne, fixedDigits, a%fixedOne)
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
+
+// MarshalJSON writes the exact decimal value as a JSON number
+func (a Fixed) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON reads a decimal value with at most fixedDigits decimal places
+func (a *Fixed) UnmarshalJSON(data []byte) error {
+	whole, fraction, _ := strings.Cut(string(data), ".")
+	if len(fraction) > fixedDigits {
+		return fmt.Errorf("tabulation: %s has more than %d decimal places", data, fixedDigits)
+	}
+	w, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return err
+	}
+	var f int64
+	if fraction != "" {
+		if f, err = strconv.ParseInt(fraction+strings.Repeat("0", fixedDigits-len(fraction)), 10, 64); err != nil {
+			return err
+		}
+	}
+	*a = Fixed(w)*fixedOne + Fixed(f)
+	return nil
+}
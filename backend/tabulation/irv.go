@@ -0,0 +1,143 @@
+package tabulation
+
+import "sort"
+
+// IRVTieBreak documents how the instant-runoff count breaks ties; it is stored in every report
+const IRVTieBreak = "When several candidates share the fewest votes, the one with fewer votes in the latest earlier round " +
+	"where the tied candidates' totals differed is eliminated. If they were tied in every round, the candidate with the " +
+	"highest candidate ID is eliminated."
+
+// CandidateVotes is a continuing candidate's total in one round
+type CandidateVotes struct {
+	CandidateID int `json:"candidate_id"`
+	Votes       int `json:"votes"`
+}
+
+// IRVRound is one round of an instant-runoff count
+type IRVRound struct {
+	Round      int              `json:"round"`
+	Votes      []CandidateVotes `json:"votes"`     // Continuing candidates, most votes first
+	Exhausted  int              `json:"exhausted"` // Ballots with no continuing preference left, so far
+	Elected    int              `json:"elected,omitempty"`
+	Eliminated int              `json:"eliminated,omitempty"`
+	TieBroken  bool             `json:"tie_broken,omitempty"` // The elimination needed IRVTieBreak
+	Transfers  map[int]int      `json:"transfers,omitempty"`  // Votes the eliminated candidate passed on, by recipient
+}
+
+// IRVReport is the round-by-round record of an instant-runoff count in one constituency
+type IRVReport struct {
+	Method     string     `json:"method"` // Always MethodIRV
+	Candidates []int      `json:"candidates"`
+	Ballots    int        `json:"ballots"`
+	Rounds     []IRVRound `json:"rounds"`
+	Winner     int        `json:"winner"` // 0 only when no candidate stood
+	TieBreak   string     `json:"tie_break"`
+}
+
+// InstantRunoff counts ranked ballots one elimination at a time. Each ballot
+// counts for its highest-ranked continuing candidate; a candidate holding more
+// than half of the continuing votes, or the last one left, wins.
+func InstantRunoff(candidates []int, ballots []Ballot) *IRVReport {
+	report := &IRVReport{Method: MethodIRV, Candidates: candidates, Ballots: len(ballots), Rounds: []IRVRound{}, TieBreak: IRVTieBreak}
+
+	continuing := make(map[int]bool, len(candidates))
+	for _, id := range candidates {
+		continuing[id] = true
+	}
+
+	// piles holds the ballots currently counting for each candidate
+	piles := make(map[int][]Ballot, len(candidates))
+	exhausted := 0
+	for _, b := range ballots {
+		if next := b.next(continuing); next != 0 {
+			piles[next] = append(piles[next], b)
+		} else {
+			exhausted++
+		}
+	}
+
+	var history []map[int]int
+	for len(continuing) > 0 {
+		round := IRVRound{Round: len(history) + 1, Exhausted: exhausted}
+		votes := make(map[int]int, len(continuing))
+		total := 0
+		for id := range continuing {
+			votes[id] = len(piles[id])
+			total += votes[id]
+		}
+		history = append(history, votes)
+		round.Votes = sortedVotes(votes)
+
+		leader := round.Votes[0]
+		if leader.Votes*2 > total || len(continuing) == 1 {
+			round.Elected = leader.CandidateID
+			report.Winner = leader.CandidateID
+			report.Rounds = append(report.Rounds, round)
+			break
+		}
+
+		loser, tieBroken := lowest(continuing, history)
+		round.Eliminated = loser
+		round.TieBroken = tieBroken
+		delete(continuing, loser)
+
+		round.Transfers = make(map[int]int)
+		for _, b := range piles[loser] {
+			if next := b.next(continuing); next != 0 {
+				piles[next] = append(piles[next], b)
+				round.Transfers[next]++
+			} else {
+				exhausted++
+			}
+		}
+		delete(piles, loser)
+		report.Rounds = append(report.Rounds, round)
+	}
+	return report
+}
+
+// LastVotes returns each candidate's total in the last round they were counted
+// in, which is what election_results records for an instant-runoff count
+func (r *IRVReport) LastVotes() map[int]int {
+	last := make(map[int]int, len(r.Candidates))
+	for _, round := range r.Rounds {
+		for _, v := range round.Votes {
+			last[v.CandidateID] = v.Votes
+		}
+	}
+	return last
+}
+
+// sortedVotes orders a round's totals by votes, then candidate ID
+func sortedVotes(votes map[int]int) []CandidateVotes {
+	sorted := make([]CandidateVotes, 0, len(votes))
+	for id, n := range votes {
+		sorted = append(sorted, CandidateVotes{CandidateID: id, Votes: n})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Votes != sorted[j].Votes {
+			return sorted[i].Votes > sorted[j].Votes
+		}
+		return sorted[i].CandidateID < sorted[j].CandidateID
+	})
+	return sorted
+}
+
+// lowest picks the continuing candidate to eliminate after the latest round in
+// history, applying IRVTieBreak, and reports whether a tie had to be broken
+func lowest(continuing map[int]bool, history []map[int]int) (int, bool) {
+	current := history[len(history)-1]
+	var tied []int
+	for id := range continuing {
+		switch {
+		case len(tied) == 0 || current[id] < current[tied[0]]:
+			tied = []int{id}
+		case current[id] == current[tied[0]]:
+			tied = append(tied, id)
+		}
+	}
+	if len(tied) == 1 {
+		return tied[0], false
+	}
+	return breakTie(tied, history[:len(history)-1], false), true
+}
@@ -0,0 +1,179 @@
+package tabulation_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Haste007/E-Voting/Backend/tabulation"
+)
+
+// repeat returns n copies of one ranking
+func repeat(n int, ranking ...int) []tabulation.Ballot {
+	ballots := make([]tabulation.Ballot, n)
+	for i := range ballots {
+		ballots[i] = ranking
+	}
+	return ballots
+}
+
+// join concatenates groups of ballots
+func join(groups ...[]tabulation.Ballot) []tabulation.Ballot {
+	var ballots []tabulation.Ballot
+	for _, group := range groups {
+		ballots = append(ballots, group...)
+	}
+	return ballots
+}
+
+// TestInstantRunoff checks winners, elimination order, tie-breaks and
+// exhausted ballots against counts worked out by hand
+func TestInstantRunoff(t *testing.T) {
+	const memphis, nashville, chattanooga, knoxville = 1, 2, 3, 4
+
+	tests := []struct {
+		name       string
+		candidates []int
+		ballots    []tabulation.Ballot
+		winner     int
+		eliminated []int // In order, one per round but the last
+		tieBroken  []int // Rounds whose elimination needed IRVTieBreak
+		exhausted  int   // In the last round
+		lastVotes  map[int]int
+	}{
+		{
+			// The Tennessee capital example used to illustrate instant runoff
+			name:       "tennessee capital",
+			candidates: []int{memphis, nashville, chattanooga, knoxville},
+			ballots: join(
+				repeat(42, memphis, nashville, chattanooga, knoxville),
+				repeat(26, nashville, chattanooga, knoxville, memphis),
+				repeat(15, chattanooga, knoxville, nashville, memphis),
+				repeat(17, knoxville, chattanooga, nashville, memphis),
+			),
+			winner:     knoxville,
+			eliminated: []int{chattanooga, nashville},
+			lastVotes:  map[int]int{memphis: 42, nashville: 26, chattanooga: 15, knoxville: 58},
+		},
+		{
+			name:       "majority in the first round",
+			candidates: []int{1, 2, 3},
+			ballots:    join(repeat(6, 1), repeat(3, 2, 1), repeat(2, 3)),
+			winner:     1,
+			lastVotes:  map[int]int{1: 6, 2: 3, 3: 2},
+		},
+		{
+			// 2 and 3 tie for fewest in round 2; 2 had fewer in round 1
+			name:       "tie broken by an earlier round",
+			candidates: []int{1, 2, 3, 4},
+			ballots:    join(repeat(6, 1), repeat(3, 2, 3), repeat(4, 3), repeat(1, 4, 2)),
+			winner:     3,
+			eliminated: []int{4, 2},
+			tieBroken:  []int{2},
+			exhausted:  1,
+			lastVotes:  map[int]int{1: 6, 2: 4, 3: 7, 4: 1},
+		},
+		{
+			name:       "tie in every round eliminates the highest ID",
+			candidates: []int{1, 2, 3},
+			ballots:    join(repeat(2, 1), repeat(1, 2, 1), repeat(1, 3, 1)),
+			winner:     1,
+			eliminated: []int{3},
+			tieBroken:  []int{1},
+			lastVotes:  map[int]int{1: 3, 2: 1, 3: 1},
+		},
+		{
+			// Ballots ranking only eliminated candidates stop counting, so the
+			// majority is of continuing votes
+			name:       "exhausted ballots",
+			candidates: []int{1, 2, 3},
+			ballots:    join(repeat(4, 1), repeat(3, 2), repeat(2, 3), repeat(1)),
+			winner:     1,
+			eliminated: []int{3},
+			exhausted:  3,
+			lastVotes:  map[int]int{1: 4, 2: 3, 3: 2},
+		},
+		{
+			name:       "zero ballots",
+			candidates: []int{1, 2, 3},
+			winner:     1,
+			eliminated: []int{3, 2},
+			tieBroken:  []int{1, 2},
+			lastVotes:  map[int]int{1: 0, 2: 0, 3: 0},
+		},
+		{
+			name:       "single candidate",
+			candidates: []int{7},
+			ballots:    repeat(2, 7),
+			winner:     7,
+			lastVotes:  map[int]int{7: 2},
+		},
+		{
+			name:      "no candidates",
+			ballots:   repeat(2, 1),
+			lastVotes: map[int]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := tabulation.InstantRunoff(tt.candidates, tt.ballots)
+
+			if report.Winner != tt.winner {
+				t.Errorf("winner = %d, want %d", report.Winner, tt.winner)
+			}
+			if report.Ballots != len(tt.ballots) {
+				t.Errorf("ballots = %d, want %d", report.Ballots, len(tt.ballots))
+			}
+
+			var eliminated, tieBroken []int
+			for _, round := range report.Rounds {
+				if round.Eliminated != 0 {
+					eliminated = append(eliminated, round.Eliminated)
+				}
+				if round.TieBroken {
+					tieBroken = append(tieBroken, round.Round)
+				}
+			}
+			if !reflect.DeepEqual(eliminated, tt.eliminated) {
+				t.Errorf("eliminated %v, want %v", eliminated, tt.eliminated)
+			}
+			if !reflect.DeepEqual(tieBroken, tt.tieBroken) {
+				t.Errorf("ties broken in rounds %v, want %v", tieBroken, tt.tieBroken)
+			}
+			if len(report.Rounds) > 0 {
+				last := report.Rounds[len(report.Rounds)-1]
+				if last.Elected != tt.winner {
+					t.Errorf("last round elected %d, want %d", last.Elected, tt.winner)
+				}
+				if last.Exhausted != tt.exhausted {
+					t.Errorf("exhausted = %d, want %d", last.Exhausted, tt.exhausted)
+				}
+			}
+			if got := report.LastVotes(); !reflect.DeepEqual(got, tt.lastVotes) {
+				t.Errorf("last votes %v, want %v", got, tt.lastVotes)
+			}
+		})
+	}
+}
+
+// TestInstantRunoffTransfers checks that an eliminated candidate's ballots move
+// to their next continuing preference and that tied totals list by candidate ID
+func TestInstantRunoffTransfers(t *testing.T) {
+	ballots := join(repeat(5, 1), repeat(4, 2), repeat(2, 3, 2), repeat(1, 3, 1))
+	report := tabulation.InstantRunoff([]int{1, 2, 3}, ballots)
+
+	if len(report.Rounds) < 2 {
+		t.Fatalf("got %d rounds, want at least 2", len(report.Rounds))
+	}
+	first := report.Rounds[0]
+	if first.Eliminated != 3 {
+		t.Fatalf("eliminated %d in round 1, want 3", first.Eliminated)
+	}
+	if want := map[int]int{1: 1, 2: 2}; !reflect.DeepEqual(first.Transfers, want) {
+		t.Errorf("transfers %v, want %v", first.Transfers, want)
+	}
+	want := []tabulation.CandidateVotes{{CandidateID: 1, Votes: 6}, {CandidateID: 2, Votes: 6}}
+	if got := report.Rounds[1].Votes; !reflect.DeepEqual(got, want) {
+		t.Errorf("round 2 votes %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,429 @@
+package tabulation
+
+import "sort"
+
+// Surplus transfer rules of an STV count, stored in elections.stv_transfer
+const (
+	// TransferGregory moves every ballot of an elected candidate's pile on at a
+	// fraction of its weight (weighted inclusive Gregory)
+	TransferGregory = "gregory"
+	// TransferMeek gives each elected candidate a keep factor and passes the
+	// rest of every ballot's weight down its ranking, recounting from scratch
+	TransferMeek = "meek"
+)
+
+// Candidate states in an STV count
+const (
+	StatusHopeful  = "hopeful"
+	StatusElected  = "elected"
+	StatusExcluded = "excluded"
+)
+
+// Actions that start an STV stage
+const (
+	ActionFirstPreferences = "first_preferences"
+	ActionSurplus          = "surplus"
+	ActionExclusion        = "exclusion"
+)
+
+const (
+	// meekTolerance is how far above the quota elected candidates may stand,
+	// in total, once keep factors have converged
+	meekTolerance Fixed = 1_000
+	// meekMaxIterations bounds the keep factor iteration of one stage
+	meekMaxIterations = 1_000
+)
+
+// STVTieBreak documents how an STV count breaks ties; it is stored in every report
+const STVTieBreak = "Vote values are kept to 9 decimal places and truncated, except Meek keep factors, which are rounded up. " +
+	"When candidates must be ordered by votes (to elect several reaching the quota in one stage, to fill the last seats, or " +
+	"to pick whose surplus moves first), ties are broken by the latest earlier stage in which the tied candidates' votes " +
+	"differed, more votes going first; candidates tied in every stage go first by lowest candidate ID. When several " +
+	"candidates share the fewest votes, the one with fewer votes in the latest earlier stage where their votes differed " +
+	"is excluded; candidates tied in every stage are excluded by highest candidate ID."
+
+// STVCandidate is one candidate's position at the end of a stage
+type STVCandidate struct {
+	CandidateID int    `json:"candidate_id"`
+	Votes       Fixed  `json:"votes"`
+	Status      string `json:"status"`
+	KeepFactor  *Fixed `json:"keep_factor,omitempty"` // Meek only
+}
+
+// STVStage is one stage of an STV count: a transfer or exclusion and the totals
+// it leaves
+type STVStage struct {
+	Stage         int            `json:"stage"`
+	Action        string         `json:"action"`
+	From          int            `json:"from,omitempty"`           // Candidate whose surplus moved (Gregory)
+	Excluded      int            `json:"excluded,omitempty"`       // Candidate excluded at the start of the stage
+	TransferValue *Fixed         `json:"transfer_value,omitempty"` // Weight multiplier of a Gregory surplus transfer
+	Transfers     map[int]Fixed  `json:"transfers,omitempty"`      // Votes moved, by recipient (Gregory)
+	Candidates    []STVCandidate `json:"candidates"`               // Every candidate, in ballot order
+	Quota         Fixed          `json:"quota"`
+	Exhausted     Fixed          `json:"exhausted"`          // Votes with no hopeful preference left, so far
+	Rounding      Fixed          `json:"rounding,omitempty"` // Votes lost to truncation, so far
+	Iterations    int            `json:"iterations,omitempty"`
+	Elected       []int          `json:"elected,omitempty"`    // Elected at the end of the stage, in order
+	TieBroken     bool           `json:"tie_broken,omitempty"` // The stage needed STVTieBreak
+}
+
+// STVReport is the stage-by-stage count sheet of an STV count in one constituency
+type STVReport struct {
+	Method     string     `json:"method"` // Always MethodSTV
+	Transfer   string     `json:"transfer"`
+	Seats      int        `json:"seats"`
+	Candidates []int      `json:"candidates"`
+	Ballots    int        `json:"ballots"`
+	Stages     []STVStage `json:"stages"`
+	Elected    []int      `json:"elected"` // In order of election
+	TieBreak   string     `json:"tie_break"`
+}
+
+// droopQuota is the smallest whole number of votes that only seats candidates can reach
+func droopQuota(votes Fixed, seats int) Fixed {
+	return (votes/fixedInt(seats+1))*fixedOne + fixedOne
+}
+
+// stvCount is the state shared by both transfer rules
+type stvCount struct {
+	report  *STVReport
+	status  map[int]string
+	hopeful map[int]bool
+	history []map[int]Fixed
+}
+
+// SingleTransferableVote fills seats from ranked ballots with the Droop quota,
+// moving surpluses by the given transfer rule. Candidates reaching the quota
+// are elected; when nobody does and no surplus is left to move, the candidate
+// with the fewest votes is excluded. Once the hopeful candidates only just
+// fill the remaining seats, they are all elected.
+func SingleTransferableVote(candidates []int, seats int, ballots []Ballot, transfer string) *STVReport {
+	s := &stvCount{
+		report:  &STVReport{Method: MethodSTV, Transfer: transfer, Seats: seats, Candidates: candidates, Ballots: len(ballots), Stages: []STVStage{}, Elected: []int{}, TieBreak: STVTieBreak},
+		status:  make(map[int]string, len(candidates)),
+		hopeful: make(map[int]bool, len(candidates)),
+	}
+	for _, id := range candidates {
+		s.status[id] = StatusHopeful
+		s.hopeful[id] = true
+	}
+	if transfer == TransferMeek {
+		s.meek(ballots)
+	} else {
+		s.gregory(ballots)
+	}
+	return s.report
+}
+
+// elect closes a stage with the given totals: it elects every hopeful at or
+// above the quota, fills the last seats if only enough hopefuls are left, and
+// records the stage. It reports whether the count is over.
+func (s *stvCount) elect(stage STVStage, votes map[int]Fixed, keep map[int]Fixed) bool {
+	s.history = append(s.history, votes)
+	remaining := s.report.Seats - len(s.report.Elected)
+
+	var reached []int
+	for id := range s.hopeful {
+		if votes[id] >= stage.Quota {
+			reached = append(reached, id)
+		}
+	}
+	if len(s.hopeful) <= remaining {
+		reached = s.hopefulIDs()
+	}
+	ordered, tieBroken := s.order(reached, votes)
+	if len(ordered) > remaining {
+		ordered = ordered[:remaining]
+	}
+	for _, id := range ordered {
+		delete(s.hopeful, id)
+		s.status[id] = StatusElected
+		s.report.Elected = append(s.report.Elected, id)
+	}
+	stage.Elected = ordered
+	stage.TieBroken = stage.TieBroken || tieBroken
+
+	stage.Stage = len(s.report.Stages) + 1
+	stage.Candidates = make([]STVCandidate, 0, len(s.report.Candidates))
+	for _, id := range s.report.Candidates {
+		candidate := STVCandidate{CandidateID: id, Votes: votes[id], Status: s.status[id]}
+		if k, ok := keep[id]; ok {
+			candidate.KeepFactor = &k
+		}
+		stage.Candidates = append(stage.Candidates, candidate)
+	}
+	s.report.Stages = append(s.report.Stages, stage)
+
+	return len(s.report.Elected) == s.report.Seats || len(s.hopeful) == 0
+}
+
+// hopefulIDs lists the hopeful candidates
+func (s *stvCount) hopefulIDs() []int {
+	ids := make([]int, 0, len(s.hopeful))
+	for id := range s.hopeful {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// order sorts candidates by votes, most first, applying STVTieBreak, and
+// reports whether a tie had to be broken
+func (s *stvCount) order(ids []int, votes map[int]Fixed) ([]int, bool) {
+	sort.Slice(ids, func(i, j int) bool {
+		if votes[ids[i]] != votes[ids[j]] {
+			return votes[ids[i]] > votes[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	earlier := s.history[:len(s.history)-1]
+	tieBroken := false
+	for start := 0; start < len(ids); {
+		end := start + 1
+		for end < len(ids) && votes[ids[end]] == votes[ids[start]] {
+			end++
+		}
+		if end-start > 1 {
+			tieBroken = true
+			tied := append([]int(nil), ids[start:end]...)
+			for i := start; i < end; i++ {
+				ids[i] = breakTie(tied, earlier, true)
+				tied = remove(tied, ids[i])
+			}
+		}
+		start = end
+	}
+	return ids, tieBroken
+}
+
+// lowest picks the hopeful candidate to exclude, applying STVTieBreak, and
+// reports whether a tie had to be broken
+func (s *stvCount) lowest() (int, bool) {
+	current := s.history[len(s.history)-1]
+	var tied []int
+	for id := range s.hopeful {
+		switch {
+		case len(tied) == 0 || current[id] < current[tied[0]]:
+			tied = []int{id}
+		case current[id] == current[tied[0]]:
+			tied = append(tied, id)
+		}
+	}
+	if len(tied) == 1 {
+		return tied[0], false
+	}
+	return breakTie(tied, s.history[:len(s.history)-1], false), true
+}
+
+// exclude takes a candidate out of the count
+func (s *stvCount) exclude(id int) {
+	delete(s.hopeful, id)
+	s.status[id] = StatusExcluded
+}
+
+// remove drops one candidate ID from a list
+func remove(ids []int, id int) []int {
+	for i, v := range ids {
+		if v == id {
+			return append(ids[:i], ids[i+1:]...)
+		}
+	}
+	return ids
+}
+
+// parcel is a ballot and the weight it currently carries
+type parcel struct {
+	ranking Ballot
+	weight  Fixed
+}
+
+// gregory runs the count with weighted inclusive Gregory transfers: an elected
+// candidate's whole pile moves on at transfer value surplus / total, one
+// surplus per stage, largest first; an excluded candidate's pile moves on at
+// the weights it carries.
+func (s *stvCount) gregory(ballots []Ballot) {
+	quota := droopQuota(fixedInt(len(ballots)), s.report.Seats)
+	piles := make(map[int][]parcel)
+	settled := make(map[int]bool) // Elected candidates whose surplus has moved
+	var exhausted, rounding Fixed
+
+	// pass moves parcels to their next hopeful preference, returning the
+	// votes moved by recipient and the votes that had nowhere to go
+	pass := func(parcels []parcel) (map[int]Fixed, Fixed) {
+		transfers := make(map[int]Fixed)
+		var lost Fixed
+		for _, p := range parcels {
+			if next := p.ranking.next(s.hopeful); next != 0 {
+				piles[next] = append(piles[next], p)
+				transfers[next] += p.weight
+			} else {
+				lost += p.weight
+			}
+		}
+		return transfers, lost
+	}
+
+	initial := make([]parcel, len(ballots))
+	for i, b := range ballots {
+		initial[i] = parcel{ranking: b, weight: fixedOne}
+	}
+	_, lost := pass(initial)
+	exhausted += lost
+
+	stage := STVStage{Action: ActionFirstPreferences}
+	for {
+		votes := make(map[int]Fixed)
+		for _, id := range s.report.Candidates {
+			if settled[id] {
+				votes[id] = quota
+				continue
+			}
+			for _, p := range piles[id] {
+				votes[id] += p.weight
+			}
+		}
+		stage.Quota, stage.Exhausted, stage.Rounding = quota, exhausted, rounding
+		if s.elect(stage, votes, nil) {
+			return
+		}
+		stage = STVStage{}
+
+		// Move the largest surplus not yet transferred
+		var surpluses []int
+		for _, id := range s.report.Elected {
+			if !settled[id] && votes[id] > quota {
+				surpluses = append(surpluses, id)
+			}
+		}
+		if len(surpluses) > 0 {
+			ordered, tieBroken := s.order(surpluses, votes)
+			from := ordered[0]
+			total, surplus := votes[from], votes[from]-quota
+			value := surplus.div(total)
+
+			moving := piles[from]
+			delete(piles, from)
+			settled[from] = true
+			for i := range moving {
+				moving[i].weight = moving[i].weight.mul(value)
+			}
+			transfers, lost := pass(moving)
+			var moved Fixed
+			for _, v := range transfers {
+				moved += v
+			}
+			exhausted += lost
+			rounding += surplus - moved - lost
+
+			stage = STVStage{Action: ActionSurplus, From: from, TransferValue: &value, Transfers: transfers, TieBroken: tieBroken}
+			continue
+		}
+
+		loser, tieBroken := s.lowest()
+		s.exclude(loser)
+		moving := piles[loser]
+		delete(piles, loser)
+		transfers, lost := pass(moving)
+		exhausted += lost
+		stage = STVStage{Action: ActionExclusion, Excluded: loser, Transfers: transfers, TieBroken: tieBroken}
+	}
+}
+
+// meek runs the count with Meek's method: every ballot is recounted from
+// scratch each stage, each elected candidate keeping only their keep factor of
+// the weight reaching them and passing the rest on. Keep factors are iterated
+// until elected candidates stand at the quota, which itself falls as votes
+// exhaust.
+func (s *stvCount) meek(ballots []Ballot) {
+	keep := make(map[int]Fixed, len(s.report.Candidates))
+	for _, id := range s.report.Candidates {
+		keep[id] = fixedOne
+	}
+
+	stage := STVStage{Action: ActionFirstPreferences}
+	for {
+		votes, quota, exhausted, iterations := s.converge(ballots, keep)
+		stage.Quota, stage.Exhausted, stage.Iterations = quota, exhausted, iterations
+
+		before := len(s.report.Elected)
+		shown := make(map[int]Fixed, len(keep))
+		for id, k := range keep {
+			shown[id] = k
+		}
+		if s.elect(stage, votes, shown) {
+			return
+		}
+
+		if len(s.report.Elected) > before {
+			stage = STVStage{Action: ActionSurplus}
+			continue
+		}
+		loser, tieBroken := s.lowest()
+		s.exclude(loser)
+		keep[loser] = 0
+		stage = STVStage{Action: ActionExclusion, Excluded: loser, TieBroken: tieBroken}
+	}
+}
+
+// converge distributes every ballot by the keep factors, lowering the keep
+// factors of elected candidates until their surpluses are within
+// meekTolerance. It returns the totals, the quota, the exhausted votes and the
+// number of iterations taken.
+func (s *stvCount) converge(ballots []Ballot, keep map[int]Fixed) (map[int]Fixed, Fixed, Fixed, int) {
+	total := fixedInt(len(ballots))
+	for iterations := 1; ; iterations++ {
+		votes := make(map[int]Fixed, len(keep))
+		var exhausted Fixed
+		for _, b := range ballots {
+			weight := fixedOne
+			for _, id := range b {
+				if keep[id] == 0 {
+					continue
+				}
+				kept := weight.mul(keep[id])
+				votes[id] += kept
+				weight -= kept
+				if weight == 0 {
+					break
+				}
+			}
+			exhausted += weight
+		}
+		quota := (total - exhausted).div(fixedInt(s.report.Seats+1)) + 1
+
+		var surplus Fixed
+		for _, id := range s.report.Elected {
+			if votes[id] > quota {
+				surplus += votes[id] - quota
+			}
+		}
+		if surplus <= meekTolerance || iterations == meekMaxIterations {
+			return votes, quota, exhausted, iterations
+		}
+
+		for _, id := range s.report.Elected {
+			if votes[id] == 0 {
+				continue
+			}
+			if k := mulDiv(keep[id], quota, votes[id], true); k < fixedOne {
+				keep[id] = k
+			} else {
+				keep[id] = fixedOne
+			}
+		}
+	}
+}
+
+// FinalVotes returns each candidate's votes in the last stage they were not
+// excluded in, which is what election_results records for an STV count
+func (r *STVReport) FinalVotes() map[int]Fixed {
+	final := make(map[int]Fixed, len(r.Candidates))
+	for _, stage := range r.Stages {
+		for _, c := range stage.Candidates {
+			if c.Status != StatusExcluded {
+				final[c.CandidateID] = c.Votes
+			}
+		}
+	}
+	return final
+}
@@ -0,0 +1,198 @@
+package tabulation_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Haste007/E-Voting/Backend/tabulation"
+)
+
+// TestSingleTransferableVote checks who is elected, in what order, and how
+// exclusions and ties are resolved under both transfer rules
+func TestSingleTransferableVote(t *testing.T) {
+	const oranges, pears, chocolate, strawberries, hamburgers = 1, 2, 3, 4, 5
+	food := join(
+		repeat(4, oranges),
+		repeat(2, pears, oranges),
+		repeat(8, chocolate, strawberries),
+		repeat(4, chocolate, hamburgers),
+		repeat(1, strawberries),
+		repeat(1, hamburgers),
+	)
+
+	tests := []struct {
+		name       string
+		candidates []int
+		seats      int
+		ballots    []tabulation.Ballot
+		transfer   string
+		elected    []int  // In order of election
+		excluded   []int  // In order of exclusion
+		tieBroken  []int  // Stages that needed STVTieBreak
+		quota      string // Of the first stage
+		exhausted  string // Of the last stage
+	}{
+		{
+			// The food election used to illustrate STV: Chocolate's surplus
+			// leaves Strawberries one short of the quota until Pears is out
+			name:       "food example, Gregory",
+			candidates: []int{oranges, pears, chocolate, strawberries, hamburgers},
+			seats:      3,
+			ballots:    food,
+			transfer:   tabulation.TransferGregory,
+			elected:    []int{chocolate, oranges, strawberries},
+			excluded:   []int{pears, hamburgers},
+			quota:      "6",
+			exhausted:  "3",
+		},
+		{
+			// The quota is 20/4 without rounding, so the whole transfer from
+			// Chocolate elects Strawberries straight away
+			name:       "food example, Meek",
+			candidates: []int{oranges, pears, chocolate, strawberries, hamburgers},
+			seats:      3,
+			ballots:    food,
+			transfer:   tabulation.TransferMeek,
+			elected:    []int{chocolate, strawberries, oranges},
+			excluded:   []int{pears},
+			quota:      "5.000000001",
+			exhausted:  "1.142856132", // 8/7 once keep factors converge within tolerance
+		},
+		{
+			// Gregory moves only the one vote above the whole-number quota of
+			// 2 to candidate 3; Meek's quota of 4/3 frees 5/3 of a vote for 3,
+			// which elects them ahead of candidate 1
+			name:       "Gregory keeps the integer quota",
+			candidates: []int{1, 2, 3, 4},
+			seats:      2,
+			ballots:    join(repeat(1, 4, 3), repeat(2, 4, 3, 1), repeat(1, 1, 4, 3)),
+			transfer:   tabulation.TransferGregory,
+			elected:    []int{4, 1},
+			excluded:   []int{2, 3},
+			quota:      "2",
+			exhausted:  "0.333333333",
+		},
+		{
+			name:       "Meek lowers the quota",
+			candidates: []int{1, 2, 3, 4},
+			seats:      2,
+			ballots:    join(repeat(1, 4, 3), repeat(2, 4, 3, 1), repeat(1, 1, 4, 3)),
+			transfer:   tabulation.TransferMeek,
+			elected:    []int{4, 3},
+			quota:      "1.333333334",
+			exhausted:  "0",
+		},
+		{
+			name:       "candidates reaching the quota together are elected by lowest ID",
+			candidates: []int{1, 2, 3},
+			seats:      2,
+			ballots:    join(repeat(3, 2), repeat(3, 1), repeat(1, 3)),
+			transfer:   tabulation.TransferGregory,
+			elected:    []int{1, 2},
+			tieBroken:  []int{1},
+			quota:      "3",
+			exhausted:  "0",
+		},
+		{
+			// 2 and 3 tie at stage 3; 2 had fewer votes at stage 1
+			name:       "exclusion tie broken by an earlier stage",
+			candidates: []int{1, 2, 3, 4},
+			seats:      2,
+			ballots:    join(repeat(5, 1, 2), repeat(2, 3), repeat(1, 2), repeat(1, 4)),
+			transfer:   tabulation.TransferGregory,
+			elected:    []int{1, 3},
+			excluded:   []int{4, 2},
+			tieBroken:  []int{4},
+			quota:      "4",
+			exhausted:  "3",
+		},
+		{
+			name:       "exhausted ballots",
+			candidates: []int{1, 2, 3},
+			seats:      1,
+			ballots:    join(repeat(3, 1), repeat(2, 2), repeat(2, 3)),
+			transfer:   tabulation.TransferGregory,
+			elected:    []int{1},
+			excluded:   []int{3, 2},
+			tieBroken:  []int{2},
+			quota:      "4",
+			exhausted:  "4",
+		},
+		{
+			name:       "zero ballots, Gregory",
+			candidates: []int{1, 2, 3},
+			seats:      2,
+			transfer:   tabulation.TransferGregory,
+			elected:    []int{1, 2},
+			excluded:   []int{3},
+			tieBroken:  []int{2},
+			quota:      "1",
+			exhausted:  "0",
+		},
+		{
+			name:       "zero ballots, Meek",
+			candidates: []int{1, 2, 3},
+			seats:      2,
+			transfer:   tabulation.TransferMeek,
+			elected:    []int{1, 2},
+			excluded:   []int{3},
+			tieBroken:  []int{2},
+			quota:      "0.000000001",
+			exhausted:  "0",
+		},
+		{
+			name:       "more seats than candidates",
+			candidates: []int{1, 2},
+			seats:      3,
+			ballots:    join(repeat(1, 2), repeat(2, 1)),
+			transfer:   tabulation.TransferGregory,
+			elected:    []int{1, 2},
+			quota:      "1",
+			exhausted:  "0",
+		},
+		{
+			name:       "as many seats as candidates",
+			candidates: []int{1, 2},
+			seats:      2,
+			ballots:    join(repeat(2, 2), repeat(1, 1)),
+			transfer:   tabulation.TransferMeek,
+			elected:    []int{2, 1},
+			quota:      "1.000000001",
+			exhausted:  "0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := tabulation.SingleTransferableVote(tt.candidates, tt.seats, tt.ballots, tt.transfer)
+
+			if !reflect.DeepEqual(report.Elected, tt.elected) {
+				t.Errorf("elected %v, want %v", report.Elected, tt.elected)
+			}
+			var excluded, tieBroken []int
+			for _, stage := range report.Stages {
+				if stage.Excluded != 0 {
+					excluded = append(excluded, stage.Excluded)
+				}
+				if stage.TieBroken {
+					tieBroken = append(tieBroken, stage.Stage)
+				}
+			}
+			if !reflect.DeepEqual(excluded, tt.excluded) {
+				t.Errorf("excluded %v, want %v", excluded, tt.excluded)
+			}
+			if !reflect.DeepEqual(tieBroken, tt.tieBroken) {
+				t.Errorf("ties broken at stages %v, want %v", tieBroken, tt.tieBroken)
+			}
+			if len(report.Stages) == 0 {
+				t.Fatal("no stages")
+			}
+			if quota := report.Stages[0].Quota.String(); quota != tt.quota {
+				t.Errorf("quota = %s, want %s", quota, tt.quota)
+			}
+			if exhausted := report.Stages[len(report.Stages)-1].Exhausted.String(); exhausted != tt.exhausted {
+				t.Errorf("exhausted = %s, want %s", exhausted, tt.exhausted)
+			}
+		})
+	}
+}
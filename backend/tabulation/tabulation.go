@@ -0,0 +1,210 @@
+// Package tabulation counts ranked ballots.
+//
+// The counting methods, instant runoff for single seats and single transferable
+// vote for several, are pure functions over candidate IDs and ballots, so a
+// count can be replayed by anyone from the ballots published on the bulletin
+// board. CountConstituency loads a constituency's ranked ballots, runs the
+// count and stores the full report; the tally package turns its totals into
+// results.
+package tabulation
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Haste007/E-Voting/Backend/ballot"
+)
+
+// Counting methods, stored with each report
+const (
+	MethodIRV = "irv"
+	MethodSTV = "stv"
+)
+
+// Ballot is one voter's ranking of candidate IDs, most preferred first
+type Ballot []int
+
+// next returns the highest-ranked continuing candidate on the ballot, or 0 if
+// the ballot is exhausted
+func (b Ballot) next(continuing map[int]bool) int {
+	for _, id := range b {
+		if continuing[id] {
+			return id
+		}
+	}
+	return 0
+}
+
+// breakTie resolves a tie among candidates by looking back through earlier
+// rounds, latest first, for the first one in which their totals differ. It
+// picks the candidate with the fewest votes there, or the most if most is set.
+// Candidates still tied fall back to candidate ID: the highest when picking the
+// fewest, the lowest when picking the most.
+func breakTie[V ~int | ~int64](tied []int, history []map[int]V, most bool) int {
+	for i := len(history) - 1; i >= 0 && len(tied) > 1; i-- {
+		votes := history[i]
+		best := votes[tied[0]]
+		for _, id := range tied[1:] {
+			if (most && votes[id] > best) || (!most && votes[id] < best) {
+				best = votes[id]
+			}
+		}
+		var next []int
+		for _, id := range tied {
+			if votes[id] == best {
+				next = append(next, id)
+			}
+		}
+		tied = next
+	}
+
+	pick := tied[0]
+	for _, id := range tied[1:] {
+		if (most && id < pick) || (!most && id > pick) {
+			pick = id
+		}
+	}
+	return pick
+}
+
+// constituencyBallots loads the counted ranked ballots of one constituency in
+// the order they are published on the bulletin board at close
+func constituencyBallots(q ballot.Querier, electionID, constituencyID int) ([]Ballot, error) {
+	query := `
+        SELECT ranking
+        FROM ballot_box
+        WHERE election_id = $1 AND constituency_id = $2 AND choice = 'ranked' AND NOT replaced
+        ORDER BY id
+    `
+	rows, err := q.Query(query, electionID, constituencyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ballots []Ballot
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var ranking Ballot
+		if err := json.Unmarshal(data, &ranking); err != nil {
+			return nil, err
+		}
+		ballots = append(ballots, ranking)
+	}
+	return ballots, rows.Err()
+}
+
+// candidateIDs lists the candidates on a constituency's ballot in ballot order
+func candidateIDs(q ballot.Querier, electionID, constituencyID int) ([]int, error) {
+	options, err := ballot.BallotOptions(q, electionID, constituencyID)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	for _, option := range options {
+		if option.Choice == ballot.ChoiceCandidate {
+			ids = append(ids, option.CandidateID)
+		}
+	}
+	return ids, nil
+}
+
+// CountConstituency counts one constituency of a ranked election, by instant
+// runoff or, for STV elections, by single transferable vote over the
+// constituency's seats. It stores the report in tabulation_reports, replacing
+// any earlier one, and returns each candidate's votes in the last round or
+// stage they were counted in along with the winners.
+func CountConstituency(tx *sql.Tx, electionID, constituencyID int) (map[int]int, map[int]bool, error) {
+	var ballotType string
+	var transfer sql.NullString
+	var seats int
+	query := `
+        SELECT e.ballot_type, e.stv_transfer, c.seats
+        FROM elections e, constituencies c
+        WHERE e.id = $1 AND c.id = $2
+    `
+	if err := tx.QueryRow(query, electionID, constituencyID).Scan(&ballotType, &transfer, &seats); err != nil {
+		return nil, nil, err
+	}
+
+	candidates, err := candidateIDs(tx, electionID, constituencyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	ballots, err := constituencyBallots(tx, electionID, constituencyID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	votes := make(map[int]int)
+	elected := make(map[int]bool)
+	if ballotType == ballot.TypeSTV {
+		report := SingleTransferableVote(candidates, seats, ballots, transfer.String)
+		if err := storeReport(tx, electionID, constituencyID, report.Method, report); err != nil {
+			return nil, nil, err
+		}
+		for candidateID, v := range report.FinalVotes() {
+			votes[candidateID] = v.Int()
+		}
+		for _, candidateID := range report.Elected {
+			elected[candidateID] = true
+		}
+	} else {
+		report := InstantRunoff(candidates, ballots)
+		if err := storeReport(tx, electionID, constituencyID, report.Method, report); err != nil {
+			return nil, nil, err
+		}
+		votes = report.LastVotes()
+		elected[report.Winner] = true
+	}
+	return votes, elected, nil
+}
+
+// storeReport records the count report of one constituency
+func storeReport(tx *sql.Tx, electionID, constituencyID int, method string, report interface{}) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	query := `
+        INSERT INTO tabulation_reports (election_id, constituency_id, method, report)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (election_id, constituency_id) DO UPDATE SET method = EXCLUDED.method, report = EXCLUDED.report
+    `
+	if _, err := tx.Exec(query, electionID, constituencyID, method, string(data)); err != nil {
+		return fmt.Errorf("storing %s report for constituency %d: %w", method, constituencyID, err)
+	}
+	return nil
+}
+
+// Report is a stored count report as served to clients
+type Report struct {
+	ConstituencyID int             `json:"constituency_id"`
+	Method         string          `json:"method"`
+	Report         json.RawMessage `json:"report"`
+}
+
+// Reports returns the stored count reports of an election by constituency
+func Reports(q ballot.Querier, electionID interface{}) ([]Report, error) {
+	rows, err := q.Query("SELECT constituency_id, method, report FROM tabulation_reports WHERE election_id = $1 ORDER BY constituency_id", electionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []Report{}
+	for rows.Next() {
+		var report Report
+		var data []byte
+		if err := rows.Scan(&report.ConstituencyID, &report.Method, &data); err != nil {
+			return nil, err
+		}
+		report.Report = json.RawMessage(data)
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
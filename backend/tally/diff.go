@@ -0,0 +1,72 @@
+package tally
+
+// Change compares one result line across two snapshots. A line missing from
+// either side counts as zero votes, not elected.
+type Change struct {
+	Choice          string `json:"choice"`
+	CandidateID     *int   `json:"candidate_id"`
+	PreviousVotes   int    `json:"previous_votes"`
+	Votes           int    `json:"votes"`
+	Difference      int    `json:"difference"`
+	PreviousElected bool   `json:"previous_elected"`
+	Elected         bool   `json:"elected"`
+}
+
+// Diff is the outcome of a recount against the constituency's previous snapshot
+type Diff struct {
+	ConstituencyID  int      `json:"constituency_id"`
+	PreviousVersion int      `json:"previous_version"` // 0 if the election was tallied before snapshots were kept
+	Version         int      `json:"version"`
+	InputChanged    bool     `json:"input_changed"` // The set of ballots counted differs
+	Changed         bool     `json:"changed"`       // Any line's votes or winner differs
+	Lines           []Change `json:"lines"`
+}
+
+// lineKey identifies a result line within a constituency
+func lineKey(line Line) int {
+	if line.CandidateID == nil {
+		return 0
+	}
+	return *line.CandidateID
+}
+
+// compare lines up the result of a constituency before and after a recount
+func compare(previousVersion int, previous Constituency, version int, current Constituency) Diff {
+	diff := Diff{
+		ConstituencyID:  current.ConstituencyID,
+		PreviousVersion: previousVersion,
+		Version:         version,
+		InputChanged:    previous.InputHash != "" && previous.InputHash != current.InputHash,
+		Lines:           []Change{},
+	}
+
+	index := make(map[int]int)
+	add := func(line Line) *Change {
+		key := lineKey(line)
+		if i, ok := index[key]; ok {
+			return &diff.Lines[i]
+		}
+		index[key] = len(diff.Lines)
+		diff.Lines = append(diff.Lines, Change{Choice: line.Choice, CandidateID: line.CandidateID})
+		return &diff.Lines[len(diff.Lines)-1]
+	}
+	for _, line := range previous.Lines {
+		change := add(line)
+		change.PreviousVotes = line.Votes
+		change.PreviousElected = line.Elected
+	}
+	for _, line := range current.Lines {
+		change := add(line)
+		change.Votes = line.Votes
+		change.Elected = line.Elected
+	}
+
+	for i := range diff.Lines {
+		change := &diff.Lines[i]
+		change.Difference = change.Votes - change.PreviousVotes
+		if change.Difference != 0 || change.Elected != change.PreviousElected {
+			diff.Changed = true
+		}
+	}
+	return diff
+}
@@ -0,0 +1,120 @@
+package tally
+
+import (
+	"reflect"
+	"testing"
+)
+
+// candidate returns a pointer to a candidate ID for a result line
+func candidate(id int) *int {
+	return &id
+}
+
+// TestCompare checks the recount diff line by line, including lines present on
+// only one side and snapshots taken before input hashes were kept
+func TestCompare(t *testing.T) {
+	nota := Line{Choice: "None of the above", Votes: 4}
+	alice := Line{Choice: "Alice", CandidateID: candidate(1), Votes: 50, Elected: true}
+	bob := Line{Choice: "Bob", CandidateID: candidate(2), Votes: 48}
+	carol := Line{Choice: "Carol", CandidateID: candidate(3), Votes: 2}
+	before := Constituency{ConstituencyID: 7, InputHash: "aa", Lines: []Line{alice, bob, nota}}
+
+	with := func(line Line, votes int, elected bool) Line {
+		line.Votes, line.Elected = votes, elected
+		return line
+	}
+
+	tests := []struct {
+		name         string
+		previous     Constituency
+		current      Constituency
+		inputChanged bool
+		changed      bool
+		lines        []Change
+	}{
+		{
+			name:     "unchanged",
+			previous: before,
+			current:  Constituency{ConstituencyID: 7, InputHash: "aa", Lines: []Line{alice, bob, nota}},
+			lines: []Change{
+				{Choice: "Alice", CandidateID: candidate(1), PreviousVotes: 50, Votes: 50, PreviousElected: true, Elected: true},
+				{Choice: "Bob", CandidateID: candidate(2), PreviousVotes: 48, Votes: 48},
+				{Choice: "None of the above", PreviousVotes: 4, Votes: 4},
+			},
+		},
+		{
+			name:         "votes changed",
+			previous:     before,
+			current:      Constituency{ConstituencyID: 7, InputHash: "bb", Lines: []Line{with(alice, 49, true), with(bob, 48, false), with(nota, 6, false)}},
+			inputChanged: true,
+			changed:      true,
+			lines: []Change{
+				{Choice: "Alice", CandidateID: candidate(1), PreviousVotes: 50, Votes: 49, Difference: -1, PreviousElected: true, Elected: true},
+				{Choice: "Bob", CandidateID: candidate(2), PreviousVotes: 48, Votes: 48},
+				{Choice: "None of the above", PreviousVotes: 4, Votes: 6, Difference: 2},
+			},
+		},
+		{
+			// A winner decided differently on the same votes, such as a tie
+			// broken after a correction, still counts as a change
+			name:     "winner changed",
+			previous: before,
+			current:  Constituency{ConstituencyID: 7, InputHash: "aa", Lines: []Line{with(alice, 50, false), with(bob, 48, true), nota}},
+			changed:  true,
+			lines: []Change{
+				{Choice: "Alice", CandidateID: candidate(1), PreviousVotes: 50, Votes: 50, PreviousElected: true},
+				{Choice: "Bob", CandidateID: candidate(2), PreviousVotes: 48, Votes: 48, Elected: true},
+				{Choice: "None of the above", PreviousVotes: 4, Votes: 4},
+			},
+		},
+		{
+			name:         "lines on one side only",
+			previous:     before,
+			current:      Constituency{ConstituencyID: 7, InputHash: "bb", Lines: []Line{carol, alice, nota}},
+			inputChanged: true,
+			changed:      true,
+			lines: []Change{
+				{Choice: "Alice", CandidateID: candidate(1), PreviousVotes: 50, Votes: 50, PreviousElected: true, Elected: true},
+				{Choice: "Bob", CandidateID: candidate(2), PreviousVotes: 48, Difference: -48},
+				{Choice: "None of the above", PreviousVotes: 4, Votes: 4},
+				{Choice: "Carol", CandidateID: candidate(3), Votes: 2, Difference: 2},
+			},
+		},
+		{
+			name:     "previous snapshot without an input hash",
+			previous: Constituency{ConstituencyID: 7, Lines: []Line{alice, bob}},
+			current:  Constituency{ConstituencyID: 7, InputHash: "aa", Lines: []Line{alice, bob}},
+			lines: []Change{
+				{Choice: "Alice", CandidateID: candidate(1), PreviousVotes: 50, Votes: 50, PreviousElected: true, Elected: true},
+				{Choice: "Bob", CandidateID: candidate(2), PreviousVotes: 48, Votes: 48},
+			},
+		},
+		{
+			name:    "no previous result",
+			current: Constituency{ConstituencyID: 7, InputHash: "aa", Lines: []Line{alice}},
+			changed: true,
+			lines: []Change{
+				{Choice: "Alice", CandidateID: candidate(1), Votes: 50, Difference: 50, Elected: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := compare(2, tt.previous, 3, tt.current)
+
+			if diff.ConstituencyID != 7 || diff.PreviousVersion != 2 || diff.Version != 3 {
+				t.Errorf("diff of constituency %d, versions %d to %d; want 7, 2 to 3", diff.ConstituencyID, diff.PreviousVersion, diff.Version)
+			}
+			if diff.InputChanged != tt.inputChanged {
+				t.Errorf("input changed = %v, want %v", diff.InputChanged, tt.inputChanged)
+			}
+			if diff.Changed != tt.changed {
+				t.Errorf("changed = %v, want %v", diff.Changed, tt.changed)
+			}
+			if !reflect.DeepEqual(diff.Lines, tt.lines) {
+				t.Errorf("lines %+v, want %+v", diff.Lines, tt.lines)
+			}
+		})
+	}
+}
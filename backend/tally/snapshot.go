@@ -0,0 +1,207 @@
+package tally
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Haste007/E-Voting/Backend/ballot"
+)
+
+// ErrNotFound is returned for a snapshot that does not exist
+var ErrNotFound = errors.New("tally run not found")
+
+// Line is one result line of a constituency
+type Line struct {
+	Choice      string `json:"choice"`
+	CandidateID *int   `json:"candidate_id"` // NULL for NOTA
+	Votes       int    `json:"votes"`
+	Elected     bool   `json:"elected"`
+}
+
+// Constituency is the result of one constituency in a snapshot
+type Constituency struct {
+	ConstituencyID int    `json:"constituency_id"`
+	InputHash      string `json:"input_hash"` // SHA-256 over the content of the ballots counted
+	Lines          []Line `json:"lines"`
+}
+
+// Snapshot is one recorded tally run, recount or correction
+type Snapshot struct {
+	Version        int            `json:"version"` // Numbers an election's snapshots from 1
+	Kind           string         `json:"kind"`
+	ConstituencyID *int           `json:"constituency_id"` // Set when only one constituency was counted or corrected
+	InputHash      string         `json:"input_hash"`      // SHA-256 over the input hashes of its constituencies
+	Reason         string         `json:"reason"`
+	Operator       string         `json:"operator"`
+	RunAt          string         `json:"run_at"`
+	Constituencies []Constituency `json:"constituencies,omitempty"` // Left out of listings
+}
+
+// record snapshots the current result lines of the given constituencies
+func record(tx *sql.Tx, electionID int, kind string, constituencyIDs []int, constituencyID *int, reason, operator string) (Snapshot, error) {
+	snapshot := Snapshot{Kind: kind, ConstituencyID: constituencyID, Reason: reason, Operator: operator, Constituencies: []Constituency{}}
+	all := sha256.New()
+	for _, id := range constituencyIDs {
+		hash, err := inputHash(tx, electionID, id)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		lines, err := resultLines(tx, electionID, id)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		fmt.Fprintf(all, "%d %s\n", id, hash)
+		snapshot.Constituencies = append(snapshot.Constituencies, Constituency{ConstituencyID: id, InputHash: hash, Lines: lines})
+	}
+	snapshot.InputHash = hex.EncodeToString(all.Sum(nil))
+
+	data, err := json.Marshal(snapshot.Constituencies)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	// Callers hold the election's row lock, so versions cannot collide
+	query := `
+        INSERT INTO tally_runs (election_id, version, kind, constituency_id, input_hash, reason, operator, results)
+        SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5, $6, $7
+        FROM tally_runs
+        WHERE election_id = $1
+        RETURNING version, run_at
+    `
+	err = tx.QueryRow(query, electionID, kind, constituencyID, snapshot.InputHash, reason, operator, string(data)).Scan(&snapshot.Version, &snapshot.RunAt)
+	return snapshot, err
+}
+
+// inputHash hashes the content of the ballots counted in a constituency,
+// ordered by their random IDs
+func inputHash(q ballot.Querier, electionID, constituencyID int) (string, error) {
+	query := `
+        SELECT id, COALESCE(choice, ''), COALESCE(candidate_id, 0), COALESCE(ranking::TEXT, ''), COALESCE(encrypted_choice::TEXT, '')
+        FROM ballot_box
+        WHERE election_id = $1 AND constituency_id = $2 AND NOT replaced
+        ORDER BY id
+    `
+	rows, err := q.Query(query, electionID, constituencyID)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	h := sha256.New()
+	fmt.Fprintf(h, "election:%d/constituency:%d\n", electionID, constituencyID)
+	for rows.Next() {
+		var id, choice, ranking, encryptedChoice string
+		var candidateID int
+		if err := rows.Scan(&id, &choice, &candidateID, &ranking, &encryptedChoice); err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s %s %d %s %s\n", id, choice, candidateID, ranking, encryptedChoice)
+	}
+	return hex.EncodeToString(h.Sum(nil)), rows.Err()
+}
+
+// resultLines reads a constituency's current result lines, NOTA last
+func resultLines(q ballot.Querier, electionID, constituencyID int) ([]Line, error) {
+	query := `
+        SELECT choice, candidate_id, total_votes, elected
+        FROM election_results
+        WHERE election_id = $1 AND constituency_id = $2
+        ORDER BY candidate_id NULLS LAST
+    `
+	rows, err := q.Query(query, electionID, constituencyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []Line{}
+	for rows.Next() {
+		var line Line
+		if err := rows.Scan(&line.Choice, &line.CandidateID, &line.Votes, &line.Elected); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
+// Latest returns the version and result of a constituency's most recent
+// snapshot. Elections tallied before snapshots were kept have none, so their
+// current result lines stand in, with version 0 and no input hash.
+func Latest(q ballot.Querier, electionID, constituencyID int) (int, Constituency, error) {
+	query := `
+        SELECT version, results
+        FROM tally_runs
+        WHERE election_id = $1 AND (constituency_id IS NULL OR constituency_id = $2)
+        ORDER BY version DESC
+        LIMIT 1
+    `
+	var version int
+	var data []byte
+	err := q.QueryRow(query, electionID, constituencyID).Scan(&version, &data)
+	if err == sql.ErrNoRows {
+		lines, err := resultLines(q, electionID, constituencyID)
+		return 0, Constituency{ConstituencyID: constituencyID, Lines: lines}, err
+	} else if err != nil {
+		return 0, Constituency{}, err
+	}
+
+	var constituencies []Constituency
+	if err := json.Unmarshal(data, &constituencies); err != nil {
+		return 0, Constituency{}, err
+	}
+	for _, constituency := range constituencies {
+		if constituency.ConstituencyID == constituencyID {
+			return version, constituency, nil
+		}
+	}
+	// The constituency joined the election after that run
+	return version, Constituency{ConstituencyID: constituencyID, Lines: []Line{}}, nil
+}
+
+// List returns an election's snapshots without their result lines, newest first
+func List(q ballot.Querier, electionID int) ([]Snapshot, error) {
+	query := `
+        SELECT version, kind, constituency_id, input_hash, reason, operator, run_at
+        FROM tally_runs
+        WHERE election_id = $1
+        ORDER BY version DESC
+    `
+	rows, err := q.Query(query, electionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := []Snapshot{}
+	for rows.Next() {
+		var snapshot Snapshot
+		if err := rows.Scan(&snapshot.Version, &snapshot.Kind, &snapshot.ConstituencyID, &snapshot.InputHash, &snapshot.Reason, &snapshot.Operator, &snapshot.RunAt); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, rows.Err()
+}
+
+// Get returns one snapshot of an election with its result lines
+func Get(q ballot.Querier, electionID, version int) (Snapshot, error) {
+	query := `
+        SELECT version, kind, constituency_id, input_hash, reason, operator, run_at, results
+        FROM tally_runs
+        WHERE election_id = $1 AND version = $2
+    `
+	var snapshot Snapshot
+	var data []byte
+	err := q.QueryRow(query, electionID, version).Scan(&snapshot.Version, &snapshot.Kind, &snapshot.ConstituencyID, &snapshot.InputHash,
+		&snapshot.Reason, &snapshot.Operator, &snapshot.RunAt, &data)
+	if err == sql.ErrNoRows {
+		return Snapshot{}, ErrNotFound
+	} else if err != nil {
+		return Snapshot{}, err
+	}
+	return snapshot, json.Unmarshal(data, &snapshot.Constituencies)
+}
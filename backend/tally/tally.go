@@ -0,0 +1,201 @@
+// Package tally turns an election's counted ballots into election_results.
+//
+// A tally can be re-run at any time after close: each run replaces the result
+// lines of the constituencies it covers instead of adding to them, so running
+// it twice gives the same results. Every run, and every manual correction, is
+// kept in tally_runs as a numbered snapshot of the lines it produced, with a
+// hash of the ballots it counted and the admin who ran it. An official recount
+// of one constituency counts its ballots again and is compared line by line
+// with the constituency's previous snapshot.
+package tally
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/Haste007/E-Voting/Backend/ballot"
+	"github.com/Haste007/E-Voting/Backend/lifecycle"
+	"github.com/Haste007/E-Voting/Backend/seats"
+	"github.com/Haste007/E-Voting/Backend/tabulation"
+)
+
+// Kinds of snapshot
+const (
+	KindTally      = "tally"
+	KindRecount    = "recount"
+	KindCorrection = "correction"
+)
+
+// CodeNotInElection is returned for a recount of a constituency the election does not cover
+const CodeNotInElection = "CONSTITUENCY_NOT_IN_ELECTION"
+
+// ErrNotDecrypted is returned when an encrypted election's aggregates have not been decrypted yet
+var ErrNotDecrypted = errors.New("encrypted tallies have not been decrypted yet")
+
+// Run counts every constituency of an election, replaces its results and
+// reserved seat allocations, and records the run as a snapshot. It is called
+// when polls close, once any trustee decryption is done, and again whenever a
+// tallied election's count is re-run.
+func Run(tx *sql.Tx, electionID int, reason, operator string) (Snapshot, error) {
+	if _, err := lifecycle.CurrentStatus(tx, electionID, "FOR UPDATE"); err != nil {
+		return Snapshot{}, err
+	}
+	constituencyIDs, err := electionConstituencies(tx, electionID)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if err := count(tx, electionID, constituencyIDs); err != nil {
+		return Snapshot{}, err
+	}
+	return record(tx, electionID, KindTally, constituencyIDs, nil, reason, operator)
+}
+
+// Recount counts the ballots of one constituency of a tallied election again,
+// replacing its results, and returns the new snapshot with its differences
+// from the constituency's previous one
+func Recount(tx *sql.Tx, electionID, constituencyID int, reason, operator string) (Snapshot, Diff, error) {
+	if _, err := lifecycle.Require(tx, electionID, lifecycle.OpRecount, "FOR UPDATE"); err != nil {
+		return Snapshot{}, Diff{}, err
+	}
+	var ok bool
+	query := "SELECT EXISTS (SELECT 1 FROM election_constituencies WHERE election_id = $1 AND constituency_id = $2)"
+	if err := tx.QueryRow(query, electionID, constituencyID).Scan(&ok); err != nil {
+		return Snapshot{}, Diff{}, err
+	}
+	if !ok {
+		return Snapshot{}, Diff{}, &ballot.ValidationError{Code: CodeNotInElection, Message: "Constituency is not part of this election", Status: http.StatusUnprocessableEntity}
+	}
+
+	previousVersion, previous, err := Latest(tx, electionID, constituencyID)
+	if err != nil {
+		return Snapshot{}, Diff{}, err
+	}
+	if err := count(tx, electionID, []int{constituencyID}); err != nil {
+		return Snapshot{}, Diff{}, err
+	}
+	snapshot, err := record(tx, electionID, KindRecount, []int{constituencyID}, &constituencyID, reason, operator)
+	if err != nil {
+		return Snapshot{}, Diff{}, err
+	}
+	return snapshot, compare(previousVersion, previous, snapshot.Version, snapshot.Constituencies[0]), nil
+}
+
+// Correct records a snapshot of one constituency after its results were corrected by hand
+func Correct(tx *sql.Tx, electionID, constituencyID int, reason, operator string) (Snapshot, error) {
+	return record(tx, electionID, KindCorrection, []int{constituencyID}, &constituencyID, reason, operator)
+}
+
+// count replaces the result lines of the given constituencies from their
+// counted ballots, then redoes the winners and reserved seats that follow from them
+func count(tx *sql.Tx, electionID int, constituencyIDs []int) error {
+	encrypted, err := ballot.IsEncrypted(tx, electionID)
+	if err != nil {
+		return err
+	}
+	ballotType, err := ballot.BallotType(tx, electionID)
+	if err != nil {
+		return err
+	}
+	ranked := ballot.IsRanked(ballotType)
+
+	for _, constituencyID := range constituencyIDs {
+		if _, err := tx.Exec("DELETE FROM election_results WHERE election_id = $1 AND constituency_id = $2", electionID, constituencyID); err != nil {
+			return err
+		}
+		switch {
+		case encrypted:
+			err = countEncrypted(tx, electionID, constituencyID)
+		case ranked:
+			err = countRanked(tx, electionID, constituencyID)
+		default:
+			err = countPlaintext(tx, electionID, constituencyID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Ranked counts decide their own winners
+	if !ranked {
+		if err := ballot.MarkPluralityWinners(tx, electionID); err != nil {
+			return err
+		}
+	}
+	return seats.AllocateElection(tx, electionID)
+}
+
+// countPlaintext adds up a constituency's plaintext single-choice ballots
+func countPlaintext(tx *sql.Tx, electionID, constituencyID int) error {
+	query := `
+        INSERT INTO election_results (election_id, constituency_id, choice, candidate_id, total_votes)
+        SELECT b.election_id, b.constituency_id, b.choice, b.candidate_id, COUNT(b.id)
+        FROM ballot_box b
+        WHERE b.election_id = $1 AND b.constituency_id = $2 AND NOT b.replaced
+        GROUP BY b.election_id, b.constituency_id, b.choice, b.candidate_id
+    `
+	_, err := tx.Exec(query, electionID, constituencyID)
+	return err
+}
+
+// countRanked runs a constituency's ranked count
+func countRanked(tx *sql.Tx, electionID, constituencyID int) error {
+	votes, elected, err := tabulation.CountConstituency(tx, electionID, constituencyID)
+	if err != nil {
+		return err
+	}
+	query := `
+        INSERT INTO election_results (election_id, constituency_id, choice, candidate_id, total_votes, elected)
+        VALUES ($1, $2, $3, $4, $5, $6)
+    `
+	for candidateID, v := range votes {
+		if _, err := tx.Exec(query, electionID, constituencyID, ballot.ChoiceCandidate, candidateID, v, elected[candidateID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countEncrypted copies a constituency's decrypted counts, after checking that
+// its encrypted ballots still add up to the aggregates that were decrypted
+func countEncrypted(tx *sql.Tx, electionID, constituencyID int) error {
+	var pending bool
+	pendingQuery := "SELECT EXISTS (SELECT 1 FROM encrypted_tallies WHERE election_id = $1 AND constituency_id = $2 AND total_votes IS NULL)"
+	if err := tx.QueryRow(pendingQuery, electionID, constituencyID).Scan(&pending); err != nil {
+		return err
+	}
+	if pending {
+		return ErrNotDecrypted
+	}
+	if err := ballot.VerifyAggregates(tx, electionID, constituencyID); err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO election_results (election_id, constituency_id, choice, candidate_id, total_votes)
+        SELECT election_id, constituency_id, choice, candidate_id, total_votes
+        FROM encrypted_tallies
+        WHERE election_id = $1 AND constituency_id = $2
+    `
+	_, err := tx.Exec(query, electionID, constituencyID)
+	return err
+}
+
+// electionConstituencies lists the constituencies an election covers
+func electionConstituencies(q ballot.Querier, electionID int) ([]int, error) {
+	rows, err := q.Query("SELECT constituency_id FROM election_constituencies WHERE election_id = $1 ORDER BY constituency_id", electionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
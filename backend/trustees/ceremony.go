@@ -0,0 +1,355 @@
+// Package trustees runs the threshold key ceremony of encrypted elections.
+//
+// Trustees enroll while an election is a draft. Once it is scheduled, each of
+// the N trustees uploads a dealing (commitments to a random polynomial) and one
+// encrypted share for every other trustee. When all dealings are in, a complaint
+// window opens: a trustee whose share does not match its dealer's commitments
+// files a complaint, and the dealer must reveal that share publicly. Dealers who
+// do not, or whose revealed share is wrong, are disqualified. Once the window
+// ends, only the joint public key of the qualified dealers is stored; no one,
+// the server included, holds the secret.
+// After the election closes, any k trustees submit partial decryptions of the
+// tally with proofs, and the results are combined from those.
+package trustees
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/Haste007/E-Voting/Backend/ballot"
+	"github.com/Haste007/E-Voting/Backend/elgamal"
+	"github.com/Haste007/E-Voting/Backend/utils"
+)
+
+// DefaultComplaintWindow is how long trustees have to complain about their
+// shares, and accused dealers then have to answer, unless TRUSTEE_COMPLAINT_WINDOW is set
+const DefaultComplaintWindow = 24 * time.Hour
+
+// completionInterval is how often ceremonies whose complaint window ended are looked for
+const completionInterval = time.Minute
+
+// complaintWindow is the configured length of each complaint phase
+var complaintWindow = DefaultComplaintWindow
+
+// dueCeremony matches trustee elections whose complaint window is over: the
+// time to answer complaints ran out, or complaints closed with every one of
+// them answered. A ceremony left with fewer qualified trustees than its
+// threshold never matches again and has to be reset by unscheduling.
+const dueCeremony = `status = 'scheduled'
+        AND NOT EXISTS (SELECT 1 FROM election_keys k WHERE k.election_id = elections.id)
+        AND (trustee_reveals_close_at <= NOW() OR (trustee_complaints_close_at <= NOW()
+            AND NOT EXISTS (SELECT 1 FROM trustee_complaints c WHERE c.election_id = elections.id AND c.revealed_share IS NULL)))
+        AND (SELECT COUNT(*) FROM trustees t WHERE t.election_id = elections.id AND NOT t.disqualified) >= trustee_threshold`
+
+// ErrNotEnoughTrustees is returned when a trustee election is scheduled with fewer
+// enrolled trustees than its threshold
+var ErrNotEnoughTrustees = errors.New("fewer trustees are enrolled than the decryption threshold")
+
+// DealingContext binds a trustee's dealing proof to one election and trustee index
+func DealingContext(electionID, index int) string {
+	return fmt.Sprintf("election:%d/trustee:%d", electionID, index)
+}
+
+// ShareContext binds an encrypted share to its dealer and recipient
+func ShareContext(electionID, dealer, recipient int) string {
+	return fmt.Sprintf("%s/share:%d", DealingContext(electionID, dealer), recipient)
+}
+
+// Threshold returns the number of trustees needed to decrypt, or 0 if the
+// election does not use trustees
+func Threshold(q ballot.Querier, electionID interface{}) (int, error) {
+	var threshold sql.NullInt64
+	err := q.QueryRow("SELECT trustee_threshold FROM elections WHERE id = $1", electionID).Scan(&threshold)
+	return int(threshold.Int64), err
+}
+
+// RequireEnrollment returns ErrNotEnoughTrustees unless a trustee election has
+// at least its threshold of trustees; other elections always pass
+func RequireEnrollment(q ballot.Querier, electionID interface{}) error {
+	threshold, err := Threshold(q, electionID)
+	if err != nil || threshold == 0 {
+		return err
+	}
+
+	var enrolled int
+	if err := q.QueryRow("SELECT COUNT(*) FROM trustees WHERE election_id = $1", electionID).Scan(&enrolled); err != nil {
+		return err
+	}
+	if enrolled < threshold {
+		return ErrNotEnoughTrustees
+	}
+	return nil
+}
+
+// ResetCeremony discards the dealings, shares and joint key of a trustee
+// election so the ceremony can run again for a changed set of trustees
+func ResetCeremony(tx *sql.Tx, electionID string) error {
+	threshold, err := Threshold(tx, electionID)
+	if err != nil || threshold == 0 {
+		return err
+	}
+
+	// Complaints go with the shares they refer to
+	if _, err := tx.Exec("DELETE FROM trustee_shares WHERE election_id = $1", electionID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE trustees SET dealing = NULL, verification_key = NULL, disqualified = FALSE WHERE election_id = $1", electionID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE elections SET trustee_complaints_close_at = NULL, trustee_reveals_close_at = NULL WHERE id = $1", electionID); err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM election_keys WHERE election_id = $1", electionID)
+	return err
+}
+
+// trusteeIndex returns the index of the admin's trustee seat in an election
+func trusteeIndex(q ballot.Querier, electionID interface{}, adminID int) (int, error) {
+	var index int
+	err := q.QueryRow("SELECT trustee_index FROM trustees WHERE election_id = $1 AND admin_id = $2", electionID, adminID).Scan(&index)
+	return index, err
+}
+
+// parseKey decodes a hex-encoded group element as stored in the database
+func parseKey(hexKey string) (*big.Int, error) {
+	key, ok := new(big.Int).SetString(hexKey, 16)
+	if !ok {
+		return nil, fmt.Errorf("malformed key %q", hexKey)
+	}
+	return key, nil
+}
+
+// openComplaints starts the complaint window once every trustee has uploaded
+// their dealing, and reports whether it did
+func openComplaints(tx *sql.Tx, electionID int) (bool, error) {
+	var waiting bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM trustees WHERE election_id = $1 AND dealing IS NULL)", electionID).Scan(&waiting); err != nil || waiting {
+		return false, err
+	}
+
+	query := `
+        UPDATE elections
+        SET trustee_complaints_close_at = NOW() + make_interval(secs => $2),
+            trustee_reveals_close_at = NOW() + make_interval(secs => $2 * 2)
+        WHERE id = $1
+    `
+	_, err := tx.Exec(query, electionID, complaintWindow.Seconds())
+	return err == nil, err
+}
+
+// completeCeremony disqualifies dealers who left a complaint unanswered, then
+// publishes the joint public key of the remaining dealings and the qualified
+// trustees' verification keys. It reports false, keeping the disqualifications,
+// when fewer than threshold trustees qualify.
+func completeCeremony(tx *sql.Tx, electionID, threshold int) (bool, error) {
+	disqualifyQuery := `
+        UPDATE trustees SET disqualified = TRUE
+        WHERE election_id = $1 AND trustee_index IN (
+            SELECT dealer_index FROM trustee_complaints WHERE election_id = $1 AND revealed_share IS NULL
+        )
+    `
+	if _, err := tx.Exec(disqualifyQuery, electionID); err != nil {
+		return false, err
+	}
+
+	rows, err := tx.Query("SELECT trustee_index, dealing FROM trustees WHERE election_id = $1 AND NOT disqualified ORDER BY trustee_index", electionID)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	var indices []int
+	var dealings []*elgamal.Dealing
+	for rows.Next() {
+		var index int
+		var data []byte
+		if err := rows.Scan(&index, &data); err != nil {
+			return false, err
+		}
+		if data == nil {
+			return false, nil // Still waiting for this trustee
+		}
+		var dealing elgamal.Dealing
+		if err := json.Unmarshal(data, &dealing); err != nil {
+			return false, err
+		}
+		indices = append(indices, index)
+		dealings = append(dealings, &dealing)
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	rows.Close()
+	if len(indices) < threshold {
+		return false, nil
+	}
+
+	// Disqualified trustees get no verification key, so they cannot decrypt
+	group := elgamal.DefaultGroup
+	for _, index := range indices {
+		verificationKey := elgamal.VerificationKey(group, dealings, index).Text(16)
+		query := "UPDATE trustees SET verification_key = $3 WHERE election_id = $1 AND trustee_index = $2"
+		if _, err := tx.Exec(query, electionID, index, verificationKey); err != nil {
+			return false, err
+		}
+	}
+
+	// The secret key column stays NULL: the joint secret exists only as shares
+	publicKey := elgamal.JointPublicKey(group, dealings)
+	query := "INSERT INTO election_keys (election_id, public_key) VALUES ($1, $2)"
+	if _, err := tx.Exec(query, electionID, publicKey.H.Big().Text(16)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CompleteDueCeremonies completes every ceremony whose complaint window is
+// over. Failures are logged and retried on the next run.
+func CompleteDueCeremonies() {
+	rows, err := utils.DB.Query("SELECT id FROM elections WHERE " + dueCeremony + " ORDER BY id")
+	if err != nil {
+		log.Println("Error finding key ceremonies to complete:", err)
+		return
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			log.Println("Error finding key ceremonies to complete:", err)
+			return
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		log.Println("Error finding key ceremonies to complete:", err)
+		return
+	}
+
+	for _, electionID := range ids {
+		complete, err := completeDue(electionID)
+		if err != nil {
+			log.Printf("Error completing the key ceremony of election %d: %v", electionID, err)
+		} else if !complete {
+			log.Printf("Key ceremony of election %d failed: too few trustees qualified", electionID)
+		}
+	}
+}
+
+// completeDue completes one ceremony under the election's row lock. Another
+// replica that got there first leaves nothing due, which counts as complete.
+func completeDue(electionID int) (bool, error) {
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var threshold int
+	err = tx.QueryRow("SELECT trustee_threshold FROM elections WHERE id = $1 AND "+dueCeremony+" FOR UPDATE", electionID).Scan(&threshold)
+	if err == sql.ErrNoRows {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	complete, err := completeCeremony(tx, electionID, threshold)
+	if err != nil {
+		return false, err
+	}
+	return complete, tx.Commit()
+}
+
+// Start reads TRUSTEE_COMPLAINT_WINDOW (a Go duration such as "12h") and
+// completes due ceremonies in the background. It is safe to start on every replica.
+func Start() error {
+	if value := os.Getenv("TRUSTEE_COMPLAINT_WINDOW"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		complaintWindow = parsed
+	}
+
+	go func() {
+		for range time.Tick(completionInterval) {
+			CompleteDueCeremonies()
+		}
+	}()
+	return nil
+}
+
+// combineDecryptions decrypts every aggregate from the partial decryptions of
+// the lowest-indexed threshold trustees, if that many have submitted
+func combineDecryptions(tx *sql.Tx, electionID, threshold int) (bool, error) {
+	indexQuery := `
+        SELECT DISTINCT trustee_index
+        FROM trustee_decryptions
+        WHERE election_id = $1
+        ORDER BY trustee_index
+        LIMIT $2
+    `
+	rows, err := tx.Query(indexQuery, electionID, threshold)
+	if err != nil {
+		return false, err
+	}
+	var indices []int
+	for rows.Next() {
+		var index int
+		if err := rows.Scan(&index); err != nil {
+			rows.Close()
+			return false, err
+		}
+		indices = append(indices, index)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	if len(indices) < threshold {
+		return false, nil
+	}
+
+	tallies, err := ballot.EncryptedTallies(tx, electionID)
+	if err != nil {
+		return false, err
+	}
+
+	group := elgamal.DefaultGroup
+	for _, tally := range tallies {
+		factors := make(map[int]*big.Int, threshold)
+		decryption := make([]ballot.TallyDecryption, 0, threshold)
+		for _, index := range indices {
+			var data []byte
+			shareQuery := `
+                SELECT share FROM trustee_decryptions
+                WHERE election_id = $1 AND trustee_index = $2 AND constituency_id = $3 AND option_index = $4
+            `
+			if err := tx.QueryRow(shareQuery, electionID, index, tally.ConstituencyID, tally.Index).Scan(&data); err != nil {
+				return false, err
+			}
+			var share elgamal.DecryptionShare
+			if err := json.Unmarshal(data, &share); err != nil {
+				return false, err
+			}
+			factors[index] = share.D.Big()
+			decryption = append(decryption, ballot.TallyDecryption{Trustee: index, DecryptionShare: share})
+		}
+
+		votes, err := elgamal.DecryptWithFactor(group, tally.Aggregate, elgamal.CombineDecryptionFactors(group, factors), tally.BallotCount)
+		if err != nil {
+			return false, err
+		}
+		if err := ballot.PublishTally(tx, electionID, tally, decryption, votes); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,649 @@
+package trustees
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/Haste007/E-Voting/Backend/ballot"
+	"github.com/Haste007/E-Voting/Backend/elgamal"
+	"github.com/Haste007/E-Voting/Backend/lifecycle"
+	"github.com/Haste007/E-Voting/Backend/middleware"
+	"github.com/Haste007/E-Voting/Backend/models"
+	"github.com/Haste007/E-Voting/Backend/tally"
+	"github.com/Haste007/E-Voting/Backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// errNotTrustee is returned when the caller holds no trustee seat in the election
+var errNotTrustee = errors.New("not a trustee of this election")
+
+// beginTrusteeAction parses the :id param, opens a transaction and locks the
+// election row, requiring it to be a trustee election in the given state
+func beginTrusteeAction(c *fiber.Ctx, status, action string) (*sql.Tx, int, int, error) {
+	electionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return nil, 0, 0, fiber.NewError(fiber.StatusBadRequest, "Invalid election ID")
+	}
+
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	current, err := lifecycle.CurrentStatus(tx, electionID, "FOR UPDATE")
+	if err == nil && current != status {
+		err = &lifecycle.StateError{Status: current, Action: action}
+	}
+	var threshold int
+	if err == nil {
+		threshold, err = Threshold(tx, electionID)
+	}
+	if err == nil && threshold == 0 {
+		err = fiber.NewError(fiber.StatusConflict, "Election does not use trustees")
+	}
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, 0, err
+	}
+	return tx, electionID, threshold, nil
+}
+
+// callerIndex returns the trustee index of the authenticated admin
+func callerIndex(c *fiber.Ctx, q ballot.Querier, electionID int) (int, error) {
+	claims := c.Locals(middleware.AdminLocalsKey).(*utils.AdminClaims)
+	index, err := trusteeIndex(q, electionID, claims.AdminID)
+	if err == sql.ErrNoRows {
+		return 0, errNotTrustee
+	}
+	return index, err
+}
+
+// errorResponse maps ceremony errors to HTTP responses
+func errorResponse(c *fiber.Ctx, err error, fallback string) error {
+	var stateErr *lifecycle.StateError
+	var fiberErr *fiber.Error
+	switch {
+	case errors.Is(err, lifecycle.ErrElectionNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Election not found"})
+	case errors.As(err, &stateErr):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": stateErr.Error(), "status": stateErr.Status})
+	case errors.Is(err, errNotTrustee):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Not a trustee of this election"})
+	case errors.As(err, &fiberErr):
+		return c.Status(fiberErr.Code).JSON(fiber.Map{"error": fiberErr.Message})
+	default:
+		log.Println(fallback+":", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": fallback})
+	}
+}
+
+// EnrollTrustee gives the calling trustee account a seat in a draft election.
+// The communication key is the trustee's own ElGamal public key, which other
+// trustees encrypt their shares to.
+func EnrollTrustee(c *fiber.Ctx) error {
+	var request struct {
+		CommunicationKey *elgamal.PublicKey `json:"communicationKey"`
+	}
+	if err := c.BodyParser(&request); err != nil || !request.CommunicationKey.Valid(elgamal.DefaultGroup) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid communication key"})
+	}
+
+	// Super admins pass every role check, but must not hold key shares themselves
+	claims := c.Locals(middleware.AdminLocalsKey).(*utils.AdminClaims)
+	if claims.Role != models.RoleTrustee {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Only trustee accounts can enroll"})
+	}
+
+	tx, electionID, _, err := beginTrusteeAction(c, lifecycle.StatusDraft, "enroll trustees")
+	if err != nil {
+		return errorResponse(c, err, "Failed to enroll trustee")
+	}
+	defer tx.Rollback()
+
+	var index int
+	query := `
+        INSERT INTO trustees (election_id, trustee_index, admin_id, communication_key)
+        SELECT $1::int, COALESCE(MAX(trustee_index), 0) + 1, $2::int, $3::text FROM trustees WHERE election_id = $1
+        ON CONFLICT (election_id, admin_id) DO NOTHING
+        RETURNING trustee_index
+    `
+	err = tx.QueryRow(query, electionID, claims.AdminID, request.CommunicationKey.H.Big().Text(16)).Scan(&index)
+	if err == sql.ErrNoRows {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Already enrolled as a trustee of this election"})
+	} else if err != nil {
+		return errorResponse(c, err, "Failed to enroll trustee")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errorResponse(c, err, "Failed to enroll trustee")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Trustee enrolled successfully", "index": index})
+}
+
+// RemoveTrustee removes a trustee seat from a draft election
+func RemoveTrustee(c *fiber.Ctx) error {
+	tx, electionID, _, err := beginTrusteeAction(c, lifecycle.StatusDraft, "remove trustees")
+	if err != nil {
+		return errorResponse(c, err, "Failed to remove trustee")
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM trustees WHERE election_id = $1 AND trustee_index = $2", electionID, c.Params("index"))
+	if err != nil {
+		return errorResponse(c, err, "Failed to remove trustee")
+	}
+	if removed, _ := result.RowsAffected(); removed == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Trustee not found"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errorResponse(c, err, "Failed to remove trustee")
+	}
+
+	return c.JSON(fiber.Map{"message": "Trustee removed successfully"})
+}
+
+// GetCeremony returns the public state of an election's key ceremony: every
+// trustee's communication key, dealing and verification key, the complaints
+// and revealed shares, and the joint public key once it exists. Anyone can
+// check each revealed share against its dealing and that the joint key is the
+// product of the qualified dealings' constant-term commitments.
+func GetCeremony(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	threshold, err := Threshold(utils.DB, id)
+	if err == sql.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Election not found"})
+	} else if err != nil {
+		return errorResponse(c, err, "Failed to fetch key ceremony")
+	}
+	if threshold == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Election does not use trustees"})
+	}
+
+	type trustee struct {
+		Index            int              `json:"index"`
+		CommunicationKey string           `json:"communication_key"`
+		Dealing          *elgamal.Dealing `json:"dealing"`
+		VerificationKey  *string          `json:"verification_key"`
+		Disqualified     bool             `json:"disqualified"`
+		Decrypted        bool             `json:"decrypted"` // Submitted partial decryptions
+	}
+
+	query := `
+        SELECT t.trustee_index, t.communication_key, t.dealing, t.verification_key, t.disqualified,
+               EXISTS (SELECT 1 FROM trustee_decryptions d WHERE d.election_id = t.election_id AND d.trustee_index = t.trustee_index)
+        FROM trustees t
+        WHERE t.election_id = $1
+        ORDER BY t.trustee_index
+    `
+	rows, err := utils.DB.Query(query, id)
+	if err != nil {
+		return errorResponse(c, err, "Failed to fetch key ceremony")
+	}
+	defer rows.Close()
+
+	trustees := []trustee{}
+	for rows.Next() {
+		var t trustee
+		var dealing []byte
+		var verificationKey sql.NullString
+		if err := rows.Scan(&t.Index, &t.CommunicationKey, &dealing, &verificationKey, &t.Disqualified, &t.Decrypted); err != nil {
+			return errorResponse(c, err, "Failed to fetch key ceremony")
+		}
+		if dealing != nil {
+			if err := json.Unmarshal(dealing, &t.Dealing); err != nil {
+				return errorResponse(c, err, "Failed to fetch key ceremony")
+			}
+		}
+		if verificationKey.Valid {
+			t.VerificationKey = &verificationKey.String
+		}
+		trustees = append(trustees, t)
+	}
+
+	type complaint struct {
+		Dealer        int     `json:"dealer"`
+		Complainant   int     `json:"complainant"`
+		RevealedShare *string `json:"revealed_share"` // Hex; null until the dealer answers
+	}
+	complaintQuery := `
+        SELECT dealer_index, complainant_index, revealed_share
+        FROM trustee_complaints
+        WHERE election_id = $1
+        ORDER BY dealer_index, complainant_index
+    `
+	complaintRows, err := utils.DB.Query(complaintQuery, id)
+	if err != nil {
+		return errorResponse(c, err, "Failed to fetch key ceremony")
+	}
+	defer complaintRows.Close()
+	complaints := []complaint{}
+	for complaintRows.Next() {
+		var k complaint
+		if err := complaintRows.Scan(&k.Dealer, &k.Complainant, &k.RevealedShare); err != nil {
+			return errorResponse(c, err, "Failed to fetch key ceremony")
+		}
+		complaints = append(complaints, k)
+	}
+
+	var complaintsCloseAt, revealsCloseAt *time.Time
+	windowQuery := "SELECT trustee_complaints_close_at, trustee_reveals_close_at FROM elections WHERE id = $1"
+	if err := utils.DB.QueryRow(windowQuery, id).Scan(&complaintsCloseAt, &revealsCloseAt); err != nil {
+		return errorResponse(c, err, "Failed to fetch key ceremony")
+	}
+
+	publicKey, err := ballot.ElectionPublicKey(utils.DB, id)
+	if err != nil && err != sql.ErrNoRows {
+		return errorResponse(c, err, "Failed to fetch key ceremony")
+	}
+
+	return c.JSON(fiber.Map{
+		"threshold":           threshold,
+		"trustees":            trustees,
+		"complaints":          complaints,
+		"complaints_close_at": complaintsCloseAt,
+		"reveals_close_at":    revealsCloseAt,
+		"complete":            publicKey != nil,
+		"public_key":          publicKey,
+	})
+}
+
+// SubmitDealing stores the calling trustee's dealing and its encrypted shares,
+// one for every other trustee. The last dealing opens the complaint window.
+func SubmitDealing(c *fiber.Ctx) error {
+	var request struct {
+		Dealing *elgamal.Dealing                `json:"dealing"`
+		Shares  map[int]*elgamal.EncryptedShare `json:"shares"` // Keyed by recipient trustee index
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	tx, electionID, threshold, err := beginTrusteeAction(c, lifecycle.StatusScheduled, "accept dealings")
+	if err != nil {
+		return errorResponse(c, err, "Failed to store dealing")
+	}
+	defer tx.Rollback()
+
+	index, err := callerIndex(c, tx, electionID)
+	if err != nil {
+		return errorResponse(c, err, "Failed to store dealing")
+	}
+
+	group := elgamal.DefaultGroup
+	if err := request.Dealing.Verify(group, threshold, DealingContext(electionID, index)); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Invalid dealing"})
+	}
+
+	// Exactly one well-formed share for each other trustee
+	rows, err := tx.Query("SELECT trustee_index FROM trustees WHERE election_id = $1 AND trustee_index <> $2", electionID, index)
+	if err != nil {
+		return errorResponse(c, err, "Failed to store dealing")
+	}
+	var recipients []int
+	for rows.Next() {
+		var recipient int
+		if err := rows.Scan(&recipient); err != nil {
+			rows.Close()
+			return errorResponse(c, err, "Failed to store dealing")
+		}
+		recipients = append(recipients, recipient)
+	}
+	rows.Close()
+	if len(request.Shares) != len(recipients) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "One share is required for every other trustee"})
+	}
+	for _, recipient := range recipients {
+		if !request.Shares[recipient].Valid(group) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Invalid share for trustee " + strconv.Itoa(recipient)})
+		}
+	}
+
+	dealing, err := json.Marshal(request.Dealing)
+	if err != nil {
+		return errorResponse(c, err, "Failed to store dealing")
+	}
+	result, err := tx.Exec("UPDATE trustees SET dealing = $3 WHERE election_id = $1 AND trustee_index = $2 AND dealing IS NULL", electionID, index, string(dealing))
+	if err != nil {
+		return errorResponse(c, err, "Failed to store dealing")
+	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Dealing already submitted"})
+	}
+
+	for _, recipient := range recipients {
+		share, err := json.Marshal(request.Shares[recipient])
+		if err != nil {
+			return errorResponse(c, err, "Failed to store dealing")
+		}
+		query := `
+            INSERT INTO trustee_shares (election_id, dealer_index, recipient_index, encrypted_share)
+            VALUES ($1, $2, $3, $4)
+        `
+		if _, err := tx.Exec(query, electionID, index, recipient, string(share)); err != nil {
+			return errorResponse(c, err, "Failed to store dealing")
+		}
+	}
+
+	complaintsOpen, err := openComplaints(tx, electionID)
+	if err != nil {
+		return errorResponse(c, err, "Failed to store dealing")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errorResponse(c, err, "Failed to store dealing")
+	}
+
+	return c.JSON(fiber.Map{"message": "Dealing stored successfully", "complaints_open": complaintsOpen})
+}
+
+// complaintPhase reports whether complaints can still be filed and answered
+func complaintPhase(q ballot.Querier, electionID int) (bool, bool, error) {
+	var complaintsOpen, revealsOpen bool
+	query := `
+        SELECT COALESCE(trustee_complaints_close_at > NOW(), FALSE), COALESCE(trustee_reveals_close_at > NOW(), FALSE)
+        FROM elections
+        WHERE id = $1
+    `
+	err := q.QueryRow(query, electionID).Scan(&complaintsOpen, &revealsOpen)
+	return complaintsOpen, revealsOpen, err
+}
+
+// FileComplaint records the caller's complaint that the share trustee :dealer
+// encrypted for them does not match the dealer's commitments. The dealer must
+// reveal that share before the reveal deadline or be disqualified.
+func FileComplaint(c *fiber.Ctx) error {
+	dealer, err := strconv.Atoi(c.Params("dealer"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid dealer index"})
+	}
+
+	tx, electionID, _, err := beginTrusteeAction(c, lifecycle.StatusScheduled, "accept complaints")
+	if err != nil {
+		return errorResponse(c, err, "Failed to file complaint")
+	}
+	defer tx.Rollback()
+
+	index, err := callerIndex(c, tx, electionID)
+	if err != nil {
+		return errorResponse(c, err, "Failed to file complaint")
+	}
+	complaintsOpen, _, err := complaintPhase(tx, electionID)
+	if err != nil {
+		return errorResponse(c, err, "Failed to file complaint")
+	}
+	if !complaintsOpen {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Complaints are only accepted during the complaint window"})
+	}
+
+	var received bool
+	shareQuery := "SELECT EXISTS (SELECT 1 FROM trustee_shares WHERE election_id = $1 AND dealer_index = $2 AND recipient_index = $3)"
+	if err := tx.QueryRow(shareQuery, electionID, dealer, index).Scan(&received); err != nil {
+		return errorResponse(c, err, "Failed to file complaint")
+	}
+	if !received {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No share from this dealer"})
+	}
+
+	query := `
+        INSERT INTO trustee_complaints (election_id, dealer_index, complainant_index)
+        VALUES ($1, $2, $3)
+        ON CONFLICT DO NOTHING
+    `
+	result, err := tx.Exec(query, electionID, dealer, index)
+	if err != nil {
+		return errorResponse(c, err, "Failed to file complaint")
+	}
+	if inserted, _ := result.RowsAffected(); inserted == 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Complaint already filed"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errorResponse(c, err, "Failed to file complaint")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "Complaint filed successfully"})
+}
+
+// RevealShare answers a complaint against the caller by publishing the share
+// they dealt to trustee :complainant. A share that does not match the caller's
+// commitments disqualifies them at once.
+func RevealShare(c *fiber.Ctx) error {
+	complainant, err := strconv.Atoi(c.Params("complainant"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid complainant index"})
+	}
+	var request struct {
+		Share string `json:"share"` // Hex f(complainant)
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+	share, err := parseKey(request.Share)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid share"})
+	}
+
+	tx, electionID, _, err := beginTrusteeAction(c, lifecycle.StatusScheduled, "accept revealed shares")
+	if err != nil {
+		return errorResponse(c, err, "Failed to reveal share")
+	}
+	defer tx.Rollback()
+
+	index, err := callerIndex(c, tx, electionID)
+	if err != nil {
+		return errorResponse(c, err, "Failed to reveal share")
+	}
+	_, revealsOpen, err := complaintPhase(tx, electionID)
+	if err != nil {
+		return errorResponse(c, err, "Failed to reveal share")
+	}
+	if !revealsOpen {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "The time to answer complaints has ended"})
+	}
+
+	var data []byte
+	complaintQuery := `
+        SELECT t.dealing
+        FROM trustee_complaints tc
+        JOIN trustees t ON t.election_id = tc.election_id AND t.trustee_index = tc.dealer_index
+        WHERE tc.election_id = $1 AND tc.dealer_index = $2 AND tc.complainant_index = $3 AND tc.revealed_share IS NULL
+    `
+	err = tx.QueryRow(complaintQuery, electionID, index, complainant).Scan(&data)
+	if err == sql.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No unanswered complaint from this trustee"})
+	} else if err != nil {
+		return errorResponse(c, err, "Failed to reveal share")
+	}
+	var dealing elgamal.Dealing
+	if err := json.Unmarshal(data, &dealing); err != nil {
+		return errorResponse(c, err, "Failed to reveal share")
+	}
+
+	if !dealing.VerifyShare(elgamal.DefaultGroup, complainant, share) {
+		if _, err := tx.Exec("UPDATE trustees SET disqualified = TRUE WHERE election_id = $1 AND trustee_index = $2", electionID, index); err != nil {
+			return errorResponse(c, err, "Failed to reveal share")
+		}
+		if err := tx.Commit(); err != nil {
+			return errorResponse(c, err, "Failed to reveal share")
+		}
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "Share does not match your dealing; you are disqualified"})
+	}
+
+	query := `
+        UPDATE trustee_complaints SET revealed_share = $4, revealed_at = NOW()
+        WHERE election_id = $1 AND dealer_index = $2 AND complainant_index = $3
+    `
+	if _, err := tx.Exec(query, electionID, index, complainant, share.Text(16)); err != nil {
+		return errorResponse(c, err, "Failed to reveal share")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errorResponse(c, err, "Failed to reveal share")
+	}
+
+	return c.JSON(fiber.Map{"message": "Share revealed successfully"})
+}
+
+// GetReceivedShares returns the shares other trustees encrypted for the caller,
+// with each dealer's dealing so the caller can verify them before use. A share
+// the dealer revealed in answer to a complaint replaces the encrypted one, and
+// shares from disqualified dealers are left out of the caller's key share.
+func GetReceivedShares(c *fiber.Ctx) error {
+	electionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid election ID"})
+	}
+
+	index, err := callerIndex(c, utils.DB, electionID)
+	if err != nil {
+		return errorResponse(c, err, "Failed to fetch shares")
+	}
+
+	query := `
+        SELECT s.dealer_index, t.dealing, t.disqualified, s.encrypted_share, tc.revealed_share
+        FROM trustee_shares s
+        JOIN trustees t ON t.election_id = s.election_id AND t.trustee_index = s.dealer_index
+        LEFT JOIN trustee_complaints tc ON tc.election_id = s.election_id AND tc.dealer_index = s.dealer_index AND tc.complainant_index = s.recipient_index
+        WHERE s.election_id = $1 AND s.recipient_index = $2
+        ORDER BY s.dealer_index
+    `
+	rows, err := utils.DB.Query(query, electionID, index)
+	if err != nil {
+		return errorResponse(c, err, "Failed to fetch shares")
+	}
+	defer rows.Close()
+
+	type receivedShare struct {
+		Dealer        int                     `json:"dealer"`
+		Dealing       *elgamal.Dealing        `json:"dealing"`
+		Disqualified  bool                    `json:"disqualified"`
+		Share         *elgamal.EncryptedShare `json:"share"`
+		RevealedShare *string                 `json:"revealed_share"` // Hex; set when the dealer answered a complaint
+	}
+	shares := []receivedShare{}
+	for rows.Next() {
+		var share receivedShare
+		var dealing, encrypted []byte
+		if err := rows.Scan(&share.Dealer, &dealing, &share.Disqualified, &encrypted, &share.RevealedShare); err != nil {
+			return errorResponse(c, err, "Failed to fetch shares")
+		}
+		if err := json.Unmarshal(dealing, &share.Dealing); err != nil {
+			return errorResponse(c, err, "Failed to fetch shares")
+		}
+		if err := json.Unmarshal(encrypted, &share.Share); err != nil {
+			return errorResponse(c, err, "Failed to fetch shares")
+		}
+		shares = append(shares, share)
+	}
+
+	return c.JSON(fiber.Map{"index": index, "shares": shares})
+}
+
+// SubmitPartialDecryption stores the caller's decryption share for every
+// aggregate of a closed election, each checked against the caller's verification
+// key. Once threshold trustees have submitted, the results are combined and the
+// election is tallied.
+func SubmitPartialDecryption(c *fiber.Ctx) error {
+	var request struct {
+		Shares []struct {
+			ConstituencyID int `json:"constituency_id"`
+			OptionIndex    int `json:"option_index"`
+			elgamal.DecryptionShare
+		} `json:"shares"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	tx, electionID, threshold, err := beginTrusteeAction(c, lifecycle.StatusClosed, "accept partial decryptions")
+	if err != nil {
+		return errorResponse(c, err, "Failed to store partial decryption")
+	}
+	defer tx.Rollback()
+
+	index, err := callerIndex(c, tx, electionID)
+	if err != nil {
+		return errorResponse(c, err, "Failed to store partial decryption")
+	}
+
+	var verificationHex sql.NullString
+	query := "SELECT verification_key FROM trustees WHERE election_id = $1 AND trustee_index = $2"
+	if err := tx.QueryRow(query, electionID, index).Scan(&verificationHex); err != nil {
+		return errorResponse(c, err, "Failed to store partial decryption")
+	}
+	if !verificationHex.Valid {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Disqualified trustees cannot decrypt"})
+	}
+	verificationKey, err := parseKey(verificationHex.String)
+	if err != nil {
+		return errorResponse(c, err, "Failed to store partial decryption")
+	}
+
+	tallies, err := ballot.EncryptedTallies(tx, electionID)
+	if err != nil {
+		return errorResponse(c, err, "Failed to store partial decryption")
+	}
+	if len(request.Shares) != len(tallies) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": "One decryption share is required for every aggregate"})
+	}
+
+	group := elgamal.DefaultGroup
+	for i, tally := range tallies {
+		share := request.Shares[i]
+		context := ballot.OptionContext(electionID, tally.ConstituencyID, tally.Option)
+		if share.ConstituencyID != tally.ConstituencyID || share.OptionIndex != tally.Index ||
+			!share.DecryptionShare.Verify(group, verificationKey, tally.Aggregate, context) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error":           "Invalid decryption share",
+				"constituency_id": tally.ConstituencyID,
+				"option_index":    tally.Index,
+			})
+		}
+
+		data, err := json.Marshal(share.DecryptionShare)
+		if err != nil {
+			return errorResponse(c, err, "Failed to store partial decryption")
+		}
+		insertQuery := `
+            INSERT INTO trustee_decryptions (election_id, trustee_index, constituency_id, option_index, share)
+            VALUES ($1, $2, $3, $4, $5)
+            ON CONFLICT DO NOTHING
+        `
+		result, err := tx.Exec(insertQuery, electionID, index, tally.ConstituencyID, tally.Index, string(data))
+		if err != nil {
+			return errorResponse(c, err, "Failed to store partial decryption")
+		}
+		if inserted, _ := result.RowsAffected(); inserted == 0 {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Partial decryption already submitted"})
+		}
+	}
+
+	tallied, err := combineDecryptions(tx, electionID, threshold)
+	if err != nil {
+		return errorResponse(c, err, "Failed to store partial decryption")
+	}
+	status := lifecycle.StatusClosed
+	if tallied {
+		claims := c.Locals(middleware.AdminLocalsKey).(*utils.AdminClaims)
+		if _, err := tally.Run(tx, electionID, "Trustee decryption complete", claims.Username); err != nil {
+			return errorResponse(c, err, "Failed to store partial decryption")
+		}
+		if err := lifecycle.Transition(tx, electionID, lifecycle.StatusTallied, claims.Username); err != nil {
+			return errorResponse(c, err, "Failed to store partial decryption")
+		}
+		status = lifecycle.StatusTallied
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errorResponse(c, err, "Failed to store partial decryption")
+	}
+
+	return c.JSON(fiber.Map{"message": "Partial decryption stored successfully", "status": status})
+}
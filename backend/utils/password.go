@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"errors"
+	"log"
+	"os"
+
+	"github.com/Haste007/E-Voting/Backend/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HashPassword hashes an admin password with bcrypt
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches the bcrypt hash
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// SeedSuperAdmin creates a super-admin from ADMIN_USER/ADMIN_PASS when the admins table is empty
+func SeedSuperAdmin() error {
+	var count int
+	if err := DB.QueryRow("SELECT COUNT(*) FROM admins").Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	username := os.Getenv("ADMIN_USER")
+	password := os.Getenv("ADMIN_PASS")
+	if username == "" || password == "" {
+		return errors.New("no admin accounts exist and ADMIN_USER/ADMIN_PASS are not set")
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	query := "INSERT INTO admins (username, password_hash, role) VALUES ($1, $2, $3)"
+	if _, err := DB.Exec(query, username, hash, models.RoleSuperAdmin); err != nil {
+		return err
+	}
+
+	log.Println("Created initial super admin account:", username)
+	return nil
+}
@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// VoterTokenTTL is how long a voter session token stays valid after a successful face match
+const VoterTokenTTL = 10 * time.Minute
+
+const voterAudience = "voter"
+
+// VoterClaims identifies the authenticated citizen and the district they may vote in
+type VoterClaims struct {
+	CitizenID  int    `json:"cid"`
+	NID        string `json:"nid"`
+	DistrictID int    `json:"did"`
+	jwt.RegisteredClaims
+}
+
+func jwtSecret() ([]byte, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil, errors.New("JWT_SECRET environment variable not set")
+	}
+	return []byte(secret), nil
+}
+
+// GenerateVoterToken issues a short-lived HS256 token scoped to a citizen and their district
+func GenerateVoterToken(citizenID int, nid string, districtID int) (string, error) {
+	secret, err := jwtSecret()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := VoterClaims{
+		CitizenID:  citizenID,
+		NID:        nid,
+		DistrictID: districtID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(citizenID),
+			Audience:  jwt.ClaimStrings{voterAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(VoterTokenTTL)),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// ParseVoterToken verifies the signature, expiry and audience of a voter token
+func ParseVoterToken(tokenString string) (*VoterClaims, error) {
+	secret, err := jwtSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &VoterClaims{}
+	_, err = jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(voterAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.CitizenID == 0 || claims.NID == "" || claims.DistrictID == 0 {
+		return nil, errors.New("voter token is missing required claims")
+	}
+
+	return claims, nil
+}
+
+// AdminTokenTTL is how long an admin bearer token stays valid after login
+const AdminTokenTTL = 8 * time.Hour
+
+const adminAudience = "admin"
+
+// AdminClaims identifies a logged-in admin account and its role
+type AdminClaims struct {
+	AdminID  int    `json:"aid"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// GenerateAdminToken issues an HS256 bearer token for an admin account
+func GenerateAdminToken(adminID int, username, role string) (string, error) {
+	secret, err := jwtSecret()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := AdminClaims{
+		AdminID:  adminID,
+		Username: username,
+		Role:     role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(adminID),
+			Audience:  jwt.ClaimStrings{adminAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AdminTokenTTL)),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// ParseAdminToken verifies the signature, expiry and audience of an admin token
+func ParseAdminToken(tokenString string) (*AdminClaims, error) {
+	secret, err := jwtSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &AdminClaims{}
+	_, err = jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(adminAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.AdminID == 0 || claims.Role == "" {
+		return nil, errors.New("admin token is missing required claims")
+	}
+
+	return claims, nil
+}
@@ -1,16 +1,197 @@
 package utils
 
 import (
+	"bufio"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
 )
 
-var secretKey = []byte("your-secret-key") // Replace with a secure key
+// voterKeys maps each pseudonym key version to its master key. Old versions are
+// kept so that pseudonyms of past elections can still be recomputed for audits.
+var voterKeys = map[int][]byte{}
 
-// HashVoterID hashes the voter ID using HMAC-SHA256
-func HashVoterID(voterID string) string {
-	h := hmac.New(sha256.New, secretKey)
+// currentVoterKeyVersion is the version assigned to newly created elections
+var currentVoterKeyVersion int
+
+// sampleVoterKeyFingerprint is the fingerprint of the VOTER_KEY that was once
+// committed in backend/.env. Anyone with the repository has it, so it may only
+// stay loaded as an old version for auditing past elections.
+const sampleVoterKeyFingerprint = "9964df6c90bfe10c1eea178e54339c3d76c9bffce009c15c985f5cf1792d855f"
+
+// LoadVoterKeys loads the pseudonym master keys and records their versions in pseudonym_keys.
+//
+// Keys are read from the file named by VOTER_KEY_FILE, one "<version>:<hex key>" per line,
+// or from a single hex VOTER_KEY used as version 1. The highest version is used for new
+// elections. A version whose key no longer matches its recorded fingerprint is refused,
+// since that would silently break every pseudonym of the elections that used it, and so
+// is the published sample key as the current version.
+func LoadVoterKeys() error {
+	keys, err := readVoterKeys()
+	if err != nil {
+		return err
+	}
+
+	for version, key := range keys {
+		fingerprint := keyFingerprint(key)
+		if fingerprint == sampleVoterKeyFingerprint && version == highestVersion(keys) {
+			return fmt.Errorf("voter key version %d is the published sample key; add a newly generated key as a higher version", version)
+		}
+
+		var recorded string
+		query := `
+            INSERT INTO pseudonym_keys (version, fingerprint)
+            VALUES ($1, $2)
+            ON CONFLICT (version) DO UPDATE SET version = EXCLUDED.version
+            RETURNING fingerprint
+        `
+		if err := DB.QueryRow(query, version, fingerprint).Scan(&recorded); err != nil {
+			return err
+		}
+		if recorded != fingerprint {
+			return fmt.Errorf("voter key version %d does not match its recorded fingerprint", version)
+		}
+
+		if version > currentVoterKeyVersion {
+			currentVoterKeyVersion = version
+		}
+	}
+
+	voterKeys = keys
+	log.Printf("Loaded %d voter pseudonym key(s), current version %d", len(keys), currentVoterKeyVersion)
+	return nil
+}
+
+func readVoterKeys() (map[int][]byte, error) {
+	keys := map[int][]byte{}
+
+	if path := os.Getenv("VOTER_KEY_FILE"); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			versionText, keyText, ok := strings.Cut(line, ":")
+			version, err := strconv.Atoi(strings.TrimSpace(versionText))
+			if !ok || err != nil || version < 1 {
+				return nil, fmt.Errorf("invalid line in VOTER_KEY_FILE: expected <version>:<hex key>")
+			}
+			key, err := decodeVoterKey(keyText)
+			if err != nil {
+				return nil, fmt.Errorf("voter key version %d: %w", version, err)
+			}
+			keys[version] = key
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	} else if keyText := os.Getenv("VOTER_KEY"); keyText != "" {
+		key, err := decodeVoterKey(keyText)
+		if err != nil {
+			return nil, fmt.Errorf("VOTER_KEY: %w", err)
+		}
+		keys[1] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no voter pseudonym keys configured; set VOTER_KEY_FILE or VOTER_KEY")
+	}
+	return keys, nil
+}
+
+// highestVersion returns the newest key version
+func highestVersion(keys map[int][]byte) int {
+	highest := 0
+	for version := range keys {
+		if version > highest {
+			highest = version
+		}
+	}
+	return highest
+}
+
+func decodeVoterKey(keyText string) ([]byte, error) {
+	key, err := hex.DecodeString(strings.TrimSpace(keyText))
+	if err != nil {
+		return nil, errors.New("key must be hex encoded")
+	}
+	if len(key) < 32 {
+		return nil, errors.New("key must be at least 32 bytes")
+	}
+	return key, nil
+}
+
+// keyFingerprint identifies a key without revealing it
+func keyFingerprint(key []byte) string {
+	sum := sha256.Sum256(append([]byte("evoting-voter-key-fingerprint:"), key...))
+	return hex.EncodeToString(sum[:])
+}
+
+// CurrentVoterKeyVersion returns the key version to record on new elections
+func CurrentVoterKeyVersion() int {
+	return currentVoterKeyVersion
+}
+
+// HashVoterID derives a voter's pseudonym for one election using HMAC-SHA256.
+// Each election gets its own secret derived from the master key of keyVersion,
+// so pseudonyms cannot be linked across elections.
+func HashVoterID(electionID, keyVersion int, voterID string) (string, error) {
+	return deriveVoterHash("evoting-election-pseudonym:", electionID, keyVersion, voterID)
+}
+
+// deriveVoterHash computes HMAC(HMAC(masterKey, domain || electionID), voterID)
+func deriveVoterHash(domain string, electionID, keyVersion int, voterID string) (string, error) {
+	masterKey, ok := voterKeys[keyVersion]
+	if !ok {
+		return "", fmt.Errorf("voter key version %d is not loaded", keyVersion)
+	}
+
+	electionKey := hmac.New(sha256.New, masterKey)
+	electionKey.Write([]byte(domain + strconv.Itoa(electionID)))
+
+	h := hmac.New(sha256.New, electionKey.Sum(nil))
 	h.Write([]byte(voterID))
-	return hex.EncodeToString(h.Sum(nil))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// electionKeyVersion returns the pseudonym key version recorded on an election
+func electionKeyVersion(electionID int) (int, error) {
+	var keyVersion int
+	query := "SELECT pseudonym_key_version FROM elections WHERE id = $1"
+	err := DB.QueryRow(query, electionID).Scan(&keyVersion)
+	return keyVersion, err
+}
+
+// VoterPseudonym derives a voter's pseudonym with the key version recorded on the election
+func VoterPseudonym(electionID int, voterID string) (string, error) {
+	keyVersion, err := electionKeyVersion(electionID)
+	if err != nil {
+		return "", err
+	}
+	return HashVoterID(electionID, keyVersion, voterID)
+}
+
+// RevoteTag derives the tag that groups one voter's ballots in one contest of a
+// re-voting election. It uses its own key domain, so revote_tags cannot be joined
+// to voter_participation without the master key, and it differs per contest, so
+// a voter's ballots in different contests cannot be linked to each other.
+func RevoteTag(electionID, constituencyID int, voterID string) (string, error) {
+	keyVersion, err := electionKeyVersion(electionID)
+	if err != nil {
+		return "", err
+	}
+	return deriveVoterHash("evoting-election-revote:", electionID, keyVersion, strconv.Itoa(constituencyID)+":"+voterID)
 }
@@ -1,7 +1,40 @@
 -- Delete existing tables if they exist (child tables first)
 DROP TABLE IF EXISTS
+    question_results,
+    question_answers,
+    question_participation,
+    question_options,
+    question_districts,
+    ballot_questions,
+    seat_allocations,
+    party_lists,
+    reserved_seat_pools,
+    tally_runs,
+    tabulation_reports,
+    ballot_receipts,
+    revote_tags,
+    bulletin_roots,
+    bulletin_board,
+    bulletin_heads,
+    trustee_decryptions,
+    trustee_complaints,
+    trustee_shares,
+    trustees,
+    approval_requests,
+    result_certifications,
+    signing_keys,
+    returning_officers,
+    admins,
+    encrypted_tallies,
+    election_keys,
+    pseudonym_keys,
+    polling_actions,
+    polling_extensions,
+    polling_pauses,
+    election_status_history,
     election_results,
-    votes,
+    ballot_box,
+    voter_participation,
     candidates,
     election_constituencies,
     constituency_districts,
@@ -45,7 +78,9 @@ CREATE TABLE party_members (
 -- Constituencies Table
 CREATE TABLE constituencies (
     id SERIAL PRIMARY KEY,
-    name VARCHAR(255) NOT NULL
+    name VARCHAR(255) NOT NULL,
+    tier VARCHAR(8) NOT NULL DEFAULT 'na' CHECK (tier IN ('na', 'pa')), -- National or Provincial Assembly
+    seats INT NOT NULL DEFAULT 1 CHECK (seats >= 1) -- Members returned; more than one only in STV elections
 );
 
 -- Constituency Districts Table (Many-to-Many Relationship)
@@ -55,13 +90,46 @@ CREATE TABLE constituency_districts (
     PRIMARY KEY (constituency_id, district_id)
 );
 
+-- Pseudonym Keys Table (versions of the voter pseudonym master key; the keys themselves stay outside the database)
+CREATE TABLE pseudonym_keys (
+    version INT PRIMARY KEY,
+    fingerprint CHAR(64) NOT NULL, -- SHA-256 fingerprint, used to detect a changed key
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
 -- Elections Table
 CREATE TABLE elections (
     id SERIAL PRIMARY KEY,
     name VARCHAR(255) NOT NULL,
-    date DATE NOT NULL,
-    started BOOLEAN NOT NULL DEFAULT FALSE,
-    ended BOOLEAN NOT NULL DEFAULT FALSE
+    date DATE NOT NULL, -- Election day in the election's time zone
+    opens_at TIMESTAMPTZ, -- Polls open automatically at this time once the election is scheduled
+    closes_at TIMESTAMPTZ, -- Polls close and the tally runs automatically at this time
+    approved_opens_at TIMESTAMPTZ, -- Polling hours as a second admin approved them; the scheduler
+    approved_closes_at TIMESTAMPTZ, -- only opens and closes polls while opens_at and closes_at match
+    time_zone VARCHAR(64) NOT NULL DEFAULT 'Asia/Karachi', -- IANA zone polling hours are entered and shown in
+    pseudonym_key_version INT NOT NULL REFERENCES pseudonym_keys(version), -- Key used for this election's voter pseudonyms
+    encrypted BOOLEAN NOT NULL DEFAULT FALSE, -- Ballots are ElGamal-encrypted and tallied homomorphically
+    trustee_threshold INT CHECK (trustee_threshold >= 2), -- Trustees needed to decrypt; NULL when the server holds the key
+    trustee_complaints_close_at TIMESTAMPTZ, -- Trustees may complain about their shares until then; set by the last dealing
+    trustee_reveals_close_at TIMESTAMPTZ, -- Accused dealers must reveal the disputed share by then
+    allow_revote BOOLEAN NOT NULL DEFAULT FALSE, -- Voters may recast while polls are open; the last ballot counts
+    allow_nota BOOLEAN NOT NULL DEFAULT FALSE, -- Ballots offer "None of the Above"
+    live_results BOOLEAN NOT NULL DEFAULT FALSE, -- Running vote shares are streamed while polls are open
+    ballot_type VARCHAR(16) NOT NULL DEFAULT 'fptp' CHECK (ballot_type IN ('fptp', 'irv', 'stv')), -- 'irv' and 'stv' take ranked ballots
+    stv_transfer VARCHAR(16) CHECK (stv_transfer IN ('gregory', 'meek')), -- STV surplus transfer rule; NULL otherwise
+    status VARCHAR(16) NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'scheduled', 'open', 'paused', 'closed', 'tallied', 'certified')),
+    CHECK (closes_at > opens_at)
+);
+
+-- Election Status History Table (every lifecycle transition, with who made it)
+CREATE TABLE election_status_history (
+    id SERIAL PRIMARY KEY,
+    election_id INT NOT NULL REFERENCES elections(id) ON DELETE CASCADE,
+    from_status VARCHAR(16), -- NULL for the creation entry
+    to_status VARCHAR(16) NOT NULL,
+    actor VARCHAR(255) NOT NULL, -- Admin username, or "system" for automatic changes
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW()
 );
 -- Election Constituencies Table (Many-to-Many Relationship)
 CREATE TABLE election_constituencies (
@@ -70,22 +138,98 @@ CREATE TABLE election_constituencies (
     PRIMARY KEY (election_id, constituency_id)
 );
 
+-- Polling Pauses Table (constituencies and districts where voting is suspended; nationwide pauses use elections.status)
+CREATE TABLE polling_pauses (
+    id SERIAL PRIMARY KEY,
+    election_id INT NOT NULL REFERENCES elections(id) ON DELETE CASCADE,
+    constituency_id INT REFERENCES constituencies(id) ON DELETE CASCADE,
+    district_id INT REFERENCES districts(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    paused_by VARCHAR(255) NOT NULL,
+    paused_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((constituency_id IS NULL) <> (district_id IS NULL)),
+    UNIQUE (election_id, constituency_id),
+    UNIQUE (election_id, district_id)
+);
+
+-- Polling Extensions Table (later closing times of single constituencies)
+CREATE TABLE polling_extensions (
+    election_id INT REFERENCES elections(id) ON DELETE CASCADE,
+    constituency_id INT REFERENCES constituencies(id) ON DELETE CASCADE,
+    closes_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (election_id, constituency_id)
+);
+
+-- Polling Actions Table (every pause, resume and extension, with its reason and who made it)
+CREATE TABLE polling_actions (
+    id SERIAL PRIMARY KEY,
+    election_id INT NOT NULL REFERENCES elections(id) ON DELETE CASCADE,
+    action VARCHAR(8) NOT NULL CHECK (action IN ('pause', 'resume', 'extend')),
+    scope VARCHAR(16) NOT NULL CHECK (scope IN ('nationwide', 'constituency', 'district')),
+    constituency_id INT REFERENCES constituencies(id) ON DELETE CASCADE,
+    district_id INT REFERENCES districts(id) ON DELETE CASCADE,
+    closes_at TIMESTAMPTZ, -- New closing time, for extensions
+    reason TEXT NOT NULL CHECK (reason <> ''),
+    actor VARCHAR(255) NOT NULL,
+    acted_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
 CREATE TABLE candidates (
     id SERIAL PRIMARY KEY,
-    party_id INT REFERENCES parties(id) ON DELETE CASCADE, -- Reference the party
-    citizen_id INT REFERENCES citizens(id) ON DELETE CASCADE, -- Reference the citizen (party member)
+    party_id INT REFERENCES parties(id) ON DELETE CASCADE, -- Reference the party; NULL for independents
+    citizen_id INT REFERENCES citizens(id) ON DELETE CASCADE, -- Reference the citizen
     constituency_id INT REFERENCES constituencies(id) ON DELETE CASCADE, -- Reference the constituency
-    UNIQUE (party_id, constituency_id) -- Ensure one candidate per party per constituency
+    symbol TEXT, -- Path to an independent candidate's ballot symbol
+    UNIQUE (citizen_id, constituency_id),
+    CHECK (party_id IS NULL OR symbol IS NULL) -- Party candidates use the party logo
 );
 
--- Votes Table
-CREATE TABLE votes (
-    id SERIAL PRIMARY KEY,
+-- Voter Participation Table (records THAT a voter took part, never how they voted)
+CREATE TABLE voter_participation (
     election_id INT REFERENCES elections(id) ON DELETE CASCADE,
-    constituency_id INT REFERENCES constituencies(id) ON DELETE CASCADE,
-    party_id INT REFERENCES parties(id) ON DELETE CASCADE,
+    constituency_id INT REFERENCES constituencies(id) ON DELETE CASCADE, -- The contest voted in
+    district_id INT REFERENCES districts(id) ON DELETE SET NULL, -- Voter's district, for turnout by district
     voter_hash VARCHAR(255) NOT NULL,
-    vote_time TIMESTAMP NOT NULL DEFAULT NOW()
+    PRIMARY KEY (election_id, constituency_id, voter_hash) -- One vote per voter per contest, enforced atomically
+);
+
+-- Ballot Box Table (anonymous ballots; no voter reference, random IDs, hour-level timestamps)
+CREATE TABLE ballot_box (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    election_id INT REFERENCES elections(id) ON DELETE CASCADE,
+    constituency_id INT REFERENCES constituencies(id) ON DELETE CASCADE,
+    choice VARCHAR(16) CHECK (choice IN ('candidate', 'nota', 'ranked')), -- NULL for encrypted ballots
+    candidate_id INT REFERENCES candidates(id) ON DELETE CASCADE, -- Set only for plaintext candidate choices
+    ranking JSONB, -- Candidate IDs of a ranked ballot, most preferred first
+    encrypted_choice JSONB, -- Encrypted one-hot ballot with validity proofs
+    cast_hour TIMESTAMP NOT NULL, -- Truncated to the hour so ballots cannot be matched by time
+    replaced BOOLEAN NOT NULL DEFAULT FALSE, -- Superseded by a later ballot; kept for audit, never counted
+    CHECK ((choice IS NULL) <> (encrypted_choice IS NULL)),
+    CHECK ((candidate_id IS NOT NULL) = (choice IS NOT DISTINCT FROM 'candidate')),
+    CHECK ((ranking IS NOT NULL) = (choice IS NOT DISTINCT FROM 'ranked'))
+);
+
+-- Re-vote Tags Table (each voter's current ballot per contest in re-voting elections; emptied when polls close)
+CREATE TABLE revote_tags (
+    election_id INT REFERENCES elections(id) ON DELETE CASCADE,
+    tag CHAR(64) NOT NULL, -- Keyed per-voter tag; kept apart from ballot_box and deleted at close
+    ballot_id UUID NOT NULL UNIQUE REFERENCES ballot_box(id) ON DELETE CASCADE,
+    PRIMARY KEY (election_id, tag)
+);
+
+-- Ballot Receipts Table (tracking codes; only their hash is stored and nothing links them to a voter)
+CREATE TABLE ballot_receipts (
+    code_hash CHAR(64) PRIMARY KEY, -- SHA-256 of the normalized tracking code
+    ballot_id UUID NOT NULL UNIQUE REFERENCES ballot_box(id) ON DELETE CASCADE,
+    entry_hash CHAR(64) -- Bulletin board entry, for encrypted ballots only
+);
+
+-- Election Keys Table (ElGamal key pair of an encrypted election, created when it is scheduled)
+CREATE TABLE election_keys (
+    election_id INT PRIMARY KEY REFERENCES elections(id) ON DELETE CASCADE,
+    public_key TEXT NOT NULL, -- Hex-encoded h = g^x
+    secret_key TEXT, -- Hex-encoded x; NULL for trustee elections, where no one holds it
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
 );
 
 -- Election Results Table
@@ -93,6 +237,273 @@ CREATE TABLE election_results (
     id SERIAL PRIMARY KEY,
     election_id INT REFERENCES elections(id) ON DELETE CASCADE,
     constituency_id INT REFERENCES constituencies(id) ON DELETE CASCADE,
+    choice VARCHAR(16) NOT NULL DEFAULT 'candidate' CHECK (choice IN ('candidate', 'nota')), -- NOTA rows never win
+    candidate_id INT REFERENCES candidates(id) ON DELETE CASCADE, -- NULL for NOTA
+    total_votes INT NOT NULL DEFAULT 0, -- Whole votes; STV counts keep the exact values in tabulation_reports
+    elected BOOLEAN NOT NULL DEFAULT FALSE, -- Candidate won one of the constituency's seats
+    CHECK ((candidate_id IS NOT NULL) = (choice = 'candidate')),
+    CHECK (NOT elected OR choice = 'candidate')
+);
+
+-- Tabulation Reports Table (round-by-round record, or STV count sheet, of each ranked count)
+CREATE TABLE tabulation_reports (
+    election_id INT REFERENCES elections(id) ON DELETE CASCADE,
+    constituency_id INT REFERENCES constituencies(id) ON DELETE CASCADE,
+    method VARCHAR(16) NOT NULL, -- Counting method, 'irv' or 'stv'
+    report JSONB NOT NULL,
+    PRIMARY KEY (election_id, constituency_id)
+);
+
+-- Tally Runs Table (versioned snapshot of the results after every tally run, recount and correction)
+CREATE TABLE tally_runs (
+    election_id INT REFERENCES elections(id) ON DELETE CASCADE,
+    version INT NOT NULL CHECK (version >= 1),
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('tally', 'recount', 'correction')),
+    constituency_id INT REFERENCES constituencies(id) ON DELETE CASCADE, -- Set when only one constituency was counted or corrected
+    input_hash CHAR(64) NOT NULL, -- SHA-256 over the ballot_box rows counted (ID and content, in ID order); internal, not checkable against the bulletin board
+    reason TEXT NOT NULL DEFAULT '',
+    operator VARCHAR(255) NOT NULL,
+    results JSONB NOT NULL, -- Result lines of each constituency covered
+    run_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (election_id, version),
+    CHECK ((constituency_id IS NULL) = (kind = 'tally'))
+);
+
+-- Ballot Questions Table (referendum questions put to voters alongside the candidate races)
+CREATE TABLE ballot_questions (
+    id SERIAL PRIMARY KEY,
+    election_id INT NOT NULL REFERENCES elections(id) ON DELETE CASCADE,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('yes_no', 'multiple_choice')),
+    text JSONB NOT NULL, -- Question text by language code, e.g. {"en": "...", "ur": "..."}
+    nationwide BOOLEAN NOT NULL DEFAULT TRUE, -- Otherwise only voters in question_districts may answer
+    pass_rule VARCHAR(16) NOT NULL DEFAULT 'majority' CHECK (pass_rule IN ('majority', 'supermajority')),
+    supermajority NUMERIC(5, 2) CHECK (supermajority > 50 AND supermajority <= 100), -- Percent of answers needed
+    min_turnout NUMERIC(5, 2) NOT NULL DEFAULT 0 CHECK (min_turnout BETWEEN 0 AND 100), -- Percent of eligible voters
+    CHECK ((supermajority IS NOT NULL) = (pass_rule = 'supermajority'))
+);
+
+-- Question Districts Table (where a question that is not nationwide is asked)
+CREATE TABLE question_districts (
+    question_id INT REFERENCES ballot_questions(id) ON DELETE CASCADE,
+    district_id INT REFERENCES districts(id) ON DELETE CASCADE,
+    PRIMARY KEY (question_id, district_id)
+);
+
+-- Question Options Table (answers offered; a yes/no question has Yes at position 1 and No at position 2)
+CREATE TABLE question_options (
+    id SERIAL PRIMARY KEY,
+    question_id INT NOT NULL REFERENCES ballot_questions(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    label JSONB NOT NULL, -- Option label by language code
+    UNIQUE (question_id, position)
+);
+
+-- Question Participation Table (records THAT a voter answered a question, never the answer)
+CREATE TABLE question_participation (
+    question_id INT REFERENCES ballot_questions(id) ON DELETE CASCADE,
+    voter_hash VARCHAR(255) NOT NULL,
+    PRIMARY KEY (question_id, voter_hash) -- One answer per voter per question
+);
+
+-- Question Answers Table (anonymous answers, like ballot_box)
+CREATE TABLE question_answers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    question_id INT NOT NULL REFERENCES ballot_questions(id) ON DELETE CASCADE,
+    option_id INT NOT NULL REFERENCES question_options(id) ON DELETE CASCADE,
+    cast_hour TIMESTAMP NOT NULL -- Truncated to the hour
+);
+
+-- Question Results Table (tally and outcome of each question, computed when polls close)
+CREATE TABLE question_results (
+    question_id INT PRIMARY KEY REFERENCES ballot_questions(id) ON DELETE CASCADE,
+    result JSONB NOT NULL,
+    tallied_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Reserved Seat Pools Table (seats shared among parties in proportion to the general seats they win)
+CREATE TABLE reserved_seat_pools (
+    id SERIAL PRIMARY KEY,
+    election_id INT NOT NULL REFERENCES elections(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL, -- e.g. 'Women' or 'Minorities'
+    tier VARCHAR(8) NOT NULL DEFAULT 'na' CHECK (tier IN ('na', 'pa')), -- Assembly whose general seats the pool follows
+    seats INT NOT NULL CHECK (seats >= 1),
+    method VARCHAR(16) NOT NULL CHECK (method IN ('dhondt', 'sainte_lague')),
+    min_general_seats INT NOT NULL DEFAULT 1 CHECK (min_general_seats >= 0), -- Parties with fewer general seats get none
+    min_vote_share NUMERIC(5, 2) NOT NULL DEFAULT 0 CHECK (min_vote_share BETWEEN 0 AND 100), -- Percent of candidate votes
+    UNIQUE (election_id, tier, name)
+);
+
+-- Party Lists Table (each party's priority list of members for a reserved seat pool)
+CREATE TABLE party_lists (
+    pool_id INT REFERENCES reserved_seat_pools(id) ON DELETE CASCADE,
     party_id INT REFERENCES parties(id) ON DELETE CASCADE,
-    total_votes INT NOT NULL DEFAULT 0
-);
\ No newline at end of file
+    position INT NOT NULL CHECK (position >= 1), -- 1 is seated first
+    citizen_id INT NOT NULL REFERENCES citizens(id) ON DELETE CASCADE,
+    PRIMARY KEY (pool_id, party_id, position),
+    UNIQUE (pool_id, citizen_id)
+);
+
+-- Seat Allocations Table (allocation of each reserved seat pool, computed when the election is tallied)
+CREATE TABLE seat_allocations (
+    pool_id INT PRIMARY KEY REFERENCES reserved_seat_pools(id) ON DELETE CASCADE,
+    allocation JSONB NOT NULL,
+    allocated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Encrypted Tallies Table (homomorphic aggregate per ballot option with its decryption proof)
+CREATE TABLE encrypted_tallies (
+    election_id INT REFERENCES elections(id) ON DELETE CASCADE,
+    constituency_id INT REFERENCES constituencies(id) ON DELETE CASCADE,
+    option_index INT NOT NULL, -- Position of the option on the ballot
+    choice VARCHAR(16) NOT NULL CHECK (choice IN ('candidate', 'nota')),
+    candidate_id INT REFERENCES candidates(id) ON DELETE CASCADE, -- NULL for NOTA
+    aggregate JSONB NOT NULL,
+    ballot_count INT NOT NULL, -- Ballots in the aggregate, the upper bound for its count
+    decryption JSONB, -- Decryption shares with proofs; NULL until decrypted
+    total_votes INT, -- NULL until decrypted
+    PRIMARY KEY (election_id, constituency_id, option_index)
+);
+
+-- Admins Table
+CREATE TABLE admins (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(255) UNIQUE NOT NULL,
+    password_hash TEXT NOT NULL, -- bcrypt hash
+    role VARCHAR(32) NOT NULL CHECK (role IN ('super_admin', 'election_officer', 'registrar', 'auditor', 'trustee', 'returning_officer')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Approval Requests Table (critical election actions under the two-person rule, kept as their audit log).
+-- election_id has no foreign key so that the record of an approved deletion outlives the election.
+CREATE TABLE approval_requests (
+    id SERIAL PRIMARY KEY,
+    election_id INT NOT NULL,
+    action VARCHAR(16) NOT NULL CHECK (action IN ('start', 'end', 'delete', 'certify', 'correct_result', 'hours')),
+    payload JSONB, -- Details of the action, such as the corrected result line
+    reason TEXT NOT NULL DEFAULT '',
+    proposed_by VARCHAR(255) NOT NULL,
+    proposed_by_id INT NOT NULL, -- Admin ID, so a renamed account cannot approve its own request
+    proposed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected', 'expired')),
+    decided_by VARCHAR(255),
+    decided_by_id INT,
+    decided_at TIMESTAMP,
+    outcome TEXT, -- What the approved action did, or why the request was rejected
+    CHECK (decided_by_id IS NULL OR status = 'rejected' OR decided_by_id <> proposed_by_id)
+);
+
+-- One pending request per election and action; several result corrections may wait at once
+CREATE UNIQUE INDEX approval_requests_one_pending ON approval_requests (election_id, action)
+    WHERE status = 'pending' AND action <> 'correct_result';
+
+-- Returning Officers Table (the returning officer who signs each constituency's result in an election)
+CREATE TABLE returning_officers (
+    election_id INT NOT NULL REFERENCES elections(id) ON DELETE CASCADE,
+    constituency_id INT NOT NULL REFERENCES constituencies(id) ON DELETE CASCADE,
+    admin_id INT NOT NULL REFERENCES admins(id),
+    assigned_by VARCHAR(255) NOT NULL,
+    assigned_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (election_id, constituency_id)
+);
+
+-- Signing Keys Table (Ed25519 public keys of returning officers; replaced keys are kept so old signatures still verify)
+CREATE TABLE signing_keys (
+    id SERIAL PRIMARY KEY,
+    admin_id INT NOT NULL REFERENCES admins(id),
+    public_key TEXT NOT NULL, -- Base64 of the 32-byte key
+    registered_by VARCHAR(255) NOT NULL,
+    registered_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMP -- Set when a newer key replaces it
+);
+
+CREATE UNIQUE INDEX signing_keys_one_active ON signing_keys (admin_id) WHERE revoked_at IS NULL;
+
+-- Result Certifications Table (returning officer signatures over canonical constituency result documents)
+CREATE TABLE result_certifications (
+    id SERIAL PRIMARY KEY,
+    election_id INT NOT NULL REFERENCES elections(id) ON DELETE CASCADE,
+    constituency_id INT NOT NULL REFERENCES constituencies(id) ON DELETE CASCADE,
+    tally_version INT NOT NULL, -- Snapshot in tally_runs the document was built from; 0 for elections tallied before snapshots
+    key_id INT NOT NULL REFERENCES signing_keys(id),
+    signer VARCHAR(255) NOT NULL,
+    document TEXT NOT NULL, -- Exact bytes that were signed
+    signature TEXT NOT NULL, -- Base64 Ed25519 signature
+    signed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (election_id, constituency_id, tally_version)
+);
+
+-- Trustees Table (seats in an encrypted election's threshold key ceremony)
+CREATE TABLE trustees (
+    election_id INT NOT NULL REFERENCES elections(id) ON DELETE CASCADE,
+    trustee_index INT NOT NULL CHECK (trustee_index > 0), -- Point the trustee's key share is evaluated at
+    admin_id INT NOT NULL REFERENCES admins(id),
+    communication_key TEXT NOT NULL, -- Hex public key other trustees encrypt shares to
+    dealing JSONB, -- Coefficient commitments and proof, once uploaded
+    verification_key TEXT, -- Hex g^x_i, set when the ceremony completes
+    disqualified BOOLEAN NOT NULL DEFAULT FALSE, -- Failed to answer a complaint; excluded from the joint key and decryption
+    enrolled_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (election_id, trustee_index),
+    UNIQUE (election_id, admin_id)
+);
+
+-- Trustee Shares Table (polynomial shares, encrypted to their recipient)
+CREATE TABLE trustee_shares (
+    election_id INT NOT NULL,
+    dealer_index INT NOT NULL,
+    recipient_index INT NOT NULL,
+    encrypted_share JSONB NOT NULL,
+    PRIMARY KEY (election_id, dealer_index, recipient_index),
+    FOREIGN KEY (election_id, dealer_index) REFERENCES trustees(election_id, trustee_index) ON DELETE CASCADE,
+    FOREIGN KEY (election_id, recipient_index) REFERENCES trustees(election_id, trustee_index) ON DELETE CASCADE
+);
+
+-- Trustee Complaints Table (shares a recipient says do not match their dealer's commitments)
+CREATE TABLE trustee_complaints (
+    election_id INT NOT NULL,
+    dealer_index INT NOT NULL,
+    complainant_index INT NOT NULL,
+    filed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revealed_share TEXT, -- Hex share the dealer revealed in answer; checked against the dealing
+    revealed_at TIMESTAMPTZ,
+    PRIMARY KEY (election_id, dealer_index, complainant_index),
+    FOREIGN KEY (election_id, dealer_index, complainant_index) REFERENCES trustee_shares(election_id, dealer_index, recipient_index) ON DELETE CASCADE
+);
+
+-- Trustee Decryptions Table (each trustee's partial decryption of each aggregate)
+CREATE TABLE trustee_decryptions (
+    election_id INT NOT NULL,
+    trustee_index INT NOT NULL,
+    constituency_id INT NOT NULL,
+    option_index INT NOT NULL,
+    share JSONB NOT NULL, -- Decryption factor with proof
+    PRIMARY KEY (election_id, trustee_index, constituency_id, option_index),
+    FOREIGN KEY (election_id, trustee_index) REFERENCES trustees(election_id, trustee_index) ON DELETE CASCADE
+);
+
+-- Bulletin Board Table (public, append-only hash chain of accepted ballots)
+CREATE TABLE bulletin_board (
+    election_id INT NOT NULL REFERENCES elections(id) ON DELETE CASCADE,
+    position INT NOT NULL CHECK (position >= 0),
+    payload TEXT NOT NULL, -- Exact JSON bytes that were hashed
+    prev_hash CHAR(64) NOT NULL,
+    entry_hash CHAR(64) NOT NULL,
+    PRIMARY KEY (election_id, position)
+);
+
+-- Bulletin Heads Table (latest entry of each board; its row lock orders concurrent appends)
+CREATE TABLE bulletin_heads (
+    election_id INT PRIMARY KEY REFERENCES elections(id) ON DELETE CASCADE,
+    size INT NOT NULL DEFAULT 0,
+    head_hash CHAR(64) NOT NULL
+);
+
+-- Bulletin Roots Table (Merkle roots published over a board's first size entries)
+CREATE TABLE bulletin_roots (
+    election_id INT NOT NULL REFERENCES elections(id) ON DELETE CASCADE,
+    size INT NOT NULL,
+    root CHAR(64) NOT NULL,
+    head_hash CHAR(64) NOT NULL,
+    published_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (election_id, size)
+);
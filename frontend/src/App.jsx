@@ -6,10 +6,10 @@ import { AdminPanel } from "./pages/Admin/AdminPanel";
 import { AdminLoginPage } from "./pages/Admin/AdminLoginPage";
 
 function App() {
-  // Helper function to check if a valid NID is present in localStorage
+  // Helper function to check if a voter session token is present in localStorage
   const isAuthenticatedForVoting = () => {
-    const citizenId = localStorage.getItem("citizenId");
-    return citizenId && citizenId.trim() !== ""; // Ensure NID exists and is not empty
+    const voterToken = localStorage.getItem("voterToken");
+    return voterToken && voterToken.trim() !== ""; // Ensure the token exists and is not empty
   };
 
   // Helper function to check if admin is authenticated
@@ -26,6 +26,7 @@ function ElectionSummary({ electionId, onBack }) {
         method: "GET",
         headers: {
           "Content-Type": "application/json",
+          Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
         },
       });
 
@@ -24,6 +24,8 @@ function AdminLoginPage() {
       const data = await response.json();
       if (data.success) {
         localStorage.setItem("adminAuthenticated", "true");
+        localStorage.setItem("adminToken", data.token);
+        localStorage.setItem("adminRole", data.role);
         navigate("/admin");
       } else {
         setError("Invalid admin credentials");
@@ -17,6 +17,7 @@ export function CitizenManagement() {
         method: "GET",
         headers: {
           "Content-Type": "application/json",
+          Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
         },
       });
 
@@ -46,6 +47,7 @@ export function CitizenManagement() {
         method: "DELETE",
         headers: {
           "Content-Type": "application/json",
+          Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
         },
       });
 
@@ -32,6 +32,7 @@ function Register() {
         method: "POST",
         headers: {
           "Content-Type": "application/json",
+          Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
         },
         body: JSON.stringify({ name, nid, district, image }),
       });
@@ -20,6 +20,7 @@ export function ConstituencyForm({ onSave, onCancel, availableDistricts, onUpdat
           method: "GET",
           headers: {
             "Content-Type": "application/json",
+            Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
           },
         });
         if (response.ok) {
@@ -77,6 +78,7 @@ export function ConstituencyForm({ onSave, onCancel, availableDistricts, onUpdat
         method: "GET",
         headers: {
           "Content-Type": "application/json",
+          Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
         },
       });
       if (response.ok) {
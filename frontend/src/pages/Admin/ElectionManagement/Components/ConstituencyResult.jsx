@@ -1,6 +1,12 @@
 import React from "react";
 import { Bar } from "react-chartjs-2";
 
+// candidateName looks up a candidate of a count report among the constituency's results
+function candidateName(constituency, candidateId) {
+  const result = constituency.results.find((entry) => entry.candidate_id === candidateId);
+  return result ? result.candidate : `Candidate ${candidateId}`;
+}
+
 export function ConstituencyResult({ constituency }) {
   if (!constituency || !constituency.results || constituency.results.length === 0) {
     return (
@@ -11,9 +17,20 @@ export function ConstituencyResult({ constituency }) {
     );
   }
 
+  const certification = constituency.certification;
+
   return (
     <div className="bg-white rounded-lg shadow-lg p-6 ">
       <h4 className="text-md font-bold text-gray-700 mb-4">{constituency.name}</h4>
+      {certification && (
+        <p className={`text-sm mb-4 ${certification.signed ? "text-green-700" : "text-yellow-700"}`}>
+          {certification.signed
+            ? `Signed by returning officer ${certification.signer} on ${new Date(certification.signed_at).toLocaleString()}`
+            : certification.signer
+            ? "Signature is out of date; the result changed after it was signed"
+            : "Not yet signed by the returning officer"}
+        </p>
+      )}
 
       {/* Graph */}
       <Bar
@@ -67,6 +84,7 @@ export function ConstituencyResult({ constituency }) {
               <th className="border border-gray-300 px-4 py-2 text-left">Party</th>
               <th className="border border-gray-300 px-4 py-2 text-left">Candidate</th>
               <th className="border border-gray-300 px-4 py-2 text-right">Total Votes</th>
+              <th className="border border-gray-300 px-4 py-2 text-center">Elected</th>
             </tr>
           </thead>
           <tbody>
@@ -75,10 +93,70 @@ export function ConstituencyResult({ constituency }) {
                 <td className="border border-gray-300 px-4 py-2">{result.party_name}</td>
                 <td className="border border-gray-300 px-4 py-2">{result.candidate}</td>
                 <td className="border border-gray-300 px-4 py-2 text-right">{result.total_votes}</td>
+                <td className="border border-gray-300 px-4 py-2 text-center">{result.elected ? "Yes" : ""}</td>
               </tr>
             ))}
           </tbody>
         </table>
+        {constituency.count_report && constituency.count_report.rounds && (
+          <div className="mt-6">
+            <h5 className="text-sm font-bold text-gray-700 mb-2">Count Rounds</h5>
+            {constituency.count_report.rounds.map((round) => (
+              <div key={round.round} className="mb-2 text-sm">
+                <p className="font-semibold">Round {round.round}</p>
+                <p>
+                  {round.votes
+                    .map((entry) => `${candidateName(constituency, entry.candidate_id)}: ${entry.votes}`)
+                    .join(", ")}
+                  {round.exhausted > 0 && `, exhausted: ${round.exhausted}`}
+                </p>
+                {round.eliminated > 0 && (
+                  <p>
+                    Eliminated {candidateName(constituency, round.eliminated)}
+                    {round.tie_broken && " (tie broken)"}
+                  </p>
+                )}
+                {round.elected > 0 && <p>Elected {candidateName(constituency, round.elected)}</p>}
+              </div>
+            ))}
+            <p className="text-xs text-gray-500">{constituency.count_report.tie_break}</p>
+          </div>
+        )}
+        {constituency.count_report && constituency.count_report.stages && (
+          <div className="mt-6">
+            <h5 className="text-sm font-bold text-gray-700 mb-2">
+              STV Count Sheet ({constituency.count_report.seats} seats, {constituency.count_report.transfer} transfers)
+            </h5>
+            {constituency.count_report.stages.map((stage) => (
+              <div key={stage.stage} className="mb-2 text-sm">
+                <p className="font-semibold">
+                  Stage {stage.stage}: {stage.action.replace("_", " ")}
+                  {stage.from > 0 && ` of ${candidateName(constituency, stage.from)}`}
+                  {stage.excluded > 0 && ` of ${candidateName(constituency, stage.excluded)}`}
+                  {stage.transfer_value !== undefined && ` at ${stage.transfer_value}`}
+                  {stage.tie_broken && " (tie broken)"}
+                </p>
+                <p>
+                  {stage.candidates
+                    .filter((entry) => entry.status !== "excluded")
+                    .map((entry) => `${candidateName(constituency, entry.candidate_id)}: ${entry.votes}`)
+                    .join(", ")}
+                  {`, quota: ${stage.quota}, exhausted: ${stage.exhausted}`}
+                </p>
+                {stage.elected && stage.elected.length > 0 && (
+                  <p>Elected {stage.elected.map((id) => candidateName(constituency, id)).join(", ")}</p>
+                )}
+              </div>
+            ))}
+            <p className="text-xs text-gray-500">{constituency.count_report.tie_break}</p>
+          </div>
+        )}
+        <div className="mt-4 text-sm text-gray-700 space-y-1">
+          <p>None of the Above: {constituency.nota_votes || 0}</p>
+          <p>
+            Turnout: {constituency.turnout || 0} of {constituency.registered_voters || 0} registered voters
+          </p>
+        </div>
       </div>
     </div>
   );
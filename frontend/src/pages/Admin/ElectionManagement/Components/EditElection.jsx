@@ -4,8 +4,9 @@ import { districts as allDistricts } from "../../../../data/districts"; // Impor
 
 export function EditElection({ electionId, onBack }) {
   const [electionName, setElectionName] = useState("");
-  const [electionDate, setElectionDate] = useState("");
-  const [timeLimit, setTimeLimit] = useState(24);
+  const [opensAt, setOpensAt] = useState("");
+  const [closesAt, setClosesAt] = useState("");
+  const [timeZone, setTimeZone] = useState("Asia/Karachi");
   const [constituencies, setConstituencies] = useState([]);
   const [showConstituencyForm, setShowConstituencyForm] = useState(false);
   const [availableDistricts, setAvailableDistricts] = useState(allDistricts); // Track available districts
@@ -19,14 +20,17 @@ export function EditElection({ electionId, onBack }) {
         method: "GET",
         headers: {
           "Content-Type": "application/json",
+          Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
         },
       });
 
       if (response.ok) {
         const data = await response.json();
         setElectionName(data.name);
-        setElectionDate(data.date);
-        setTimeLimit(data.time_limit);
+        // Times come in the election's own zone, so the local part fits datetime-local inputs
+        setOpensAt(data.opens_at ? data.opens_at.slice(0, 16) : "");
+        setClosesAt(data.closes_at ? data.closes_at.slice(0, 16) : "");
+        setTimeZone(data.time_zone);
         setConstituencies(
           data.constituencies.map((c, index) => new Constituency(index + 1, `NA-${index + 1}`, c.districts))
         );
@@ -79,21 +83,27 @@ export function EditElection({ electionId, onBack }) {
   };
 
   const handleSaveChanges = async () => {
-    if (!electionName || !electionDate || constituencies.length === 0) {
+    if (!electionName || constituencies.length === 0) {
       alert("Please fill in all fields and add at least one constituency.");
       return;
     }
+    if (opensAt && closesAt && closesAt <= opensAt) {
+      alert("Polls must close after they open.");
+      return;
+    }
 
     try {
       const response = await fetch(`${backendUrl}/api/elections/${electionId}`, {
         method: "PUT",
         headers: {
           "Content-Type": "application/json",
+          Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
         },
         body: JSON.stringify({
           name: electionName,
-          date: electionDate,
-          time_limit: timeLimit,
+          opensAt,
+          closesAt,
+          timeZone,
           constituencies: constituencies.map((c) => ({
             name: c.name,
             districts: c.districts,
@@ -105,7 +115,8 @@ export function EditElection({ electionId, onBack }) {
         alert("Election updated successfully!");
         onBack();
       } else {
-        alert("Failed to update the election.");
+        const data = await response.json().catch(() => ({}));
+        alert(data.error || "Failed to update the election.");
       }
     } catch (err) {
       alert("Unable to connect to the server.");
@@ -135,39 +146,55 @@ export function EditElection({ electionId, onBack }) {
           />
         </div>
 
-        {/* Election Date */}
+        {/* Polling Hours */}
         <div className="mb-4">
           <label
-            htmlFor="electionDate"
+            htmlFor="opensAt"
             className="block text-sm font-medium text-gray-700"
           >
-            Election Date
+            Polls Open
           </label>
           <input
-            type="date"
-            id="electionDate"
-            value={electionDate}
-            onChange={(e) => setElectionDate(e.target.value)}
+            type="datetime-local"
+            id="opensAt"
+            value={opensAt}
+            onChange={(e) => setOpensAt(e.target.value)}
             className="w-full px-4 py-2 mt-1 text-sm border rounded-md focus:ring focus:ring-green-300 focus:outline-none"
           />
         </div>
-
-        {/* Time Limit */}
         <div className="mb-4">
           <label
-            htmlFor="timeLimit"
+            htmlFor="closesAt"
             className="block text-sm font-medium text-gray-700"
           >
-            Time Limit (hours)
+            Polls Close
           </label>
           <input
-            type="number"
-            id="timeLimit"
-            value={timeLimit}
-            onChange={(e) => setTimeLimit(e.target.value)}
+            type="datetime-local"
+            id="closesAt"
+            value={closesAt}
+            onChange={(e) => setClosesAt(e.target.value)}
+            className="w-full px-4 py-2 mt-1 text-sm border rounded-md focus:ring focus:ring-green-300 focus:outline-none"
+          />
+        </div>
+        <div className="mb-4">
+          <label
+            htmlFor="timeZone"
+            className="block text-sm font-medium text-gray-700"
+          >
+            Time Zone
+          </label>
+          <input
+            type="text"
+            id="timeZone"
+            value={timeZone}
+            onChange={(e) => setTimeZone(e.target.value)}
             className="w-full px-4 py-2 mt-1 text-sm border rounded-md focus:ring focus:ring-green-300 focus:outline-none"
-            placeholder="Enter time limit in hours"
+            placeholder="e.g. Asia/Karachi"
           />
+          <p className="mt-1 text-xs text-gray-500">
+            Scheduled elections open and close automatically at these times. Leave a time empty to open or close polls by hand.
+          </p>
         </div>
 
         {/* Constituencies */}
@@ -53,6 +53,7 @@ export function NewElection({ onBack }) {
         method: "POST",
         headers: {
           "Content-Type": "application/json",
+          Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
         },
         body: JSON.stringify(payload),
       });
@@ -0,0 +1,119 @@
+import React, { useEffect, useState } from "react";
+
+const backendUrl = import.meta.env.VITE_BACKEND_URL;
+
+const actionNames = {
+  start: "Start election",
+  end: "End election",
+  delete: "Delete election",
+  certify: "Certify results",
+  correct_result: "Correct result",
+};
+
+// Actions proposed by one admin that a different admin has to approve
+export function PendingApprovals() {
+  const [requests, setRequests] = useState([]);
+  const [error, setError] = useState("");
+
+  const fetchPendingApprovals = async () => {
+    try {
+      const response = await fetch(`${backendUrl}/api/approvals?status=pending`, {
+        method: "GET",
+        headers: {
+          "Content-Type": "application/json",
+          Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
+        },
+      });
+
+      if (response.ok) {
+        const data = await response.json();
+        setRequests(Array.isArray(data) ? data : []);
+      } else {
+        setError("Failed to fetch pending approvals.");
+      }
+    } catch (err) {
+      setError("Unable to connect to the server.");
+    }
+  };
+
+  useEffect(() => {
+    fetchPendingApprovals();
+  }, []);
+
+  const decide = async (request, decision) => {
+    const body = {};
+    if (decision === "reject") {
+      const reason = window.prompt("Reason for rejecting this request:");
+      if (reason === null) return;
+      body.reason = reason;
+    } else if (!window.confirm(`Approve "${actionNames[request.action] || request.action}" proposed by ${request.proposed_by}?`)) {
+      return;
+    }
+
+    try {
+      const response = await fetch(`${backendUrl}/api/approvals/${request.id}/${decision}`, {
+        method: "POST",
+        headers: {
+          "Content-Type": "application/json",
+          Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
+        },
+        body: JSON.stringify(body),
+      });
+
+      const data = await response.json().catch(() => ({}));
+      alert(response.ok ? data.message : data.error || `Failed to ${decision} the request.`);
+      fetchPendingApprovals();
+    } catch (err) {
+      alert("Unable to connect to the server.");
+    }
+  };
+
+  return (
+    <div className="bg-white rounded-lg shadow-lg p-4 mt-6">
+      <h3 className="text-lg font-bold text-gray-800 mb-4">Pending Approvals</h3>
+      {error && <p className="text-red-500 text-center">{error}</p>}
+      {requests.length > 0 ? (
+        <table className="w-full text-left border-collapse bg-green-900">
+          <thead>
+            <tr>
+              <th className="border-b py-2 px-4">Action</th>
+              <th className="border-b py-2 px-4">Election</th>
+              <th className="border-b py-2 px-4">Proposed By</th>
+              <th className="border-b py-2 px-4">Expires</th>
+              <th className="border-b py-2 px-4">Decision</th>
+            </tr>
+          </thead>
+          <tbody>
+            {requests.map((request) => (
+              <tr key={request.id}>
+                <td className="border-b py-2 px-4">
+                  {actionNames[request.action] || request.action}
+                  {request.reason && <p className="text-xs">{request.reason}</p>}
+                </td>
+                <td className="border-b py-2 px-4">#{request.election_id}</td>
+                <td className="border-b py-2 px-4">{request.proposed_by}</td>
+                <td className="border-b py-2 px-4">{new Date(request.expires_at).toLocaleTimeString()}</td>
+                <td className="border-b py-2 px-4">
+                  <button
+                    onClick={() => decide(request, "approve")}
+                    className="px-4 py-2 text-sm font-medium text-white bg-green-600 rounded-md hover:bg-green-700 mr-2"
+                  >
+                    Approve
+                  </button>
+                  <button
+                    onClick={() => decide(request, "reject")}
+                    className="px-4 py-2 text-sm font-medium text-white bg-red-600 rounded-md hover:bg-red-700"
+                  >
+                    Reject
+                  </button>
+                </td>
+              </tr>
+            ))}
+          </tbody>
+        </table>
+      ) : (
+        <p className="text-center text-gray-600">No actions are waiting for approval.</p>
+      )}
+    </div>
+  );
+}
@@ -29,6 +29,7 @@ export function PreviousElections({ onBack }) {
           method: "GET",
           headers: {
             "Content-Type": "application/json",
+            Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
           },
         });
 
@@ -46,13 +47,68 @@ export function PreviousElections({ onBack }) {
   }, []);
 
   if (selectedElection) {
-    const { name, date, constituencies } = selectedElection;
+    const { name, date, constituencies, party_seats: partySeats, questions } = selectedElection;
 
     return (
       <div className="bg-white rounded-lg shadow-lg p-6">
         <h3 className="text-lg font-bold text-gray-800 mb-4">{name}</h3>
         <p className="text-sm text-gray-600 mb-4">Date: {new Date(date).toLocaleDateString()}</p>
 
+        {/* Party seat totals, general and reserved */}
+        {partySeats && partySeats.length > 0 && (
+          <table className="w-full border-collapse border border-gray-300 mb-6 text-sm text-gray-800">
+            <thead>
+              <tr className="bg-gray-100">
+                <th className="border border-gray-300 px-4 py-2 text-left">Assembly</th>
+                <th className="border border-gray-300 px-4 py-2 text-left">Party</th>
+                <th className="border border-gray-300 px-4 py-2 text-right">General Seats</th>
+                <th className="border border-gray-300 px-4 py-2 text-left">Reserved Seats</th>
+                <th className="border border-gray-300 px-4 py-2 text-right">Total</th>
+              </tr>
+            </thead>
+            <tbody>
+              {partySeats.map((party) => (
+                <tr key={`${party.tier}-${party.party_id}`}>
+                  <td className="border border-gray-300 px-4 py-2 uppercase">{party.tier}</td>
+                  <td className="border border-gray-300 px-4 py-2">{party.name}</td>
+                  <td className="border border-gray-300 px-4 py-2 text-right">{party.general_seats}</td>
+                  <td className="border border-gray-300 px-4 py-2">
+                    {Object.entries(party.reserved_seats)
+                      .map(([pool, seats]) => `${pool}: ${seats}`)
+                      .join(", ")}
+                  </td>
+                  <td className="border border-gray-300 px-4 py-2 text-right">{party.total_seats}</td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+        )}
+
+        {/* Ballot question outcomes */}
+        {questions && questions.length > 0 && (
+          <div className="space-y-4 mb-6">
+            {questions.map((question) => (
+              <div key={question.question_id} className="p-4 bg-gray-100 rounded-md">
+                <p className="font-bold text-gray-800">{question.text.en || Object.values(question.text)[0]}</p>
+                <ul className="mt-2 text-sm text-gray-800">
+                  {question.options.map((option) => (
+                    <li key={option.option_id} className="flex justify-between">
+                      <span>{option.label.en || Object.values(option.label)[0]}</span>
+                      <span>{option.votes}</span>
+                    </li>
+                  ))}
+                </ul>
+                <p className="mt-2 text-xs text-gray-600">
+                  {question.answers} answers from {question.eligible_voters} eligible voters
+                </p>
+                <p className={`mt-1 text-sm font-medium ${question.passed ? "text-green-700" : "text-red-600"}`}>
+                  {question.outcome}
+                </p>
+              </div>
+            ))}
+          </div>
+        )}
+
         {/* Navbar for Constituencies */}
         <div className="flex space-x-4 overflow-x-auto mb-6">
           {constituencies.map((constituency) => (
@@ -65,7 +121,7 @@ export function PreviousElections({ onBack }) {
                   : "bg-gray-200 text-gray-800"
               }`}
             >
-              {constituency.name}
+              {constituency.name} ({constituency.tier.toUpperCase()})
             </button>
           ))}
         </div>
@@ -107,6 +163,21 @@ export function PreviousElections({ onBack }) {
                   Date: {election.date ? new Date(election.date).toLocaleDateString() : "No Date Provided"}
                 </p>
               </div>
+              <span
+                className={`px-2 py-1 text-xs font-medium rounded-md ${
+                  election.status === "certified"
+                    ? "bg-green-100 text-green-800"
+                    : election.results_signed
+                    ? "bg-blue-100 text-blue-800"
+                    : "bg-yellow-100 text-yellow-800"
+                }`}
+              >
+                {election.status === "certified"
+                  ? "Certified"
+                  : election.results_signed
+                  ? "All results signed"
+                  : "Awaiting returning officers"}
+              </span>
             </li>
           ))}
         </ul>
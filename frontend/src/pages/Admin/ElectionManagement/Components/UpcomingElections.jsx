@@ -13,6 +13,7 @@ export function UpcomingElections() {
         method: "GET",
         headers: {
           "Content-Type": "application/json",
+          Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
         },
       });
 
@@ -33,21 +34,39 @@ export function UpcomingElections() {
     fetchUpcomingElections();
   }, []);
 
-  // Start an election
-  const handleStartElection = async (id) => {
+  // Start an election; drafts are scheduled first so setup is frozen before polls open
+  const handleStartElection = async (election) => {
+    const id = election.id;
     try {
+      if (election.status === "draft") {
+        const scheduleResponse = await fetch(`${backendUrl}/api/elections/${id}/schedule`, {
+          method: "POST",
+          headers: {
+            "Content-Type": "application/json",
+            Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
+          },
+        });
+        if (!scheduleResponse.ok) {
+          alert("Failed to schedule the election.");
+          return;
+        }
+      }
+
       const response = await fetch(`${backendUrl}/api/elections/${id}/start`, {
         method: "POST",
         headers: {
           "Content-Type": "application/json",
+          Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
         },
       });
 
+      // Starting waits for a second admin to approve
+      const data = await response.json().catch(() => ({}));
       if (response.ok) {
-        alert("Election started successfully!");
+        alert("Start requested. Another admin has to approve it under Pending Approvals.");
         fetchUpcomingElections();
       } else {
-        alert("Failed to start the election.");
+        alert(data.error || "Failed to start the election.");
       }
     } catch (err) {
       alert("Unable to connect to the server.");
@@ -61,14 +80,43 @@ export function UpcomingElections() {
         method: "POST",
         headers: {
           "Content-Type": "application/json",
+          Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
+        },
+      });
+
+      const data = await response.json().catch(() => ({}));
+      if (response.ok) {
+        alert("Stop requested. Another admin has to approve it under Pending Approvals.");
+      } else {
+        alert(data.error || "Failed to stop the election.");
+      }
+    } catch (err) {
+      alert("Unable to connect to the server.");
+    }
+  };
+
+  // Pause or resume voting nationwide; constituencies and districts are paused through the API
+  const handlePauseOrResume = async (election) => {
+    const action = election.status === "paused" ? "resume" : "pause";
+    const reason = window.prompt(`Reason to ${action} voting in ${election.name}:`);
+    if (!reason || !reason.trim()) return;
+
+    try {
+      const response = await fetch(`${backendUrl}/api/elections/${election.id}/${action}`, {
+        method: "POST",
+        headers: {
+          "Content-Type": "application/json",
+          Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
         },
+        body: JSON.stringify({ reason }),
       });
 
       if (response.ok) {
-        alert("Election stopped successfully!");
+        alert(action === "pause" ? "Voting paused." : "Voting resumed.");
         fetchUpcomingElections();
       } else {
-        alert("Failed to stop the election.");
+        const data = await response.json().catch(() => ({}));
+        alert(data.error || `Failed to ${action} voting.`);
       }
     } catch (err) {
       alert("Unable to connect to the server.");
@@ -82,14 +130,15 @@ export function UpcomingElections() {
         method: "DELETE",
         headers: {
           "Content-Type": "application/json",
+          Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
         },
       });
 
+      const data = await response.json().catch(() => ({}));
       if (response.ok) {
-        alert("Election removed successfully!");
-        fetchUpcomingElections();
+        alert("Removal requested. Another admin has to approve it under Pending Approvals.");
       } else {
-        alert("Failed to remove the election.");
+        alert(data.error || "Failed to remove the election.");
       }
     } catch (err) {
       alert("Unable to connect to the server.");
@@ -113,7 +162,9 @@ export function UpcomingElections() {
           <tbody>
             {elections.map((election) => (
               <tr key={election.id}>
-                <td className="border-b py-2 px-4">{election.name || "Unnamed Election"}</td>
+                <td className="border-b py-2 px-4">
+                  {election.name || "Unnamed Election"} <span className="text-xs">({election.status})</span>
+                </td>
                 <td className="border-b py-2 px-4">
                   {election.date ? new Date(election.date).toLocaleDateString() : "No Date Provided"}
                 </td>
@@ -131,17 +182,25 @@ export function UpcomingElections() {
                   )}
                 </td>
                 <td className="border-b py-2 px-4">
-                  {election.started ? (
-                    <button
-                      onClick={() => handleStopElection(election.id)}
-                      className="px-4 py-2 text-sm font-medium text-white bg-red-600 rounded-md hover:bg-red-700"
-                    >
-                      Stop
-                    </button>
+                  {election.status === "open" || election.status === "paused" ? (
+                    <>
+                      <button
+                        onClick={() => handlePauseOrResume(election)}
+                        className="px-4 py-2 text-sm font-medium text-white bg-yellow-600 rounded-md hover:bg-yellow-700 mr-2"
+                      >
+                        {election.status === "paused" ? "Resume" : "Pause"}
+                      </button>
+                      <button
+                        onClick={() => handleStopElection(election.id)}
+                        className="px-4 py-2 text-sm font-medium text-white bg-red-600 rounded-md hover:bg-red-700"
+                      >
+                        Stop
+                      </button>
+                    </>
                   ) : (
                     <>
                       <button
-                        onClick={() => handleStartElection(election.id)}
+                        onClick={() => handleStartElection(election)}
                         className="px-4 py-2 text-sm font-medium text-white bg-green-600 rounded-md hover:bg-green-700 mr-2"
                       >
                         Start
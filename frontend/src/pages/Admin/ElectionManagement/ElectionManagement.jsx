@@ -1,5 +1,6 @@
 import React, { useState } from "react";
 import { UpcomingElections } from "./Components/UpcomingElections";
+import { PendingApprovals } from "./Components/PendingApprovals";
 import { NewElectionButton } from "./Components/NewElectionButton";
 import { PreviousElectionsButton } from "./Components/PreviousElectionsButton";
 import { NewElection } from "./Components/NewElection";
@@ -19,6 +20,9 @@ export function ElectionManagement() {
           {/* Upcoming Elections */}
           <UpcomingElections />
 
+          {/* Actions waiting for a second admin */}
+          <PendingApprovals />
+
           {/* Buttons for New Election and Previous Elections */}
           <div className="mt-6 flex justify-center space-x-4">
             <NewElectionButton onClick={() => setView("new")} />
@@ -18,6 +18,7 @@ export function AddParty({ onClose, onPartyAdded }) {
           method: "GET",
           headers: {
             "Content-Type": "application/json",
+            Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
           },
         });
 
@@ -75,6 +76,7 @@ export function AddParty({ onClose, onPartyAdded }) {
         method: "POST",
         headers: {
           "Content-Type": "application/json",
+          Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
         },
         body: JSON.stringify({
           name: formData.get("name"),
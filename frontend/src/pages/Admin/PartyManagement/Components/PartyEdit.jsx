@@ -14,6 +14,7 @@ function PartyEdit({ party, onClose, onPartyDeleted, onPartyUpdated }) {
           method: "GET",
           headers: {
             "Content-Type": "application/json",
+            Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
           },
         });
         if (response.ok) {
@@ -39,6 +40,7 @@ function PartyEdit({ party, onClose, onPartyDeleted, onPartyUpdated }) {
         method: "POST",
         headers: {
           "Content-Type": "application/json",
+          Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
         },
         body: JSON.stringify({ citizen_id: citizenId, party_id: party.id }),
       });
@@ -66,6 +68,7 @@ function PartyEdit({ party, onClose, onPartyDeleted, onPartyUpdated }) {
         method: "DELETE",
         headers: {
           "Content-Type": "application/json",
+          Authorization: `Bearer ${localStorage.getItem("adminToken")}`,
         },
       });
 
@@ -17,29 +17,9 @@ function Login() {
     setError("");
     setCitizen(null);
 
-    try {
-      const response = await fetch(`${backendUrl}/api/citizens/${nid}`, {
-        method: "GET",
-        headers: {
-          "Content-Type": "application/json",
-        },
-      });
-
-      if (response.ok) {
-        const data = await response.json();
-        if (data && data.nid) {
-          setCitizen(data);
-          startVideoStream();
-        } else {
-          setError("Citizen not found. Please check your NID.");
-        }
-      } else {
-        setError("Error fetching Citizen details. Please try again later.");
-      }
-    } catch (err) {
-      alert("error:" + err);
-      setError("Unable to connect to the server. Please try again later.");
-    }
+    // Citizen records are admin-only; an unknown NID is reported by the face check
+    setCitizen({ nid });
+    startVideoStream();
   };
 
   const startVideoStream = () => {
@@ -100,8 +80,10 @@ function Login() {
       const result = await response.json();
 
       if (response.ok) {
-        // Save authentication reference for router protection
+        // Save the voter session token; the backend takes the voter identity from it
         localStorage.setItem("citizenId", nid);
+        localStorage.setItem("districtId", result.district_id);
+        localStorage.setItem("voterToken", result.token);
         localStorage.setItem("isAuthenticated", "true");
         alert("Authentication successful!");
         stopVideoStream();
@@ -4,12 +4,17 @@ import { CardLayout } from "../../components/CardLayout";
 function Voting() {
   const [elections, setElections] = useState([]); // Initialize as an empty array
   const [selectedElection, setSelectedElection] = useState(null);
-  const [candidates, setCandidates] = useState([]);
+  const [races, setRaces] = useState([]); // Candidate contests, one per tier (National and Provincial Assembly)
+  const [activeRace, setActiveRace] = useState(0);
   const [selectedCandidate, setSelectedCandidate] = useState(null);
-  const [voteCast, setVoteCast] = useState(false);
+  const [ranking, setRanking] = useState([]); // Candidates in order of preference, for ranked elections
+  const [ballotQuestions, setBallotQuestions] = useState([]); // Referendums and other questions asked in the voter's district
+  const [answered, setAnswered] = useState({}); // Option chosen per answered question
+  const [receipts, setReceipts] = useState({}); // Tracking code per constituency voted in
   const [error, setError] = useState("");
 
-  const citizenID = localStorage.getItem("citizenId");
+  const districtID = localStorage.getItem("districtId");
+  const voterToken = localStorage.getItem("voterToken");
 
   const backendUrl = import.meta.env.VITE_BACKEND_URL;
 
@@ -33,25 +38,54 @@ function Voting() {
   // Fetch constituency data for the selected election
   const fetchConstituencyData = async (election) => {
     try {
-      const citizenResponse = await fetch(`${backendUrl}/api/citizens/${citizenID}`);
-      if (!citizenResponse.ok) throw new Error("Failed to fetch citizen data");
-      const citizenData = await citizenResponse.json();
-      const districtID = citizenData.district_id;
-
       const response = await fetch(
         `${backendUrl}/api/voting/constituency/${election.id}/${districtID}`
       );
       if (!response.ok) throw new Error("Failed to fetch constituency data");
       const data = await response.json();
-      setCandidates(data || []); // Ensure data is an array
+      const contests = data.contests || [];
+      setRaces(contests.filter((contest) => contest.type === "candidate"));
+      setActiveRace(0);
+      setReceipts({});
+      setBallotQuestions(contests.filter((contest) => contest.type === "question").map((contest) => contest.question));
+      setAnswered({});
       setSelectedElection(election);
+      setRanking([]);
     } catch (err) {
       setError(err.message);
     }
   };
 
+  const isRanked = selectedElection && selectedElection.ballot_type !== "fptp";
+  const race = races[activeRace];
+  const candidates = race ? race.lines : [];
+  const trackingCode = race && receipts[race.constituency_id];
+  const tierNames = { na: "National Assembly", pa: "Provincial Assembly" };
+  // A race takes votes unless its constituency or the voter's district is paused or past closing time
+  const raceStatus = race && selectedElection?.constituencies?.find((status) => status.constituency_id === race.constituency_id);
+  const raceOpen =
+    !raceStatus || (raceStatus.accepting_votes && !raceStatus.paused_districts.includes(Number(districtID)));
+
+  const switchRace = (index) => {
+    setActiveRace(index);
+    setRanking([]);
+  };
+
   const handleVote = (candidate) => {
-    setSelectedCandidate(candidate);
+    if (!isRanked) {
+      setSelectedCandidate(candidate);
+      return;
+    }
+    // Clicking a ranked candidate again removes them from the ranking
+    if (ranking.some((ranked) => ranked.candidate_id === candidate.candidate_id)) {
+      setRanking(ranking.filter((ranked) => ranked.candidate_id !== candidate.candidate_id));
+    } else {
+      setRanking([...ranking, candidate]);
+    }
+  };
+
+  const submitRanking = () => {
+    setSelectedCandidate({ choice: "ranked", constituency_id: ranking[0].constituency_id });
   };
 
   const confirmVote = async () => {
@@ -59,22 +93,53 @@ function Voting() {
       const votePayload = {
         electionId: selectedElection.id,
         constituencyId: selectedCandidate.constituency_id,
-        partyId: selectedCandidate.party_id,
-        voterId: citizenID,
+        choice: selectedCandidate.choice,
+        candidateId: selectedCandidate.candidate_id,
       };
+      if (selectedCandidate.choice === "ranked") {
+        votePayload.ranking = ranking.map((candidate) => candidate.candidate_id);
+      }
 
       const response = await fetch(`${backendUrl}/api/votes`, {
         method: "POST",
         headers: {
           "Content-Type": "application/json",
+          Authorization: `Bearer ${voterToken}`,
         },
         body: JSON.stringify(votePayload),
       });
 
       if (!response.ok) throw new Error("Failed to cast vote");
 
-      setVoteCast(true);
+      const result = await response.json();
+      setReceipts({ ...receipts, [selectedCandidate.constituency_id]: result.tracking_code });
       setSelectedCandidate(null);
+      setRanking([]);
+    } catch (err) {
+      setError(err.message);
+    }
+  };
+
+  // Questions are shown in English when available, otherwise in the first language given
+  const localized = (text) => text.en || Object.values(text)[0] || "";
+
+  const answerQuestion = async (question, option) => {
+    if (!window.confirm(`Answer "${localized(option.label)}"? Answers cannot be changed.`)) return;
+    try {
+      const response = await fetch(`${backendUrl}/api/votes/questions`, {
+        method: "POST",
+        headers: {
+          "Content-Type": "application/json",
+          Authorization: `Bearer ${voterToken}`,
+        },
+        body: JSON.stringify({
+          electionId: selectedElection.id,
+          questionId: question.question_id,
+          optionId: option.option_id,
+        }),
+      });
+      if (!response.ok) throw new Error("Failed to record answer");
+      setAnswered({ ...answered, [question.question_id]: option.option_id });
     } catch (err) {
       setError(err.message);
     }
@@ -119,39 +184,152 @@ function Voting() {
             ))}
           </div>
 
-          {selectedElection && candidates.length > 0 ? (
+          {selectedElection && (candidates.length > 0 || ballotQuestions.length > 0) ? (
             <div className="mt-6">
-              <h2 className="text-xl font-semibold text-center text-white">
-                {candidates[0].constituency_name}
-              </h2>
-
-              {voteCast ? (
-                <div className="mt-6 p-4 bg-green-700 text-white rounded-lg shadow-md text-center">
-                  <h3 className="text-lg font-bold">Vote Casted</h3>
-                  <p>Thank you for voting!</p>
-                </div>
-              ) : (
-                <div className="mt-4 space-y-4">
-                  {candidates.map((candidate) => (
-                    <div
-                      key={candidate.party_id}
-                      className="p-4 bg-green-700 rounded-lg shadow-md text-white flex items-center justify-between cursor-pointer hover:bg-green-800"
-                      onClick={() => handleVote(candidate)}
+              {/* One vote in each tier's contest */}
+              {races.length > 1 && (
+                <div className="flex space-x-4 mb-4">
+                  {races.map((contest, index) => (
+                    <button
+                      key={contest.constituency_id}
+                      onClick={() => switchRace(index)}
+                      className={`px-4 py-2 text-sm font-medium rounded-md ${
+                        index === activeRace ? "bg-white text-green-700" : "bg-green-800 text-white"
+                      }`}
                     >
-                      <div className="flex items-center space-x-4">
-                        <img
-                          src={backendUrl + '/' + candidate.party_logo || "images/PakistanFlag.jpg"}
-                          className="w-12 h-12 rounded-full object-cover border-2 border-white"
-                                                  />
-                        <div>
-                          <p className="text-lg font-bold">{candidate.candidate_name}</p>
-                          <p className="text-sm">Party: {candidate.party_name}</p>
-                        </div>
-                      </div>
-                    </div>
+                      {tierNames[contest.tier] || contest.tier}
+                      {receipts[contest.constituency_id] && " ✓"}
+                    </button>
                   ))}
                 </div>
               )}
+
+              {candidates.length > 0 && (
+                <>
+                  <h2 className="text-xl font-semibold text-center text-white">
+                    {candidates[0].constituency_name}
+                  </h2>
+
+                  {trackingCode ? (
+                    <div className="mt-6 p-4 bg-green-700 text-white rounded-lg shadow-md text-center">
+                      <h3 className="text-lg font-bold">Vote Casted</h3>
+                      <p>Thank you for voting!</p>
+                      {trackingCode && (
+                        <div className="mt-4">
+                          <p className="text-sm">Your tracking code:</p>
+                          <p className="font-mono text-lg">{trackingCode}</p>
+                          <p className="text-xs mt-2">
+                            Keep this code to check that your ballot was counted. It does not show who you voted for.
+                          </p>
+                        </div>
+                      )}
+                      {selectedElection.allow_revote && (
+                        <div className="mt-4">
+                          <p className="text-xs">
+                            This election lets you vote again while it is open. Only your last vote counts.
+                          </p>
+                          <button
+                            onClick={() => setReceipts({ ...receipts, [race.constituency_id]: undefined })}
+                            className="mt-2 px-4 py-2 bg-white text-green-700 rounded-md font-medium"
+                          >
+                            Change my vote
+                          </button>
+                        </div>
+                      )}
+                    </div>
+                  ) : !raceOpen ? (
+                    <div className="mt-6 p-4 bg-gray-200 text-gray-800 rounded-lg shadow-md text-center">
+                      <h3 className="text-lg font-bold">Voting Unavailable</h3>
+                      <p>
+                        {selectedElection.status === "paused" || raceStatus.paused || raceStatus.accepting_votes
+                          ? "Voting is paused here for now. Please check back later."
+                          : "Polls have closed in this constituency."}
+                      </p>
+                    </div>
+                  ) : (
+                    <div className="mt-4 space-y-4">
+                      {isRanked && (
+                        <p className="text-sm text-white text-center">
+                          Click candidates in your order of preference. You do not have to rank everyone.
+                          {candidates.length > 0 && candidates[0].seats > 1 && ` ${candidates[0].seats} candidates will be elected.`}
+                        </p>
+                      )}
+                      {candidates.map((candidate) => (
+                        <div
+                          key={`${candidate.choice}-${candidate.candidate_id}`}
+                          className="p-4 bg-green-700 rounded-lg shadow-md text-white flex items-center justify-between cursor-pointer hover:bg-green-800"
+                          onClick={() => handleVote(candidate)}
+                        >
+                          <div className="flex items-center space-x-4">
+                            {candidate.choice === "nota" ? (
+                              <div>
+                                <p className="text-lg font-bold">{candidate.candidate_name}</p>
+                                <p className="text-sm">Counted in turnout, but not for any candidate</p>
+                              </div>
+                            ) : (
+                              <>
+                                <img
+                                  src={backendUrl + '/' + candidate.party_logo || "images/PakistanFlag.jpg"}
+                                  className="w-12 h-12 rounded-full object-cover border-2 border-white"
+                                                          />
+                                <div>
+                                  <p className="text-lg font-bold">{candidate.candidate_name}</p>
+                                  <p className="text-sm">
+                                    {candidate.party_id ? `Party: ${candidate.party_name}` : "Independent"}
+                                  </p>
+                                </div>
+                              </>
+                            )}
+                          </div>
+                          {isRanked && ranking.some((ranked) => ranked.candidate_id === candidate.candidate_id) && (
+                            <span className="w-8 h-8 flex items-center justify-center rounded-full bg-white text-green-700 font-bold">
+                              {ranking.findIndex((ranked) => ranked.candidate_id === candidate.candidate_id) + 1}
+                            </span>
+                          )}
+                        </div>
+                      ))}
+                      {isRanked && (
+                        <button
+                          onClick={submitRanking}
+                          disabled={ranking.length === 0}
+                          className="w-full px-4 py-2 bg-white text-green-700 rounded-md font-medium disabled:opacity-50"
+                        >
+                          Submit ranking
+                        </button>
+                      )}
+                    </div>
+                  )}
+                </>
+              )}
+
+              {/* Ballot questions, answered one at a time and independently of the candidate vote */}
+              {ballotQuestions.map((question) => (
+                <div key={question.question_id} className="mt-6 p-4 bg-white rounded-lg shadow-md">
+                  <p className="text-lg font-bold text-gray-800">{localized(question.text)}</p>
+                  {Object.entries(question.text)
+                    .filter(([language]) => language !== "en")
+                    .map(([language, text]) => (
+                      <p key={language} lang={language} className="text-sm text-gray-600">
+                        {text}
+                      </p>
+                    ))}
+                  {answered[question.question_id] ? (
+                    <p className="mt-2 text-sm text-green-700 font-medium">Your answer has been recorded.</p>
+                  ) : (
+                    <div className="mt-4 flex flex-wrap gap-2">
+                      {question.options.map((option) => (
+                        <button
+                          key={option.option_id}
+                          onClick={() => answerQuestion(question, option)}
+                          className="px-4 py-2 bg-green-700 text-white rounded-md font-medium hover:bg-green-800"
+                        >
+                          {localized(option.label)}
+                        </button>
+                      ))}
+                    </div>
+                  )}
+                </div>
+              ))}
             </div>
           ) : (
             <div className="mt-6 p-4 bg-gray-200 text-gray-800 rounded-lg shadow-md text-center">
@@ -171,11 +349,24 @@ function Voting() {
         <div className="fixed inset-0 flex items-center justify-center bg-black bg-opacity-50">
           <div className="p-6 bg-white rounded-lg shadow-lg">
             <h3 className="text-lg font-bold text-gray-800">Confirm Your Vote</h3>
-            <p className="mt-2 text-gray-600">
-              Are you sure you want to vote for{" "}
-              <span className="font-semibold">{selectedCandidate.candidate_name}</span> (
-              {selectedCandidate.party_name})?
-            </p>
+            {selectedCandidate.choice === "ranked" ? (
+              <div className="mt-2 text-gray-600">
+                <p>Are you sure you want to submit this ranking?</p>
+                <ol className="mt-2 list-decimal list-inside">
+                  {ranking.map((candidate) => (
+                    <li key={candidate.candidate_id} className="font-semibold">
+                      {candidate.candidate_name}
+                    </li>
+                  ))}
+                </ol>
+              </div>
+            ) : (
+              <p className="mt-2 text-gray-600">
+                Are you sure you want to vote for{" "}
+                <span className="font-semibold">{selectedCandidate.candidate_name}</span>
+                {selectedCandidate.choice !== "nota" && ` (${selectedCandidate.party_name})`}?
+              </p>
+            )}
             <div className="mt-4 flex justify-end space-x-4">
               <button
                 onClick={cancelVote}